@@ -2,11 +2,14 @@ package color
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 )
 
 // Color holds color values for the document. All intensities are from 0 to 1.
-// Basecolor is a spot color name such as Pantone 119 for example.
+// Basecolor is a spot color name such as Pantone 119 for example. The alpha
+// value A is only used if HasAlpha is true, otherwise the color is opaque.
 type Color struct {
 	Space       Space
 	Basecolor   string
@@ -19,6 +22,7 @@ type Color struct {
 	G           float64
 	B           float64
 	A           float64
+	HasAlpha    bool
 }
 
 func (col Color) String() string {
@@ -26,7 +30,7 @@ func (col Color) String() string {
 	case ColorNone:
 		return "-"
 	case ColorRGB:
-		alphaStr := strconv.FormatFloat(col.A, 'f', -1, 64)
+		alphaStr := strconv.FormatFloat(col.Opacity(), 'f', -1, 64)
 		return fmt.Sprintf("rgba(%d,%d,%d,%s)", int(col.R*255), int(col.G*255), int(col.B*255), alphaStr)
 	case ColorSpotcolor:
 		return col.Basecolor
@@ -90,3 +94,60 @@ func (col *Color) PDFStringStroking() string {
 func (col *Color) PDFStringNonStroking() string {
 	return col.getPDFColorValues(false)
 }
+
+// Opacity returns the alpha value of the color between 0 (transparent) and 1
+// (opaque). Colors without an alpha value are opaque.
+func (col *Color) Opacity() float64 {
+	if col == nil || !col.HasAlpha {
+		return 1
+	}
+	return math.Max(0, math.Min(1, col.A))
+}
+
+// WithOpacity returns a copy of the color with the alpha value multiplied by
+// opacity. The color is returned unchanged if opacity is 1 or more.
+func (col *Color) WithOpacity(opacity float64) *Color {
+	if col == nil || opacity >= 1 {
+		return col
+	}
+	c := *col
+	c.A = c.Opacity() * math.Max(0, opacity)
+	c.HasAlpha = true
+	return &c
+}
+
+// alphaString formats an alpha value with at most three decimals.
+func alphaString(a float64) string {
+	return strconv.FormatFloat(math.Round(math.Max(0, math.Min(1, a))*1000)/1000, 'f', -1, 64)
+}
+
+// ExtGStateName returns the resource name of the graphics state with the non
+// stroking alpha value fill and the stroking alpha value stroke, for example
+// /GSa0.5_1. The document adds the graphics states used in the content stream
+// to the resources of the page.
+func ExtGStateName(fill, stroke float64) string {
+	return "/GSa" + alphaString(fill) + "_" + alphaString(stroke)
+}
+
+// ParseExtGStateName returns the alpha values of a graphics state name
+// created by ExtGStateName. ok is false if name is not such a name.
+func ParseExtGStateName(name string) (fill, stroke float64, ok bool) {
+	f, s, found := strings.Cut(strings.TrimPrefix(name, "/GSa"), "_")
+	if !found || !strings.HasPrefix(name, "/GSa") {
+		return 0, 0, false
+	}
+	var err error
+	if fill, err = strconv.ParseFloat(f, 64); err != nil {
+		return 0, 0, false
+	}
+	if stroke, err = strconv.ParseFloat(s, 64); err != nil {
+		return 0, 0, false
+	}
+	return fill, stroke, true
+}
+
+// PDFStringAlpha returns the PDF instructions to set the non stroking (fill)
+// and the stroking alpha values.
+func PDFStringAlpha(fill, stroke float64) string {
+	return ExtGStateName(fill, stroke) + " gs"
+}
@@ -0,0 +1,162 @@
+package color
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GradientStop is a color at a position of a gradient.
+type GradientStop struct {
+	Color Color
+	// Offset is the position of the color on the gradient line (0 to 1).
+	Offset float64
+}
+
+// Gradient is a smooth transition between colors. The stops must be sorted by
+// their offset.
+type Gradient struct {
+	Stops []GradientStop
+}
+
+// Space returns the color space of the gradient. Gray colors are converted to
+// the color space of the other colors. RGB and CMYK colors can't be mixed and
+// spot colors are not supported.
+func (g *Gradient) Space() (Space, error) {
+	if len(g.Stops) == 0 {
+		return ColorNone, fmt.Errorf("gradient without colors")
+	}
+	space := ColorGray
+	for _, stop := range g.Stops {
+		switch stop.Color.Space {
+		case ColorGray:
+			// fits any color space
+		case ColorRGB, ColorCMYK:
+			if space != ColorGray && space != stop.Color.Space {
+				return ColorNone, fmt.Errorf("gradient mixes RGB and CMYK colors")
+			}
+			space = stop.Color.Space
+		default:
+			return ColorNone, fmt.Errorf("unsupported color %s in gradient", stop.Color)
+		}
+	}
+	return space, nil
+}
+
+// convert returns the color in the color space sp. Only gray colors are
+// converted.
+func (col Color) convert(sp Space) Color {
+	if col.Space != ColorGray || sp == ColorGray {
+		return col
+	}
+	switch sp {
+	case ColorRGB:
+		return Color{Space: ColorRGB, R: col.G, G: col.G, B: col.G, A: col.A}
+	case ColorCMYK:
+		return Color{Space: ColorCMYK, K: 1 - col.G, A: col.A}
+	}
+	return col
+}
+
+// ColorAt returns the interpolated color at position t (0 to 1) of the
+// gradient. Before the first stop and after the last stop the color of the
+// stop is used. The gradient must have a valid color space (see Space).
+func (g *Gradient) ColorAt(t float64) Color {
+	sp, _ := g.Space()
+	first, last := g.Stops[0], g.Stops[len(g.Stops)-1]
+	if t <= first.Offset {
+		return first.Color.convert(sp)
+	}
+	if t >= last.Offset {
+		return last.Color.convert(sp)
+	}
+	for i := 1; i < len(g.Stops); i++ {
+		to := g.Stops[i]
+		if t > to.Offset {
+			continue
+		}
+		from := g.Stops[i-1]
+		f := 0.0
+		if to.Offset > from.Offset {
+			f = (t - from.Offset) / (to.Offset - from.Offset)
+		}
+		a, b := from.Color.convert(sp), to.Color.convert(sp)
+		mix := func(x, y float64) float64 {
+			return math.Round(100.0*(x+(y-x)*f)) / 100.0
+		}
+		return Color{
+			Space: sp,
+			C:     mix(a.C, b.C),
+			M:     mix(a.M, b.M),
+			Y:     mix(a.Y, b.Y),
+			K:     mix(a.K, b.K),
+			R:     mix(a.R, b.R),
+			G:     mix(a.G, b.G),
+			B:     mix(a.B, b.B),
+		}
+	}
+	return last.Color.convert(sp)
+}
+
+// components returns the color values of the color for a PDF function.
+func (col Color) components() string {
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	switch col.Space {
+	case ColorRGB:
+		return f(col.R) + " " + f(col.G) + " " + f(col.B)
+	case ColorCMYK:
+		return f(col.C) + " " + f(col.M) + " " + f(col.Y) + " " + f(col.K)
+	}
+	return f(col.G)
+}
+
+// Shading returns a PDF shading dictionary for the gradient. shadingType is 2
+// for an axial shading with the coordinates [x0 y0 x1 y1] of the gradient
+// line and 3 for a radial shading with the coordinates [x0 y0 r0 x1 y1 r1] of
+// the start and the end circle. The colors of the stops are combined with a
+// stitching function and the shading is extended beyond the start and the
+// end. Alpha values of the stops are ignored.
+func (g *Gradient) Shading(shadingType int, coords []float64) (string, error) {
+	sp, err := g.Space()
+	if err != nil {
+		return "", err
+	}
+	var csName string
+	switch sp {
+	case ColorRGB:
+		csName = "/DeviceRGB"
+	case ColorCMYK:
+		csName = "/DeviceCMYK"
+	default:
+		csName = "/DeviceGray"
+	}
+	stops := g.Stops
+	if first := stops[0]; first.Offset > 0 {
+		stops = append([]GradientStop{{Color: first.Color, Offset: 0}}, stops...)
+	}
+	if last := stops[len(stops)-1]; last.Offset < 1 {
+		stops = append(stops, GradientStop{Color: last.Color, Offset: 1})
+	}
+	var functions, bounds, encode []string
+	for i := 1; i < len(stops); i++ {
+		from, to := stops[i-1], stops[i]
+		if to.Offset <= from.Offset {
+			// hard color change
+			continue
+		}
+		if len(functions) > 0 {
+			bounds = append(bounds, strconv.FormatFloat(from.Offset, 'f', -1, 64))
+		}
+		functions = append(functions, fmt.Sprintf("<< /FunctionType 2 /Domain [0 1] /C0 [%s] /C1 [%s] /N 1 >>", from.Color.convert(sp).components(), to.Color.convert(sp).components()))
+		encode = append(encode, "0 1")
+	}
+	c := make([]string, len(coords))
+	for i, f := range coords {
+		c[i] = strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+	}
+	return fmt.Sprintf("<< /ShadingType %d /ColorSpace %s /Coords [%s] /Extend [true true] /Function << /FunctionType 3 /Domain [0 1] /Functions [%s] /Bounds [%s] /Encode [%s] >> >>",
+		shadingType, csName, strings.Join(c, " "), strings.Join(functions, " "), strings.Join(bounds, " "), strings.Join(encode, " ")), nil
+}
@@ -0,0 +1,188 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	pdf "github.com/speedata/baseline-pdf"
+)
+
+// FileRelationship describes how an attached file is related to the PDF
+// document. It is used for the associated files of PDF/A-3 (for example
+// ZUGFeRD and Factur-X invoices use FileRelationshipAlternative or
+// FileRelationshipData).
+type FileRelationship int
+
+const (
+	// FileRelationshipUnspecified is used when the relationship is not known.
+	FileRelationshipUnspecified FileRelationship = iota
+	// FileRelationshipSource is the original source material of the document.
+	FileRelationshipSource
+	// FileRelationshipData is the data used to create the document (for
+	// example a CSV file for a table).
+	FileRelationshipData
+	// FileRelationshipAlternative is an alternative representation of the
+	// contents (for example the XML invoice).
+	FileRelationshipAlternative
+	// FileRelationshipSupplement is a supplemental representation of the
+	// document.
+	FileRelationshipSupplement
+)
+
+func (fr FileRelationship) String() string {
+	switch fr {
+	case FileRelationshipSource:
+		return "Source"
+	case FileRelationshipData:
+		return "Data"
+	case FileRelationshipAlternative:
+		return "Alternative"
+	case FileRelationshipSupplement:
+		return "Supplement"
+	}
+	return "Unspecified"
+}
+
+// Attachment is a file embedded in the PDF document.
+type Attachment struct {
+	Name         string
+	Description  string
+	MimeType     string
+	Data         []byte
+	Relationship FileRelationship
+}
+
+// AttachFile embeds the data as a file with the given name in the PDF. The
+// file is listed in the attachments of the PDF viewer and added to the
+// associated files of the document.
+func (d *PDFDocument) AttachFile(name, description, mimetype string, data []byte, relationship FileRelationship) (*Attachment, error) {
+	if name == "" {
+		return nil, fmt.Errorf("AttachFile: the file name must not be empty")
+	}
+	for _, a := range d.attachments {
+		if a.Name == name {
+			return nil, fmt.Errorf("AttachFile: a file with the name %q is already attached", name)
+		}
+	}
+	if mimetype == "" {
+		mimetype = "application/octet-stream"
+	}
+	a := &Attachment{
+		Name:         name,
+		Description:  description,
+		MimeType:     mimetype,
+		Data:         data,
+		Relationship: relationship,
+	}
+	d.attachments = append(d.attachments, a)
+	return a, nil
+}
+
+// mimeTypeName returns the MIME type as a PDF name.
+func mimeTypeName(mimetype string) string {
+	return "/" + strings.NewReplacer("/", "#2F", " ", "#20", "+", "#2B").Replace(mimetype)
+}
+
+// nameTreeKey returns the bytes of the PDF string that pdf.StringToPDF writes
+// for str. The keys of a name tree are sorted by these bytes.
+func nameTreeKey(str string) []byte {
+	for _, r := range str {
+		if r > 127 {
+			key := []byte{0xfe, 0xff}
+			for _, u := range utf16.Encode([]rune(str)) {
+				key = append(key, byte(u>>8), byte(u))
+			}
+			return key
+		}
+	}
+	return []byte(str)
+}
+
+// sortNameDestinations sorts the named destinations by their name tree keys.
+func (d *PDFDocument) sortNameDestinations() {
+	nds := d.PDFWriter.NameDestinations
+	sort.SliceStable(nds, func(i, j int) bool {
+		return bytes.Compare(nameTreeKey(string(nds[i].Name)), nameTreeKey(string(nds[j].Name))) < 0
+	})
+}
+
+// writeAttachments writes the embedded file streams and file specifications
+// and adds the name tree and the associated files array to the catalog. The
+// named destinations are written here as well, since they share the name
+// dictionary with the embedded files.
+func (d *PDFDocument) writeAttachments() error {
+	if len(d.attachments) == 0 {
+		return nil
+	}
+	pw := d.PDFWriter
+	attachments := make([]*Attachment, len(d.attachments))
+	copy(attachments, d.attachments)
+	sort.Slice(attachments, func(i, j int) bool {
+		return bytes.Compare(nameTreeKey(attachments[i].Name), nameTreeKey(attachments[j].Name)) < 0
+	})
+
+	var names, af pdf.Array
+	for _, a := range attachments {
+		ef := pw.NewObject()
+		ef.SetCompression(d.CompressLevel)
+		ef.Data.Write(a.Data)
+		ef.ForceStream = true
+		ef.Dictionary = pdf.Dict{
+			"Type":    "/EmbeddedFile",
+			"Subtype": mimeTypeName(a.MimeType),
+			"Params": pdf.Dict{
+				"Size":    fmt.Sprintf("%d", len(a.Data)),
+				"ModDate": pdfDate(d.CreationDate),
+			},
+		}
+		if err := ef.Save(); err != nil {
+			return err
+		}
+		fs := pw.NewObject()
+		fs.Dictionary = pdf.Dict{
+			"Type":           "/Filespec",
+			"F":              pdf.StringToPDF(a.Name),
+			"UF":             pdf.StringToPDF(a.Name),
+			"AFRelationship": "/" + a.Relationship.String(),
+			"EF": pdf.Dict{
+				"F":  ef.ObjectNumber.Ref(),
+				"UF": ef.ObjectNumber.Ref(),
+			},
+		}
+		if a.Description != "" {
+			fs.Dictionary["Desc"] = pdf.StringToPDF(a.Description)
+		}
+		if err := fs.Save(); err != nil {
+			return err
+		}
+		names = append(names, pdf.StringToPDF(a.Name), fs.ObjectNumber.Ref())
+		af = append(af, fs.ObjectNumber.Ref())
+	}
+	nameDict := pdf.Dict{
+		"EmbeddedFiles": pdf.Dict{"Names": names.String()},
+	}
+
+	if nds := pw.NameDestinations; len(nds) > 0 {
+		var dests pdf.Array
+		for _, nd := range nds {
+			dest := pw.NewObject()
+			dest.Dictionary = pdf.Dict{
+				"D": fmt.Sprintf("[%s /XYZ %0.5g %0.5g null]", nd.PageObjectnumber.Ref(), nd.X, nd.Y),
+			}
+			if err := dest.Save(); err != nil {
+				return err
+			}
+			dests = append(dests, nd.Name.String(), dest.ObjectNumber.Ref())
+		}
+		nameDict["Dests"] = pdf.Dict{"Names": dests.String()}
+		// the PDF writer must not write a second name dictionary
+		pw.NameDestinations = nil
+	}
+
+	pw.Catalog["Names"] = nameDict
+	pw.Catalog["AF"] = af.String()
+	return nil
+}
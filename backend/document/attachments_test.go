@@ -0,0 +1,95 @@
+package document
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+func TestAttachFile(t *testing.T) {
+	var w bytes.Buffer
+	d := pdfxDocument(&w, FormatPDFA3b, "0 g")
+	d.CompressLevel = 0
+	if _, err := d.AttachFile("factur-x.xml", "Invoice data", "text/xml", []byte("<invoice/>"), FileRelationshipAlternative); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AttachFile("factur-x.xml", "", "text/xml", nil, FileRelationshipData); err == nil {
+		t.Errorf("expected an error for a duplicate file name")
+	}
+	if err := d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	for _, want := range []string{
+		"/Type /EmbeddedFile",
+		"/Subtype /text#2Fxml",
+		"<invoice/>",
+		"/Type /Filespec",
+		"/AFRelationship /Alternative",
+		"/UF (factur-x.xml)",
+		"/Desc (Invoice data)",
+		"/EmbeddedFiles",
+		"/AF [",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+
+	w.Reset()
+	d = pdfxDocument(&w, FormatPDFA2b, "0 g")
+	d.AttachFile("data.csv", "", "text/csv", []byte("a,b"), FileRelationshipSource)
+	if err := d.Finish(); err == nil {
+		t.Errorf("expected an error for a CSV attachment in PDF/A-2b")
+	}
+}
+
+func TestAttachmentsAndDestinations(t *testing.T) {
+	var w bytes.Buffer
+	d := NewDocument(&w)
+	d.CompressLevel = 0
+	// the Go string order differs from the order of the UTF-16 encoded keys
+	var head, cur node.Node
+	for _, name := range []string{"zebra", "Ａ", "😀", "apple"} {
+		dest := node.NewStartStop()
+		dest.Action = node.ActionDest
+		dest.Value = name
+		head = node.InsertAfter(head, cur, dest)
+		cur = dest
+	}
+	r := node.NewRule()
+	r.Width = bag.MustSp("1cm")
+	r.Height = bag.MustSp("1cm")
+	head = node.InsertAfter(head, cur, r)
+	d.NewPage()
+	d.CurrentPage.OutputAt(0, bag.MustSp("2cm"), node.Vpack(node.Hpack(head)))
+	d.CurrentPage.Shipout()
+	if _, err := d.AttachFile("data.csv", "", "text/csv", []byte("a,b"), FileRelationshipData); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	if got := strings.Count(out, "/Names"); got != 3 {
+		t.Errorf("found /Names %d times, want 3 (one name dictionary with two name trees)", got)
+	}
+	for _, key := range []string{"/EmbeddedFiles", "/Dests"} {
+		if got := strings.Count(out, key); got != 1 {
+			t.Errorf("found %s %d times, want 1", key, got)
+		}
+	}
+	m := regexp.MustCompile(`/Dests\s*<<\s*/Names\s*\[([^\]]*)\]`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatal("no destination name tree found")
+	}
+	keys := regexp.MustCompile(`\([^)]*\)|<[0-9a-f]+>`).FindAllString(m[1], -1)
+	want := []string{"(apple)", "(zebra)", "<feffd83dde00>", "<feffff21>"}
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Errorf("destination names = %v, want %v", keys, want)
+	}
+}
@@ -2,6 +2,12 @@ package document
 
 import (
 	_ "embed" // embed is used to embed the default color profile
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode/utf16"
 )
 
 // ColorProfile represents a color profile
@@ -21,11 +27,113 @@ func (cp *ColorProfile) String() string {
 //go:embed ISOcoated_v2_eci.icc
 var b []byte
 
-// LoadColorprofile loads an icc based color profile from the URL.
-func (d *PDFDocument) LoadColorprofile(filename string) (*ColorProfile, error) {
+// iccHeaderSize is the size of the fixed ICC profile header. The tag table
+// follows the header.
+const iccHeaderSize = 128
+
+// parseICCProfile reads the header and the tag table of the ICC profile in
+// data and returns a color profile with the number of colors and the profile
+// description.
+func parseICCProfile(data []byte) (*ColorProfile, error) {
+	if len(data) < iccHeaderSize+4 {
+		return nil, fmt.Errorf("ICC profile too short (%d bytes)", len(data))
+	}
+	if sig := string(data[36:40]); sig != "acsp" {
+		return nil, fmt.Errorf("invalid ICC profile signature %q", sig)
+	}
+	if size := binary.BigEndian.Uint32(data[0:4]); int(size) > len(data) {
+		return nil, fmt.Errorf("ICC profile size in header (%d) larger than the data (%d)", size, len(data))
+	}
+	cp := &ColorProfile{
+		Registry: "http://www.color.org",
+		data:     data,
+	}
+	switch cs := string(data[16:20]); cs {
+	case "GRAY":
+		cp.Colors = 1
+	case "RGB ":
+		cp.Colors = 3
+	case "CMYK":
+		cp.Colors = 4
+	default:
+		return nil, fmt.Errorf("unsupported ICC profile color space %q", strings.TrimSpace(cs))
+	}
+
+	tagCount := int(binary.BigEndian.Uint32(data[iccHeaderSize : iccHeaderSize+4]))
+	if iccHeaderSize+4+tagCount*12 > len(data) {
+		return nil, fmt.Errorf("ICC profile tag table (%d entries) exceeds the data", tagCount)
+	}
+	for i := 0; i < tagCount; i++ {
+		entry := data[iccHeaderSize+4+i*12:]
+		sig := string(entry[0:4])
+		offset := int(binary.BigEndian.Uint32(entry[4:8]))
+		size := int(binary.BigEndian.Uint32(entry[8:12]))
+		if offset+size > len(data) || offset+size < offset {
+			return nil, fmt.Errorf("ICC profile tag %q exceeds the data", sig)
+		}
+		if sig == "desc" {
+			desc, err := iccText(data[offset : offset+size])
+			if err != nil {
+				return nil, err
+			}
+			cp.Info = desc
+			cp.Identifier = desc
+		}
+	}
+	if cp.Info == "" {
+		return nil, fmt.Errorf("ICC profile has no description tag")
+	}
+	return cp, nil
+}
 
-	cp := &ColorProfile{}
+// iccText returns the text of an ICC text description tag (ICC version 2,
+// type desc) or a multi localized unicode tag (ICC version 4, type mluc). For
+// the mluc type the first entry is used.
+func iccText(tag []byte) (string, error) {
+	if len(tag) < 12 {
+		return "", fmt.Errorf("ICC profile description tag too short")
+	}
+	switch typ := string(tag[0:4]); typ {
+	case "desc":
+		l := int(binary.BigEndian.Uint32(tag[8:12]))
+		if 12+l > len(tag) {
+			return "", fmt.Errorf("ICC profile description exceeds the tag")
+		}
+		return strings.TrimRight(string(tag[12:12+l]), "\x00"), nil
+	case "mluc":
+		if len(tag) < 28 || binary.BigEndian.Uint32(tag[8:12]) == 0 {
+			return "", fmt.Errorf("ICC profile description has no entries")
+		}
+		l := int(binary.BigEndian.Uint32(tag[20:24]))
+		offset := int(binary.BigEndian.Uint32(tag[24:28]))
+		if offset+l > len(tag) {
+			return "", fmt.Errorf("ICC profile description exceeds the tag")
+		}
+		utf16be := tag[offset : offset+l]
+		runes := make([]uint16, 0, l/2)
+		for i := 0; i+1 < len(utf16be); i += 2 {
+			runes = append(runes, binary.BigEndian.Uint16(utf16be[i:]))
+		}
+		return strings.TrimRight(string(utf16.Decode(runes)), "\x00"), nil
+	default:
+		return "", fmt.Errorf("unknown ICC profile description type %q", typ)
+	}
+}
 
+// LoadColorprofile loads an ICC based color profile from the file and sets it
+// as the document color profile. The Identifier and Info fields are set to the
+// profile description and can be changed to match the output condition of the
+// print job (for example "FOGRA39" or "CGATS TR 006").
+func (d *PDFDocument) LoadColorprofile(filename string) (*ColorProfile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := parseICCProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	d.ColorProfile = cp
 	return cp, nil
 }
 
@@ -42,3 +150,100 @@ func (d *PDFDocument) LoadDefaultColorprofile() (*ColorProfile, error) {
 	d.ColorProfile = cp
 	return cp, nil
 }
+
+// s15Fixed16 converts f to the ICC s15Fixed16Number representation.
+func s15Fixed16(f float64) uint32 {
+	return uint32(int32(math.Round(f * 65536)))
+}
+
+// srgbICCProfile creates an ICC version 2 display profile for the sRGB
+// IEC61966-2.1 color space.
+func srgbICCProfile() []byte {
+	const desc = "sRGB IEC61966-2.1"
+	be := binary.BigEndian
+	xyz := func(x, y, z float64) []byte {
+		tag := make([]byte, 20)
+		copy(tag, "XYZ ")
+		be.PutUint32(tag[8:], s15Fixed16(x))
+		be.PutUint32(tag[12:], s15Fixed16(y))
+		be.PutUint32(tag[16:], s15Fixed16(z))
+		return tag
+	}
+	// textDescriptionType: ASCII part, empty Unicode and ScriptCode parts
+	descTag := make([]byte, 12+len(desc)+1+8+3+67)
+	copy(descTag, "desc")
+	be.PutUint32(descTag[8:], uint32(len(desc)+1))
+	copy(descTag[12:], desc)
+	cprtTag := append([]byte("text\x00\x00\x00\x00"), "No copyright, use freely\x00"...)
+	// the sRGB tone reproduction curve
+	const curveEntries = 1024
+	trcTag := make([]byte, 12+2*curveEntries)
+	copy(trcTag, "curv")
+	be.PutUint32(trcTag[8:], curveEntries)
+	for i := 0; i < curveEntries; i++ {
+		v := float64(i) / (curveEntries - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		be.PutUint16(trcTag[12+2*i:], uint16(math.Round(v*65535)))
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", descTag},
+		{"cprt", cprtTag},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		// primaries, chromatically adapted to D50
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trcTag},
+		{"gTRC", trcTag},
+		{"bTRC", trcTag},
+	}
+	tagTableSize := 4 + 12*len(tags)
+	data := make([]byte, iccHeaderSize+tagTableSize)
+	offsets := map[*byte]int{}
+	for i, tag := range tags {
+		// the curves share their data
+		offset, ok := offsets[&tag.data[0]]
+		if !ok {
+			offset = len(data)
+			offsets[&tag.data[0]] = offset
+			data = append(data, tag.data...)
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+		}
+		entry := data[iccHeaderSize+4+12*i:]
+		copy(entry, tag.sig)
+		be.PutUint32(entry[4:], uint32(offset))
+		be.PutUint32(entry[8:], uint32(len(tag.data)))
+	}
+	be.PutUint32(data[iccHeaderSize:], uint32(len(tags)))
+
+	be.PutUint32(data[0:], uint32(len(data)))
+	be.PutUint32(data[8:], 0x02100000)
+	copy(data[12:], "mntr")
+	copy(data[16:], "RGB ")
+	copy(data[20:], "XYZ ")
+	copy(data[36:], "acsp")
+	// D50 illuminant
+	copy(data[68:], xyz(0.9642, 1.0, 0.8249)[8:])
+	return data
+}
+
+// LoadSRGBColorprofile sets the document color profile to sRGB. This is the
+// default output intent for PDF/A documents.
+func (d *PDFDocument) LoadSRGBColorprofile() (*ColorProfile, error) {
+	cp, err := parseICCProfile(srgbICCProfile())
+	if err != nil {
+		return nil, err
+	}
+	d.ColorProfile = cp
+	return cp, nil
+}
@@ -0,0 +1,133 @@
+package document
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadColorprofile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "profile.icc")
+	if err := os.WriteFile(filename, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	d := &PDFDocument{}
+	cp, err := d.LoadColorprofile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cp.Colors, 4; got != want {
+		t.Errorf("cp.Colors = %d, want %d", got, want)
+	}
+	if got, want := cp.Info, "ISO Coated v2 (ECI)"; got != want {
+		t.Errorf("cp.Info = %q, want %q", got, want)
+	}
+	if d.ColorProfile != cp {
+		t.Errorf("d.ColorProfile not set")
+	}
+	if _, err = d.LoadColorprofile(filepath.Join(t.TempDir(), "doesnotexist.icc")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestParseICCProfileMluc(t *testing.T) {
+	// header, one tag (desc of type mluc with one record "sRGB")
+	data := make([]byte, 128+4+12+28+8)
+	binary.BigEndian.PutUint32(data[0:4], uint32(len(data)))
+	copy(data[16:20], "RGB ")
+	copy(data[36:40], "acsp")
+	binary.BigEndian.PutUint32(data[128:132], 1)
+	copy(data[132:136], "desc")
+	binary.BigEndian.PutUint32(data[136:140], 144)
+	binary.BigEndian.PutUint32(data[140:144], 36)
+	tag := data[144:]
+	copy(tag[0:4], "mluc")
+	binary.BigEndian.PutUint32(tag[8:12], 1)
+	binary.BigEndian.PutUint32(tag[12:16], 12)
+	copy(tag[16:20], "enUS")
+	binary.BigEndian.PutUint32(tag[20:24], 8)
+	binary.BigEndian.PutUint32(tag[24:28], 28)
+	for i, r := range "sRGB" {
+		binary.BigEndian.PutUint16(tag[28+2*i:], uint16(r))
+	}
+	cp, err := parseICCProfile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Colors != 3 || cp.Info != "sRGB" {
+		t.Errorf("got colors %d info %q, want 3 and sRGB", cp.Colors, cp.Info)
+	}
+}
+
+func TestParseICCProfileMalformed(t *testing.T) {
+	badSignature := make([]byte, len(b))
+	copy(badSignature, b)
+	copy(badSignature[36:40], "xxxx")
+	badTagTable := make([]byte, 200)
+	binary.BigEndian.PutUint32(badTagTable[0:4], 200)
+	copy(badTagTable[16:20], "CMYK")
+	copy(badTagTable[36:40], "acsp")
+	binary.BigEndian.PutUint32(badTagTable[128:132], 100)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"short", b[:100]},
+		{"signature", badSignature},
+		{"truncated", b[:len(b)/2]},
+		{"tag table", badTagTable},
+	}
+	for _, tc := range testCases {
+		if _, err := parseICCProfile(tc.data); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestSRGBProfile(t *testing.T) {
+	data := srgbICCProfile()
+	cp, err := parseICCProfile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Colors != 3 || cp.Identifier != "sRGB IEC61966-2.1" {
+		t.Errorf("got colors %d identifier %q, want 3 and sRGB IEC61966-2.1", cp.Colors, cp.Identifier)
+	}
+	if got := binary.BigEndian.Uint32(data[0:4]); int(got) != len(data) || len(data)%4 != 0 {
+		t.Errorf("profile size in header %d, data %d bytes, want equal and a multiple of 4", got, len(data))
+	}
+	for _, field := range []struct {
+		name       string
+		start, end int
+		want       string
+	}{
+		{"version", 8, 10, "\x02\x10"},
+		{"device class", 12, 16, "mntr"},
+		{"color space", 16, 20, "RGB "},
+		{"connection space", 20, 24, "XYZ "},
+	} {
+		if got := string(data[field.start:field.end]); got != field.want {
+			t.Errorf("%s = %q, want %q", field.name, got, field.want)
+		}
+	}
+	// the required tags of an RGB display profile with matrix and curves
+	tags := map[string]bool{}
+	for i := 0; i < int(binary.BigEndian.Uint32(data[iccHeaderSize:])); i++ {
+		tags[string(data[iccHeaderSize+4+12*i:][:4])] = true
+	}
+	for _, sig := range []string{"desc", "cprt", "wtpt", "rXYZ", "gXYZ", "bXYZ", "rTRC", "gTRC", "bTRC"} {
+		if !tags[sig] {
+			t.Errorf("tag %s missing in the sRGB profile", sig)
+		}
+	}
+
+	d := &PDFDocument{}
+	if cp, err = d.LoadSRGBColorprofile(); err != nil {
+		t.Fatal(err)
+	}
+	if d.ColorProfile != cp {
+		t.Errorf("d.ColorProfile not set")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"math"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -15,6 +17,7 @@ import (
 	"github.com/speedata/boxesandglue/backend/image"
 	"github.com/speedata/boxesandglue/backend/lang"
 	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/backend/svg"
 	"github.com/speedata/boxesandglue/frontend/pdfdraw"
 )
 
@@ -169,6 +172,20 @@ func (oc *objectContext) gotoTextMode(newMode uint8) {
 	}
 }
 
+// outputLeader fills the glue g at the horizontal position sumX of the hlist
+// starting at x with copies of the leader. The copies are aligned to multiples
+// of the leader width on the page, so the leaders of all lines line up.
+func (oc *objectContext) outputLeader(x, y, sumX bag.ScaledPoint, g *node.Glue) {
+	lw := g.Leader.Width
+	start, end := x+sumX, x+sumX+g.Width
+	oc.gotoTextMode(3)
+	for pos := (start + lw - 1) / lw * lw; pos+lw <= end; pos += lw {
+		oc.outputHorizontalItems(pos, y, g.Leader)
+	}
+	// the next glyph needs a new text position
+	oc.gotoTextMode(3)
+}
+
 // outputHorizontalItems outputs a list of horizontal item and advances the
 // cursor. x and y must be the start of the base line coordinate.
 func (oc *objectContext) outputHorizontalItems(x, y bag.ScaledPoint, hlist *node.HList) {
@@ -234,14 +251,28 @@ func (oc *objectContext) outputHorizontalItems(x, y bag.ScaledPoint, hlist *node
 			if oc.textmode > 2 {
 				yPos := y
 				if hlist.VAlign == node.VAlignTop {
-					yPos -= v.Height
+					yPos -= v.Font.Size - v.Font.Depth
 				}
 				oc.moveto(x+oc.shiftX+sumX, yPos)
 				oc.shiftX = 0
 			}
 			v.Font.Face.RegisterChar(v.Codepoint)
+			// move the glyph without changing the advance, the offset is
+			// rounded to 1/1000 em (the TJ unit) and moved back after the glyph
+			var xOffset int
+			if v.Font.Size != 0 {
+				xOffset = int(math.Round(1000 * float64(v.XOffset) / float64(v.Font.Size)))
+			}
+			if xOffset != 0 {
+				oc.gotoTextMode(2)
+				fmt.Fprintf(oc.s, " %d ", -xOffset)
+			}
 			oc.gotoTextMode(1)
 			fmt.Fprintf(oc.s, "%04x", v.Codepoint)
+			if xOffset != 0 {
+				oc.gotoTextMode(2)
+				fmt.Fprintf(oc.s, " %d ", xOffset)
+			}
 			sumX = sumX + bag.MultiplyFloat(v.Width, float64(100+oc.currentExpand)/100.0)
 		case *node.Glue:
 			od := &outputDebug{
@@ -254,6 +285,11 @@ func (oc *objectContext) outputHorizontalItems(x, y bag.ScaledPoint, hlist *node
 				od.Attributes["origin"] = origin
 			}
 			oc.curOutputDebug.Items = append(oc.curOutputDebug.Items, od)
+			if v.Leader != nil && v.Leader.Width > 0 {
+				oc.outputLeader(x, y, sumX, v)
+				sumX += v.Width
+				continue
+			}
 			if oc.textmode == 2 {
 				oc.gotoTextMode(1)
 			}
@@ -450,8 +486,8 @@ func (oc *objectContext) outputHorizontalItems(x, y bag.ScaledPoint, hlist *node
 				fmt.Fprintf(oc.s, " %d ", -1000*v.Kern/oc.currentFont.Size)
 			}
 			sumX += v.Kern
-		case *node.Lang, *node.Penalty:
-			// ignore
+		case *node.Insert, *node.Lang, *node.Penalty:
+			// ignore, inserts are placed by the page builder
 		case *node.Disc:
 			// ignore
 		case *node.HList:
@@ -580,6 +616,8 @@ func (oc *objectContext) outputVerticalItems(x, y bag.ScaledPoint, vlist *node.V
 			}
 			pdfinstructions = append(pdfinstructions, fmt.Sprintf("1 0 0 1 %s %s cm\n", -posX, -posY))
 			fmt.Fprintf(oc.s, strings.Join(pdfinstructions, " "))
+		case *node.Insert:
+			// placed by the page builder
 		case *node.StartStop:
 			posX := x
 			posY := y
@@ -820,6 +858,10 @@ func (p *Page) Shipout() {
 		p.outputDebug.Items = append(p.outputDebug.Items, oc.outputDebug)
 	}
 
+	rgb, cmyk := contentColorSpaces(st.Data.Bytes())
+	p.document.rgbUsed = p.document.rgbUsed || rgb
+	p.document.cmykUsed = p.document.cmykUsed || cmyk
+
 	page := p.document.PDFWriter.AddPage(st, pageObjectNumber)
 	page.Dict = make(pdf.Dict)
 	page.Width = (p.Width + 2*offsetX).ToPT()
@@ -835,6 +877,24 @@ func (p *Page) Shipout() {
 	for i := range usedImages {
 		page.Images = append(page.Images, i)
 	}
+	gs := contentExtGStates(st.Data.Bytes())
+	if len(gs) > 0 {
+		p.document.useFeature("transparency", 14)
+	}
+	shadings, err := p.document.contentShadings(st.Data.Bytes())
+	if err != nil {
+		bag.Logger.Error("cannot write shading", "error", err)
+	}
+	if len(gs) > 0 || len(shadings) > 0 {
+		pr := &pageResources{
+			pw:        p.document.PDFWriter,
+			page:      page,
+			extGState: gs,
+			shadings:  shadings,
+		}
+		page.Dict["Resources"] = pr
+		p.document.pageResources = append(p.document.pageResources, pr)
+	}
 
 	var structureElementObjectIDs []string
 	// annotations are hyperlinks and structure elements
@@ -919,8 +979,10 @@ type PDFDocument struct {
 	DefaultPageWidth     bag.ScaledPoint
 	Faces                []*pdf.Face
 	Filename             string
+	Format               Format
 	Keywords             string
 	Languages            map[string]*lang.Lang
+	Outlines             []*Outline
 	Pages                []*Page
 	PDFWriter            *pdf.PDF
 	RootStructureElement *StructureElement
@@ -932,12 +994,24 @@ type PDFDocument struct {
 	Title                string
 	ViewerPreferences    map[string]string
 	producer             string
+	attachments          []*Attachment
 	tracing              VTrace
 	outputDebug          *outputDebug
 	curOutputDebug       *outputDebug
 	pdfStructureObjects  []*pdfStructureObject
 	preShipoutCallback   []CallbackShipout
+	rgbUsed              bool
+	cmykUsed             bool
+	header               *headerWriter
+	features             map[string]int
 	usedPDFImages        map[string]*pdf.Imagefile
+	svgFontCallback      svg.FontFunc
+	svgDir               string
+	svgCount             int
+	imageResolutions     map[*pdf.Imagefile][2]float64
+	shadings             map[string]*shading
+	shadingNames         map[string]string
+	pageResources        []*pageResources
 }
 
 // NewDocument creates an empty document.
@@ -948,7 +1022,6 @@ func NewDocument(w io.Writer) *PDFDocument {
 		CreationDate:      time.Now(),
 		Languages:         make(map[string]*lang.Lang),
 		ViewerPreferences: make(map[string]string),
-		PDFWriter:         pdf.NewPDFWriter(w),
 		CompressLevel:     9,
 		producer:          "speedata/boxesandglue",
 		usedPDFImages:     make(map[string]*pdf.Imagefile),
@@ -957,6 +1030,8 @@ func NewDocument(w io.Writer) *PDFDocument {
 		},
 	}
 	d.curOutputDebug = d.outputDebug
+	d.header = &headerWriter{w: w, d: d}
+	d.PDFWriter = pdf.NewPDFWriter(d.header)
 	pdf.Logger = bag.Logger
 	return d
 }
@@ -1025,13 +1100,20 @@ func (d *PDFDocument) LoadImageFile(filename string) (*pdf.Imagefile, error) {
 }
 
 // LoadImageFileWithBox loads an image file. Images that should be placed in the PDF
-// file must be derived from the file.
+// file must be derived from the file. SVG files (with the extension .svg) are
+// converted to PDF, box and pagenumber are ignored for them.
 func (d *PDFDocument) LoadImageFileWithBox(filename string, box string, pagenumber int) (*pdf.Imagefile, error) {
 	key := fmt.Sprintf("%s-%s-%d", filename, box, pagenumber)
 	if imgf, ok := d.usedPDFImages[key]; ok {
 		return imgf, nil
 	}
-	imgf, err := pdf.LoadImageFileWithBox(d.PDFWriter, filename, box, pagenumber)
+	var imgf *pdf.Imagefile
+	var err error
+	if strings.EqualFold(filepath.Ext(filename), ".svg") {
+		imgf, err = d.loadSVGFile(filename)
+	} else {
+		imgf, err = pdf.LoadImageFileWithBox(d.PDFWriter, filename, box, pagenumber)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1041,7 +1123,8 @@ func (d *PDFDocument) LoadImageFileWithBox(filename string, box string, pagenumb
 
 // CreateImage returns a new Image derived from the image file. The parameter
 // pagenumber is honored only in PDF files. The Box is one of "/MediaBox", "/CropBox",
-// "/TrimBox", "/BleedBox" or "/ArtBox"
+// "/TrimBox", "/BleedBox" or "/ArtBox". The size of JPEG and PNG images is
+// derived from the resolution stored in the file (72 dpi if there is none).
 func (d *PDFDocument) CreateImage(imgfile *pdf.Imagefile, pagenumber int, box string) *image.Image {
 	img := &image.Image{}
 	img.ImageFile = imgfile
@@ -1056,8 +1139,19 @@ func (d *PDFDocument) CreateImage(imgfile *pdf.Imagefile, pagenumber int, box st
 		img.Width = bag.ScaledPointFromFloat(mb["w"])
 		img.Height = bag.ScaledPointFromFloat(mb["h"])
 	case "jpeg", "png":
-		img.Width = bag.ScaledPoint(imgfile.W) * bag.Factor
-		img.Height = bag.ScaledPoint(imgfile.H) * bag.Factor
+		res, ok := d.imageResolutions[imgfile]
+		if !ok {
+			res = [2]float64{defaultResolution, defaultResolution}
+			if x, y, ok := imageResolution(imgfile.Filename, imgfile.Format); ok {
+				res = [2]float64{x, y}
+			}
+			if d.imageResolutions == nil {
+				d.imageResolutions = make(map[*pdf.Imagefile][2]float64)
+			}
+			d.imageResolutions[imgfile] = res
+		}
+		img.Width = bag.ScaledPointFromFloat(float64(imgfile.W) * 72 / res[0])
+		img.Height = bag.ScaledPointFromFloat(float64(imgfile.H) * 72 / res[1])
 	}
 	return img
 }
@@ -1086,8 +1180,33 @@ func (d *PDFDocument) CreateFont(face *pdf.Face, size bag.ScaledPoint) *font.Fon
 
 // Finish writes all objects to the PDF and writes the XRef section. Finish does
 // not close the writer.
-func (d *PDFDocument) Finish() error {
-	var err error
+func (d *PDFDocument) Finish() (err error) {
+	// The converted SVG graphics are read when the PDF writer finishes.
+	defer func() {
+		if rerr := d.removeSVGFiles(); err == nil {
+			err = rerr
+		}
+	}()
+	if d.Format.isPDFA() && d.ColorProfile == nil {
+		if d.cmykUsed && !d.rgbUsed {
+			_, err = d.LoadDefaultColorprofile()
+		} else {
+			_, err = d.LoadSRGBColorprofile()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if d.RootStructureElement != nil {
+		d.useFeature("tagged PDF", 14)
+	}
+	if len(d.attachments) > 0 {
+		d.useFeature("associated files", 17)
+	}
+	if err = d.validateFormat(); err != nil {
+		return err
+	}
+	d.PDFWriter.Minor = uint(d.Format.pdfVersion() % 10)
 	d.PDFWriter.Catalog = pdf.Dict{}
 	if d.ColorProfile != nil {
 		cp := d.PDFWriter.NewObject()
@@ -1098,6 +1217,14 @@ func (d *PDFDocument) Finish() error {
 		if err = cp.Save(); err != nil {
 			return err
 		}
+		if d.Format.outputIntentSubtype() != "" {
+			oi := d.PDFWriter.NewObject()
+			oi.Dictionary = d.outputIntent(cp)
+			if err = oi.Save(); err != nil {
+				return err
+			}
+			d.PDFWriter.Catalog["OutputIntents"] = pdf.Array{oi.ObjectNumber.Ref()}.String()
+		}
 		for _, col := range d.Spotcolors {
 			sep := pdf.Separation{}
 			sep.ICCProfile = cp.ObjectNumber
@@ -1164,6 +1291,17 @@ func (d *PDFDocument) Finish() error {
 
 	}
 
+	d.sortNameDestinations()
+	if err = d.writeAttachments(); err != nil {
+		return err
+	}
+
+	if len(d.Outlines) > 0 {
+		if d.PDFWriter.Outlines, err = d.pdfOutlines(d.Outlines); err != nil {
+			return err
+		}
+	}
+
 	rdf := d.PDFWriter.NewObject()
 	rdf.Data.WriteString(d.getMetadata())
 	rdf.Dictionary = pdf.Dict{
@@ -1200,10 +1338,25 @@ func (d *PDFDocument) Finish() error {
 		d.PDFWriter.InfoDict["Keywords"] = pdf.StringToPDF(t)
 	}
 	d.PDFWriter.InfoDict["CreationDate"] = d.CreationDate.Format("(D:20060102150405)")
+	if d.Format.isPDFA() {
+		// the dates must match the XMP metadata
+		d.PDFWriter.InfoDict["CreationDate"] = pdfDate(d.CreationDate)
+		d.PDFWriter.InfoDict["ModDate"] = pdfDate(d.CreationDate)
+	}
+	if d.Format.isPDFX() {
+		d.PDFWriter.InfoDict["ModDate"] = d.PDFWriter.InfoDict["CreationDate"]
+		d.PDFWriter.InfoDict["GTS_PDFXVersion"] = pdf.StringToPDF(d.Format.String())
+		d.PDFWriter.InfoDict["Trapped"] = "/False"
+	}
 
 	if err = d.PDFWriter.Finish(); err != nil {
 		return err
 	}
+	for _, pr := range d.pageResources {
+		if pr.err != nil {
+			return pr.err
+		}
+	}
 	if d.Filename != "" {
 		bag.Logger.Info("Output written", "filename", d.Filename, "bytes", d.PDFWriter.Size())
 	} else {
@@ -1219,6 +1372,10 @@ type Callback int
 const (
 	// CallbackPreShipout is called right before a page shipout. It is called once for each page.
 	CallbackPreShipout Callback = iota
+	// CallbackSVGFont is called to get the font face for text in SVG graphics.
+	// The function has the signature func(family string, weight int, italic
+	// bool) *pdf.Face and returns nil for unknown font families.
+	CallbackSVGFont
 )
 
 // RegisterCallback registers the callback in fn.
@@ -1226,5 +1383,7 @@ func (d *PDFDocument) RegisterCallback(cb Callback, fn any) {
 	switch cb {
 	case CallbackPreShipout:
 		d.preShipoutCallback = append(d.preShipoutCallback, fn.(func(page *Page)))
+	case CallbackSVGFont:
+		d.svgFontCallback = fn.(func(family string, weight int, italic bool) *pdf.Face)
 	}
 }
@@ -0,0 +1,54 @@
+package document
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	pdf "github.com/speedata/baseline-pdf"
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/fonts/camingocoderegular"
+)
+
+func TestGlyphXOffset(t *testing.T) {
+	var w bytes.Buffer
+	d := NewDocument(&w)
+	face, err := d.LoadFaceFromData(camingocoderegular.TTF, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fnt := d.CreateFont(face, bag.MustSp("10pt"))
+	var head, tail node.Node
+	for i, xoffset := range []bag.ScaledPoint{
+		bag.MustSp("2pt"),
+		0,
+		// 1.6/1000 em is rounded to 2
+		1049,
+	} {
+		g := node.NewGlyph()
+		g.Font = fnt
+		g.Codepoint = 36 + i
+		g.Width = bag.MustSp("6pt")
+		g.XOffset = xoffset
+		head = node.InsertAfter(head, tail, g)
+		tail = g
+	}
+	var buf bytes.Buffer
+	oc := &objectContext{
+		textmode:    4,
+		s:           &buf,
+		usedFaces:   make(map[*pdf.Face]bool),
+		usedImages:  make(map[*pdf.Imagefile]bool),
+		p:           d.NewPage(),
+		outputDebug: &outputDebug{Name: "object"},
+	}
+	oc.curOutputDebug = oc.outputDebug
+	oc.outputHorizontalItems(0, 0, node.Hpack(head))
+	oc.gotoTextMode(4)
+	// the offsets are undone after the glyph, so the following glyphs are
+	// not moved
+	if want := "[ -200 <0024> 200 <0025> -2 <0026> 2 ]TJ"; !strings.Contains(buf.String(), want) {
+		t.Errorf("content stream %q does not contain %q", buf.String(), want)
+	}
+}
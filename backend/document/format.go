@@ -0,0 +1,224 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	pdf "github.com/speedata/baseline-pdf"
+)
+
+// Format is the PDF standard the document conforms to.
+type Format int
+
+const (
+	// FormatPDF is a regular PDF file without further restrictions.
+	FormatPDF Format = iota
+	// FormatPDFX3 is PDF/X-3:2003 (ISO 15930-6).
+	FormatPDFX3
+	// FormatPDFX4 is PDF/X-4 (ISO 15930-7).
+	FormatPDFX4
+	// FormatPDFA2b is PDF/A-2b (ISO 19005-2, basic conformance).
+	FormatPDFA2b
+	// FormatPDFA3b is PDF/A-3b (ISO 19005-3, basic conformance).
+	FormatPDFA3b
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatPDF:
+		return "PDF"
+	case FormatPDFX3:
+		return "PDF/X-3:2003"
+	case FormatPDFX4:
+		return "PDF/X-4"
+	case FormatPDFA2b:
+		return "PDF/A-2b"
+	case FormatPDFA3b:
+		return "PDF/A-3b"
+	}
+	return "unknown format"
+}
+
+// isPDFX returns true if the format is one of the PDF/X formats.
+func (f Format) isPDFX() bool {
+	return f == FormatPDFX3 || f == FormatPDFX4
+}
+
+// isPDFA returns true if the format is one of the PDF/A formats.
+func (f Format) isPDFA() bool {
+	return f == FormatPDFA2b || f == FormatPDFA3b
+}
+
+// pdfaPart returns the part number of the PDF/A standard or 0 if the format is
+// not PDF/A.
+func (f Format) pdfaPart() int {
+	switch f {
+	case FormatPDFA2b:
+		return 2
+	case FormatPDFA3b:
+		return 3
+	}
+	return 0
+}
+
+// outputIntentSubtype returns the subtype (S) of the output intent dictionary
+// or an empty string if the format needs no output intent.
+func (f Format) outputIntentSubtype() string {
+	switch {
+	case f.isPDFX():
+		return "/GTS_PDFX"
+	case f.isPDFA():
+		return "/GTS_PDFA1"
+	}
+	return ""
+}
+
+// pdfVersion returns the PDF version required by the format as major*10 +
+// minor, for example 16 for PDF 1.6.
+func (f Format) pdfVersion() int {
+	switch f {
+	case FormatPDFX3:
+		return 14
+	case FormatPDFX4:
+		return 16
+	}
+	return 17
+}
+
+// headerWriter holds back the PDF header until the first object is written,
+// so the version in the header follows the Format of the document which is
+// set after NewDocument.
+type headerWriter struct {
+	w       io.Writer
+	d       *PDFDocument
+	header  []byte
+	version int
+}
+
+func (hw *headerWriter) Write(p []byte) (int, error) {
+	if hw.version == 0 && hw.header == nil {
+		hw.header = append([]byte{}, p...)
+		return len(p), nil
+	}
+	if err := hw.flush(); err != nil {
+		return 0, err
+	}
+	return hw.w.Write(p)
+}
+
+// flush writes the header with the version of the document format. The
+// header has the same length as the one written by the PDF writer, so the
+// object offsets stay valid.
+func (hw *headerWriter) flush() error {
+	if hw.version != 0 {
+		return nil
+	}
+	hw.version = hw.d.Format.pdfVersion()
+	_, err := fmt.Fprintf(hw.w, "%%PDF-%d.%d", hw.version/10, hw.version%10)
+	hw.header = nil
+	return err
+}
+
+// useFeature records that the document uses a feature which needs at least
+// the given PDF version (major*10 + minor).
+func (d *PDFDocument) useFeature(feature string, version int) {
+	if d.features == nil {
+		d.features = make(map[string]int)
+	}
+	d.features[feature] = version
+}
+
+// contentColorSpaces reports whether the content stream sets RGB or CMYK
+// colors. Only the page content streams are scanned, the colors of images,
+// imported PDF pages and SVG graphics are not checked.
+func contentColorSpaces(content []byte) (rgb bool, cmyk bool) {
+	for _, tok := range bytes.Fields(content) {
+		switch string(tok) {
+		case "rg", "RG":
+			rgb = true
+		case "k", "K":
+			cmyk = true
+		}
+	}
+	return
+}
+
+// validateFormat checks the preconditions of the document format.
+func (d *PDFDocument) validateFormat() error {
+	if d.Format == FormatPDF {
+		return nil
+	}
+	version := d.Format.pdfVersion()
+	if hv := d.header.version; hv != 0 && hv != version {
+		return fmt.Errorf("%s: the PDF header is already written for PDF %d.%d, set the format before the first object is written", d.Format, hv/10, hv%10)
+	}
+	features := make([]string, 0, len(d.features))
+	for feature := range d.features {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	for _, feature := range features {
+		if v := d.features[feature]; v > version {
+			return fmt.Errorf("%s: %s requires PDF %d.%d, but the format is limited to PDF %d.%d", d.Format, feature, v/10, v%10, version/10, version%10)
+		}
+	}
+	if _, ok := d.features["transparency"]; ok && d.Format == FormatPDFX3 {
+		return fmt.Errorf("%s does not allow transparency", d.Format)
+	}
+	cp := d.ColorProfile
+	if cp == nil {
+		return fmt.Errorf("%s requires a color profile for the output intent", d.Format)
+	}
+	if cp.Colors != 3 && cp.Colors != 4 && cp.Colors != 1 {
+		return fmt.Errorf("%s: invalid number of colors (%d) in the color profile %q", d.Format, cp.Colors, cp.Identifier)
+	}
+	if d.rgbUsed && cp.Colors != 3 {
+		return fmt.Errorf("%s: RGB colors are used, but the output intent %q is not an RGB profile", d.Format, cp.Identifier)
+	}
+	if d.Format.isPDFA() && d.cmykUsed && cp.Colors != 4 {
+		return fmt.Errorf("%s: CMYK colors are used, but the output intent %q is not a CMYK profile", d.Format, cp.Identifier)
+	}
+	if cp.Identifier == "" {
+		return fmt.Errorf("%s: the color profile needs an output condition identifier", d.Format)
+	}
+	if d.Format.isPDFX() && d.Title == "" {
+		return fmt.Errorf("%s requires a document title", d.Format)
+	}
+	if d.Format == FormatPDFA2b {
+		for _, a := range d.attachments {
+			if a.MimeType != "application/pdf" {
+				return fmt.Errorf("%s only allows PDF/A files as attachments, use PDF/A-3 for %q", d.Format, a.Name)
+			}
+		}
+	}
+	for _, fce := range d.Faces {
+		if fce.HarfbuzzFont == nil {
+			return fmt.Errorf("%s: the font %q cannot be embedded", d.Format, fce.Filename)
+		}
+	}
+	return nil
+}
+
+// outputIntent returns the output intent dictionary for the color profile
+// object cpObj.
+func (d *PDFDocument) outputIntent(cpObj *pdf.Object) pdf.Dict {
+	cp := d.ColorProfile
+	oi := pdf.Dict{
+		"Type":                      "/OutputIntent",
+		"S":                         d.Format.outputIntentSubtype(),
+		"OutputConditionIdentifier": pdf.StringToPDF(cp.Identifier),
+		"DestOutputProfile":         cpObj.ObjectNumber.Ref(),
+	}
+	if cp.Registry != "" {
+		oi["RegistryName"] = pdf.StringToPDF(cp.Registry)
+	}
+	if cp.Info != "" {
+		oi["Info"] = pdf.StringToPDF(cp.Info)
+	}
+	if cp.Condition != "" {
+		oi["OutputCondition"] = pdf.StringToPDF(cp.Condition)
+	}
+	return oi
+}
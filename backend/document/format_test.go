@@ -0,0 +1,145 @@
+package document
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+func pdfxDocument(w *bytes.Buffer, format Format, pre string) *PDFDocument {
+	d := NewDocument(w)
+	d.Format = format
+	d.Title = "Test"
+	r := node.NewRule()
+	r.Width = bag.MustSp("1cm")
+	r.Height = bag.MustSp("1cm")
+	r.Pre = pre
+	d.NewPage()
+	d.CurrentPage.OutputAt(0, bag.MustSp("2cm"), node.Vpack(r))
+	d.CurrentPage.Shipout()
+	return d
+}
+
+func TestPDFXOutputIntent(t *testing.T) {
+	var w bytes.Buffer
+	d := pdfxDocument(&w, FormatPDFX4, "0 0 0 1 k")
+	if _, err := d.LoadDefaultColorprofile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	for _, want := range []string{"/OutputIntents", "/GTS_PDFX", "/GTS_PDFXVersion (PDF/X-4)", "/Trapped /False", "<pdfxid:GTS_PDFXVersion>PDF/X-4</pdfxid:GTS_PDFXVersion>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+}
+
+func TestPDFXValidation(t *testing.T) {
+	var w bytes.Buffer
+	d := pdfxDocument(&w, FormatPDFX3, "0 0 0 1 k")
+	if err := d.Finish(); err == nil || !strings.Contains(err.Error(), "color profile") {
+		t.Errorf("expected an error for the missing color profile, got %v", err)
+	}
+
+	w.Reset()
+	d = pdfxDocument(&w, FormatPDFX3, "1 0 0 rg")
+	if _, err := d.LoadDefaultColorprofile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Finish(); err == nil || !strings.Contains(err.Error(), "RGB") {
+		t.Errorf("expected an error for RGB colors, got %v", err)
+	}
+}
+
+func TestPDFA(t *testing.T) {
+	var w bytes.Buffer
+	d := pdfxDocument(&w, FormatPDFA3b, "1 0 0 rg")
+	d.Title = "Invoice"
+	d.Author = "ACME & Co"
+	d.CreationDate = time.Date(2023, 8, 31, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	if err := d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	for _, want := range []string{
+		"/S /GTS_PDFA1",
+		"<pdfaid:part>3</pdfaid:part>",
+		"<pdfaid:conformance>B</pdfaid:conformance>",
+		`<rdf:li xml:lang="x-default">Invoice</rdf:li>`,
+		"<rdf:li>ACME &amp; Co</rdf:li>",
+		"<xmp:CreateDate>2023-08-31T12:00:00+02:00</xmp:CreateDate>",
+		"/CreationDate (D:20230831120000+02'00')",
+		"/ModDate (D:20230831120000+02'00')",
+		"/ID [<",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+
+	w.Reset()
+	d = pdfxDocument(&w, FormatPDFA2b, "1 0 0 rg 0 0 0 1 k")
+	if err := d.Finish(); err == nil {
+		t.Errorf("expected an error for RGB and CMYK colors with one output intent")
+	}
+}
+
+func TestPDFXVersion(t *testing.T) {
+	testdata := []struct {
+		format Format
+		header string
+	}{
+		{FormatPDF, "%PDF-1.7"},
+		{FormatPDFX3, "%PDF-1.4"},
+		{FormatPDFX4, "%PDF-1.6"},
+		{FormatPDFA3b, "%PDF-1.7"},
+	}
+	for _, td := range testdata {
+		var w bytes.Buffer
+		d := pdfxDocument(&w, td.format, "0 0 0 1 k")
+		if _, err := d.LoadDefaultColorprofile(); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Finish(); err != nil {
+			t.Fatal(err)
+		}
+		if got := w.String()[:len(td.header)]; got != td.header {
+			t.Errorf("%s: header = %q, want %q", td.format, got, td.header)
+		}
+	}
+
+	// associated files need PDF 1.7
+	var w bytes.Buffer
+	d := pdfxDocument(&w, FormatPDFX4, "0 0 0 1 k")
+	if _, err := d.LoadDefaultColorprofile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.AttachFile("data.xml", "", "text/xml", []byte("<data/>"), FileRelationshipData); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Finish(); err == nil || !strings.Contains(err.Error(), "PDF 1.7") {
+		t.Errorf("expected an error for associated files in PDF/X-4, got %v", err)
+	}
+
+	// the header is written when the first object is saved, so the format
+	// can be changed after the pages are shipped out
+	w.Reset()
+	d = pdfxDocument(&w, FormatPDF, "0 0 0 1 k")
+	d.Format = FormatPDFX4
+	if _, err := d.LoadDefaultColorprofile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.String()[:8]; got != "%PDF-1.6" {
+		t.Errorf("header = %q, want %%PDF-1.6", got)
+	}
+}
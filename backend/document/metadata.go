@@ -10,6 +10,21 @@ import (
 
 var xmlescape = strings.NewReplacer("<", "&lt;", "&", "&amp;")
 
+// pdfDate returns the date in the PDF date format including the time zone, as
+// required for PDF/A where the Info dictionary and the XMP metadata must match.
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	if offset == 0 {
+		return t.Format("(D:20060102150405Z)")
+	}
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("(D:%s%c%02d'%02d')", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
 func (d *PDFDocument) getMetadata() string {
 	var dateFormat = time.RFC3339
 	docID := uuid.New().String()
@@ -20,19 +35,16 @@ func (d *PDFDocument) getMetadata() string {
 	 <rdf:Description rdf:about="" xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/">
 	   <xmpMM:DocumentID>uuid:%[2]s</xmpMM:DocumentID>
 	   <xmpMM:InstanceID>uuid:%[3]s</xmpMM:InstanceID>
-	 </rdf:Description>%[4]s
+	 </rdf:Description>%[4]s%[11]s
 	 <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
 		<xmp:CreateDate>%[5]s</xmp:CreateDate>
 		<xmp:ModifyDate>%[5]s</xmp:ModifyDate>
-		<xmp:MetadataDate>%[5]s</xmp:MetadataDate>
-		<xmp:CreatorTool>%[6]s</xmp:CreatorTool>
+		<xmp:MetadataDate>%[5]s</xmp:MetadataDate>%[6]s
 	 </rdf:Description>
 	 <rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">
-	   <pdf:Producer>%[7]s</pdf:Producer>%[10]s
+	   <pdf:Producer>%[7]s</pdf:Producer>%[10]s%[12]s
 	 </rdf:Description>
-	 <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
-	   <dc:title>%[8]s</dc:title>
-	   <dc:creator>%[9]s</dc:creator>
+	 <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">%[8]s%[9]s%[13]s
 	 </rdf:Description>
    </rdf:RDF>
  </x:xmpmeta>
@@ -42,16 +54,55 @@ func (d *PDFDocument) getMetadata() string {
 		instanceID = "fbb12364-c841-4d5e-b1b0-f53bd6e22649"
 	}
 	var pdfuaident string
-	var keywords string
+	var creatorTool, keywords, title, creator, description string
+	if d.Creator != "" {
+		creatorTool = fmt.Sprintf(`
+		<xmp:CreatorTool>%s</xmp:CreatorTool>`, xmlescape.Replace(d.Creator))
+	}
 	if d.Keywords != "" {
 		keywords = fmt.Sprintf(`
-        <pdf:keywords>%s</pdf:keywords>`, xmlescape.Replace(d.Keywords))
+        <pdf:Keywords>%s</pdf:Keywords>`, xmlescape.Replace(d.Keywords))
+	}
+	if d.Title != "" {
+		title = fmt.Sprintf(`
+	   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:title>`, xmlescape.Replace(d.Title))
+	}
+	if d.Author != "" {
+		creator = fmt.Sprintf(`
+	   <dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>`, xmlescape.Replace(d.Author))
+	}
+	if d.Subject != "" {
+		description = fmt.Sprintf(`
+	   <dc:description><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:description>`, xmlescape.Replace(d.Subject))
 	}
 	if d.RootStructureElement != nil {
 		pdfuaident = `
 <rdf:Description rdf:about="" xmlns:pdfuaid="http://www.aiim.org/pdfua/ns/id/">
 	<pdfuaid:part>1</pdfuaid:part>
 </rdf:Description>`
+	}
+	var formatident, trapped string
+	switch d.Format {
+	case FormatPDFX3:
+		formatident = `
+<rdf:Description rdf:about="" xmlns:pdfx="http://ns.adobe.com/pdfx/1.3/">
+	<pdfx:GTS_PDFXVersion>PDF/X-3:2003</pdfx:GTS_PDFXVersion>
+</rdf:Description>`
+	case FormatPDFX4:
+		formatident = `
+<rdf:Description rdf:about="" xmlns:pdfxid="http://www.npes.org/pdfx/ns/id/">
+	<pdfxid:GTS_PDFXVersion>PDF/X-4</pdfxid:GTS_PDFXVersion>
+</rdf:Description>`
+	case FormatPDFA2b, FormatPDFA3b:
+		formatident = fmt.Sprintf(`
+<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">
+	<pdfaid:part>%d</pdfaid:part>
+	<pdfaid:conformance>B</pdfaid:conformance>
+</rdf:Description>`, d.Format.pdfaPart())
+	}
+	if d.Format.isPDFX() {
+		trapped = `
+        <pdf:Trapped>False</pdf:Trapped>`
 	}
 	return fmt.Sprintf(str,
 		"\xEF\xBB\xBF",
@@ -59,11 +110,14 @@ func (d *PDFDocument) getMetadata() string {
 		instanceID,
 		pdfuaident,
 		d.CreationDate.Format(dateFormat),
-		xmlescape.Replace(d.Creator),
+		creatorTool,
 		xmlescape.Replace(d.producer),
-		xmlescape.Replace(d.Title),
-		xmlescape.Replace(d.Author),
+		title,
+		creator,
 		keywords,
+		formatident,
+		trapped,
+		description,
 	)
 
 }
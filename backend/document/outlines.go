@@ -1,8 +1,81 @@
 package document
 
-import pdf "github.com/speedata/baseline-pdf"
+import (
+	"fmt"
+
+	pdf "github.com/speedata/baseline-pdf"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+// Outline represents a PDF bookmark. The destination of the outline is a
+// StartStop node with the action node.ActionDest which must be shipped out
+// before the document is finished. Outlines with Open set to true show their
+// children in the PDF viewer.
+type Outline struct {
+	Children []*Outline
+	Title    string
+	Open     bool
+	Dest     *node.StartStop
+}
+
+// AddChild appends a new outline with the title and the destination to the
+// children of ol and returns the new outline.
+func (ol *Outline) AddChild(title string, dest *node.StartStop) *Outline {
+	cld := &Outline{
+		Title: title,
+		Dest:  dest,
+	}
+	ol.Children = append(ol.Children, cld)
+	return cld
+}
 
 // GetNumDest returns the PDF destination object with the internal number.
 func (pw *PDFDocument) GetNumDest(num int) *pdf.NumDest {
 	return pw.PDFWriter.NumDestinations[num]
 }
+
+// destString returns the PDF destination for the dest node. The node must be
+// shipped out before.
+func (pw *PDFDocument) destString(dest *node.StartStop) (string, error) {
+	if dest == nil {
+		return "null", nil
+	}
+	switch t := dest.Value.(type) {
+	case int:
+		nd := pw.GetNumDest(t)
+		if nd == nil {
+			return "", fmt.Errorf("destination %d not found", t)
+		}
+		return fmt.Sprintf("[%s /XYZ %s %s null]", nd.PageObjectnumber.Ref(), pdf.FloatToPoint(nd.X), pdf.FloatToPoint(nd.Y)), nil
+	case string:
+		for _, nd := range pw.PDFWriter.NameDestinations {
+			if string(nd.Name) == t {
+				return pdf.StringToPDF(t), nil
+			}
+		}
+		return "", fmt.Errorf("destination %q not found", t)
+	}
+	return "", fmt.Errorf("unknown destination type %T", dest.Value)
+}
+
+// pdfOutlines converts the outlines to the outline structure of the PDF
+// writer.
+func (pw *PDFDocument) pdfOutlines(outlines []*Outline) ([]*pdf.Outline, error) {
+	ret := make([]*pdf.Outline, 0, len(outlines))
+	for _, ol := range outlines {
+		dest, err := pw.destString(ol.Dest)
+		if err != nil {
+			return nil, fmt.Errorf("outline %q: %w", ol.Title, err)
+		}
+		pol := &pdf.Outline{
+			Title: ol.Title,
+			Open:  ol.Open,
+			Dest:  dest,
+		}
+		if pol.Children, err = pw.pdfOutlines(ol.Children); err != nil {
+			return nil, err
+		}
+		ret = append(ret, pol)
+	}
+	return ret, nil
+}
@@ -0,0 +1,55 @@
+package document
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+func TestDestString(t *testing.T) {
+	var w bytes.Buffer
+	d := NewDocument(&w)
+	numDest := node.NewStartStop()
+	numDest.Action = node.ActionDest
+	numDest.Value = 3
+	nameDest := node.NewStartStop()
+	nameDest.Action = node.ActionDest
+	nameDest.Value = "intro"
+
+	// not shipped out yet
+	for _, dest := range []*node.StartStop{numDest, nameDest} {
+		if _, err := d.destString(dest); err == nil {
+			t.Errorf("destString(%v) returns no error for a destination which is not shipped out", dest.Value)
+		}
+	}
+	if _, err := d.pdfOutlines([]*Outline{{Title: "Intro", Dest: nameDest}}); err == nil || !strings.Contains(err.Error(), `outline "Intro"`) {
+		t.Errorf("pdfOutlines() error = %v, want an error for the outline Intro", err)
+	}
+
+	r := node.NewRule()
+	r.Width = bag.MustSp("1cm")
+	r.Height = bag.MustSp("1cm")
+	head := node.InsertAfter(numDest, numDest, nameDest)
+	node.InsertAfter(head, nameDest, r)
+	d.NewPage()
+	d.CurrentPage.OutputAt(0, bag.MustSp("2cm"), node.Vpack(node.Hpack(head)))
+	d.CurrentPage.Shipout()
+
+	got, err := d.destString(numDest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^\[\d+ 0 R /XYZ 0 \S+ null\]$`).MatchString(got) {
+		t.Errorf("destString(3) = %q, want an explicit destination", got)
+	}
+	if got, err = d.destString(nameDest); err != nil || got != "(intro)" {
+		t.Errorf("destString(intro) = %q, %v, want (intro)", got, err)
+	}
+	if got, err = d.destString(nil); err != nil || got != "null" {
+		t.Errorf("destString(nil) = %q, %v, want null", got, err)
+	}
+}
@@ -0,0 +1,112 @@
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// defaultResolution is the resolution of bitmap images without resolution
+// information: one pixel is one point.
+const defaultResolution = 72
+
+// imageResolution returns the horizontal and vertical resolution (in dots per
+// inch) stored in a JPEG (JFIF) or PNG (pHYs) file. The boolean is false if
+// the file has no resolution information.
+func imageResolution(filename string, format string) (float64, float64, bool) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	switch format {
+	case "jpeg":
+		return jpegResolution(r)
+	case "png":
+		return pngResolution(r)
+	}
+	return 0, 0, false
+}
+
+// jpegResolution reads the density of the JFIF APP0 segment.
+func jpegResolution(r io.Reader) (float64, float64, bool) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return 0, 0, false
+	}
+	for {
+		var marker [4]byte
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xff {
+			return 0, 0, false
+		}
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		// stop at the start of the image data
+		if marker[1] == 0xda || length < 0 {
+			return 0, 0, false
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return 0, 0, false
+		}
+		if marker[1] != 0xe0 || len(data) < 12 || !bytes.HasPrefix(data, []byte("JFIF\x00")) {
+			continue
+		}
+		unit := data[7]
+		x := float64(binary.BigEndian.Uint16(data[8:]))
+		y := float64(binary.BigEndian.Uint16(data[10:]))
+		if x == 0 || y == 0 {
+			return 0, 0, false
+		}
+		switch unit {
+		case 1:
+			return x, y, true
+		case 2:
+			// dots per cm
+			return x * 2.54, y * 2.54, true
+		}
+		// only the aspect ratio
+		return 0, 0, false
+	}
+}
+
+// pngResolution reads the pHYs chunk.
+func pngResolution(r io.Reader) (float64, float64, bool) {
+	var sig [8]byte
+	if _, err := io.ReadFull(r, sig[:]); err != nil || string(sig[:]) != "\x89PNG\r\n\x1a\n" {
+		return 0, 0, false
+	}
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return 0, 0, false
+		}
+		length := int64(binary.BigEndian.Uint32(head[:4]))
+		switch string(head[4:]) {
+		case "pHYs":
+			var data [9]byte
+			if length != 9 {
+				return 0, 0, false
+			}
+			if _, err := io.ReadFull(r, data[:]); err != nil {
+				return 0, 0, false
+			}
+			x := float64(binary.BigEndian.Uint32(data[:4]))
+			y := float64(binary.BigEndian.Uint32(data[4:8]))
+			// unit 1 is dots per meter, 0 is only the aspect ratio
+			if data[8] != 1 || x == 0 || y == 0 {
+				return 0, 0, false
+			}
+			return x * 0.0254, y * 0.0254, true
+		case "IDAT", "IEND":
+			// pHYs must be before the image data
+			return 0, 0, false
+		}
+		// skip data and CRC
+		if _, err := io.CopyN(io.Discard, r, length+4); err != nil {
+			return 0, 0, false
+		}
+	}
+}
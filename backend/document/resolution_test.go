@@ -0,0 +1,66 @@
+package document
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func pngChunk(typ string, data []byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	b.WriteString(typ)
+	b.Write(data)
+	// the CRC is not checked
+	b.Write([]byte{0, 0, 0, 0})
+	return b.Bytes()
+}
+
+func TestPNGResolution(t *testing.T) {
+	phys := make([]byte, 9)
+	binary.BigEndian.PutUint32(phys, 11811) // 300 dpi
+	binary.BigEndian.PutUint32(phys[4:], 5906)
+	phys[8] = 1
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = append(data, pngChunk("IHDR", make([]byte, 13))...)
+	withPhys := append(append([]byte{}, data...), pngChunk("pHYs", phys)...)
+	x, y, ok := pngResolution(bytes.NewReader(withPhys))
+	if !ok || int(x+0.5) != 300 || int(y+0.5) != 150 {
+		t.Errorf("pngResolution() = %f, %f, %t, want 300, 150, true", x, y, ok)
+	}
+	withoutPhys := append(data, pngChunk("IDAT", make([]byte, 4))...)
+	if _, _, ok = pngResolution(bytes.NewReader(withoutPhys)); ok {
+		t.Error("pngResolution() without pHYs = true, want false")
+	}
+}
+
+func TestJPEGResolution(t *testing.T) {
+	testdata := []struct {
+		unit byte
+		x, y float64
+		ok   bool
+	}{
+		{1, 300, 300, true},
+		{2, 254, 254, true},
+		{0, 0, 0, false},
+	}
+	for _, tc := range testdata {
+		app0 := []byte("JFIF\x00\x01\x02")
+		app0 = append(app0, tc.unit)
+		if tc.unit == 2 {
+			app0 = binary.BigEndian.AppendUint16(app0, 100)
+			app0 = binary.BigEndian.AppendUint16(app0, 100)
+		} else {
+			app0 = binary.BigEndian.AppendUint16(app0, 300)
+			app0 = binary.BigEndian.AppendUint16(app0, 300)
+		}
+		app0 = append(app0, 0, 0)
+		data := []byte{0xff, 0xd8, 0xff, 0xe0}
+		data = binary.BigEndian.AppendUint16(data, uint16(len(app0)+2))
+		data = append(data, app0...)
+		x, y, ok := jpegResolution(bytes.NewReader(data))
+		if ok != tc.ok || int(x+0.5) != int(tc.x) || int(y+0.5) != int(tc.y) {
+			t.Errorf("jpegResolution() unit %d = %f, %f, %t, want %f, %f, %t", tc.unit, x, y, ok, tc.x, tc.y, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	pdf "github.com/speedata/baseline-pdf"
+	"github.com/speedata/boxesandglue/backend/color"
+)
+
+// pageResources is the /Resources dictionary of a page which uses graphics
+// states or shadings. The PDF writer builds the resources of the fonts, color spaces and
+// images itself and has no way to add other entries, so the complete
+// dictionary is written instead. It is a fmt.Stringer because the image
+// objects are created when the PDF writer finishes the document. The first
+// object which cannot be found is stored in err and returned by
+// PDFDocument.Finish.
+type pageResources struct {
+	pw        *pdf.PDF
+	page      *pdf.Page
+	extGState []string
+	shadings  []*shading
+	err       error
+}
+
+// objectRef returns the reference to the PDF object in the field of the font
+// face or image file. The PDF writer does not export these objects, so a new
+// version of the PDF writer can break this.
+func objectRef(v any, field string) (string, error) {
+	obj := reflect.ValueOf(v).Elem().FieldByName(field)
+	if !obj.IsValid() || obj.Kind() != reflect.Pointer || obj.IsNil() {
+		return "", fmt.Errorf("page resources: no PDF object in %T.%s", v, field)
+	}
+	num := obj.Elem().FieldByName("ObjectNumber")
+	if !num.IsValid() || !num.CanInt() || num.Int() <= 0 {
+		return "", fmt.Errorf("page resources: no object number in %T.%s", v, field)
+	}
+	return pdf.Objectnumber(num.Int()).Ref(), nil
+}
+
+// ref returns the reference of objectRef. Errors are stored in pr.err.
+func (pr *pageResources) ref(v any, field string) string {
+	ref, err := objectRef(v, field)
+	if err != nil {
+		if pr.err == nil {
+			pr.err = err
+		}
+		return "null"
+	}
+	return ref
+}
+
+func (pr *pageResources) String() string {
+	res := pdf.Dict{}
+	if len(pr.page.Faces) > 0 {
+		fonts := pdf.Dict{}
+		for _, face := range pr.page.Faces {
+			fonts[pdf.Name(face.InternalName())] = pr.ref(face, "fontobject")
+		}
+		res["Font"] = fonts
+	}
+	if len(pr.pw.Colorspaces) > 0 {
+		colorspace := pdf.Dict{}
+		for _, cs := range pr.pw.Colorspaces {
+			colorspace[pdf.Name(cs.ID)] = cs.Obj.String()
+		}
+		res["ColorSpace"] = colorspace
+	}
+	if len(pr.page.Images) > 0 {
+		images := make([]string, len(pr.page.Images))
+		for i, img := range pr.page.Images {
+			images[i] = img.InternalName() + " " + pr.ref(img, "imageobject")
+		}
+		res["XObject"] = "<< " + strings.Join(images, " ") + " >>"
+	}
+	if len(pr.extGState) > 0 {
+		gs := make([]string, len(pr.extGState))
+		for i, name := range pr.extGState {
+			fill, stroke, _ := color.ParseExtGStateName(name)
+			gs[i] = fmt.Sprintf("%s << /Type /ExtGState /ca %s /CA %s >>", name, strconv.FormatFloat(fill, 'f', -1, 64), strconv.FormatFloat(stroke, 'f', -1, 64))
+		}
+		res["ExtGState"] = "<< " + strings.Join(gs, " ") + " >>"
+	}
+	if len(pr.shadings) > 0 {
+		sh := make([]string, len(pr.shadings))
+		for i, s := range pr.shadings {
+			sh[i] = s.name + " " + s.obj.ObjectNumber.Ref()
+		}
+		res["Shading"] = "<< " + strings.Join(sh, " ") + " >>"
+	}
+	return res.String()
+}
+
+// operandNames returns the sorted names used as the operand of the operator
+// op in the content stream.
+func operandNames(content []byte, op string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	toks := bytes.Fields(content)
+	for i := 1; i < len(toks); i++ {
+		if string(toks[i]) != op {
+			continue
+		}
+		name := string(toks[i-1])
+		if strings.HasPrefix(name, "/") && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contentExtGStates returns the sorted names of the graphics states with
+// alpha values (see color.ExtGStateName) used in the content stream.
+func contentExtGStates(content []byte) []string {
+	var names []string
+	for _, name := range operandNames(content, "gs") {
+		if _, _, ok := color.ParseExtGStateName(name); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// shading is a shading dictionary registered with PDFDocument.Shading. The
+// PDF object is written when the first page with the shading is shipped
+// out.
+type shading struct {
+	name string
+	dict string
+	obj  *pdf.Object
+}
+
+// Shading registers the PDF shading dictionary and returns its resource name.
+// The shading is added to the resources of the pages which paint it with the
+// sh operator.
+func (d *PDFDocument) Shading(dict string) string {
+	if name, ok := d.shadingNames[dict]; ok {
+		return name
+	}
+	if d.shadings == nil {
+		d.shadings = make(map[string]*shading)
+		d.shadingNames = make(map[string]string)
+	}
+	name := fmt.Sprintf("/Sh%d", len(d.shadings)+1)
+	d.shadings[name] = &shading{name: name, dict: dict}
+	d.shadingNames[dict] = name
+	return name
+}
+
+// contentShadings returns the registered shadings which are painted in the
+// content stream. The shading objects are written if necessary.
+func (d *PDFDocument) contentShadings(content []byte) ([]*shading, error) {
+	var ret []*shading
+	for _, name := range operandNames(content, "sh") {
+		sh, ok := d.shadings[name]
+		if !ok {
+			continue
+		}
+		if sh.obj == nil {
+			sh.obj = d.PDFWriter.NewObject()
+			sh.obj.Raw = true
+			sh.obj.Data.WriteString(sh.dict)
+			if err := sh.obj.Save(); err != nil {
+				return nil, err
+			}
+			d.rgbUsed = d.rgbUsed || strings.Contains(sh.dict, "/DeviceRGB")
+			d.cmykUsed = d.cmykUsed || strings.Contains(sh.dict, "/DeviceCMYK")
+		}
+		ret = append(ret, sh)
+	}
+	return ret, nil
+}
@@ -0,0 +1,153 @@
+package document
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/color"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/fonts/camingocoderegular"
+)
+
+func TestContentExtGStates(t *testing.T) {
+	content := []byte("q /GSa0.5_1 gs 1 0 0 rg 0 0 10 10 re f Q /GS1 gs /GSa0.25_0.25 gs /GSa0.5_1 gs")
+	got := contentExtGStates(content)
+	want := []string{"/GSa0.25_0.25", "/GSa0.5_1"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("contentExtGStates() = %v, want %v", got, want)
+	}
+}
+
+func TestTransparency(t *testing.T) {
+	var w bytes.Buffer
+	d := pdfxDocument(&w, FormatPDFX4, color.PDFStringAlpha(0.5, 0.25)+" 0 0 0 1 k")
+	if _, err := d.LoadDefaultColorprofile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	for _, want := range []string{"/Resources", "/ExtGState << /GSa0.5_0.25 << /Type /ExtGState /ca 0.5 /CA 0.25 >> >>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+
+	// PDF/X-3 does not allow transparency
+	w.Reset()
+	d = pdfxDocument(&w, FormatPDFX3, color.PDFStringAlpha(0.5, 1)+" 0 0 0 1 k")
+	if _, err := d.LoadDefaultColorprofile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Finish(); err == nil || !strings.Contains(err.Error(), "transparency") {
+		t.Errorf("expected an error for transparency in PDF/X-3, got %v", err)
+	}
+}
+
+func TestShading(t *testing.T) {
+	var w bytes.Buffer
+	d := NewDocument(&w)
+	dict := "<< /ShadingType 2 /ColorSpace /DeviceRGB /Coords [0 0 10 0] /Function << /FunctionType 2 /Domain [0 1] /C0 [1 0 0] /C1 [0 0 1] /N 1 >> >>"
+	name := d.Shading(dict)
+	if again := d.Shading(dict); again != name {
+		t.Errorf("Shading() returns %q for the same dictionary, want %q", again, name)
+	}
+	r := node.NewRule()
+	r.Width = bag.MustSp("1cm")
+	r.Height = bag.MustSp("1cm")
+	r.Pre = "q 0 0 10 10 re W n " + name + " sh Q"
+	d.NewPage()
+	d.CurrentPage.OutputAt(0, bag.MustSp("2cm"), node.Vpack(r))
+	d.CurrentPage.Shipout()
+	if err := d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	if strings.Count(out, dict) != 1 {
+		t.Errorf("the shading dictionary should be written once")
+	}
+	if !regexp.MustCompile(`/Shading << /Sh1 \d+ 0 R >>`).MatchString(out) {
+		t.Errorf("the page resources have no shading")
+	}
+}
+
+func TestPageResourcesFontsAndImages(t *testing.T) {
+	var w bytes.Buffer
+	d := NewDocument(&w)
+	face, err := d.LoadFaceFromData(camingocoderegular.TTF, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fnt := d.CreateFont(face, bag.MustSp("10pt"))
+	// the rule sets a graphics state with alpha values, so the page resources
+	// are written by pageResources
+	r := node.NewRule()
+	r.Pre = color.PDFStringAlpha(0.5, 0.5)
+	var head, tail node.Node = r, r
+	for _, atom := range fnt.Shape("Hi", nil) {
+		g := node.NewGlyph()
+		g.Font = fnt
+		g.Codepoint = atom.Codepoint
+		g.Components = atom.Components
+		g.Width = atom.Advance
+		head = node.InsertAfter(head, tail, g)
+		tail = g
+	}
+	filename := filepath.Join(t.TempDir(), "logo.svg")
+	if err = os.WriteFile(filename, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="40" height="20"><rect width="40" height="20"/></svg>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imgf, err := d.LoadImageFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := node.NewImage()
+	img.Img = d.CreateImage(imgf, 1, "/MediaBox")
+	img.Width = img.Img.Width
+	img.Height = img.Img.Height
+	hl := node.Hpack(head)
+	d.NewPage()
+	d.CurrentPage.OutputAt(0, bag.MustSp("5cm"), node.Vpack(node.InsertAfter(hl, hl, img)))
+	d.CurrentPage.Shipout()
+	if err = d.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`/Font <<\s*/F\d+ (\d+) 0 R\s*>>`),
+		regexp.MustCompile(`/XObject << /ImgBag\d+ (\d+) 0 R >>`),
+	} {
+		m := re.FindStringSubmatch(out)
+		if m == nil {
+			t.Errorf("the page resources do not match %s", re)
+			continue
+		}
+		if !strings.Contains(out, "\n"+m[1]+" 0 obj") {
+			t.Errorf("the page resources refer to the missing object %s", m[1])
+		}
+	}
+	if !strings.Contains(out, "/ExtGState << /GSa0.5_0.5") {
+		t.Error("the page resources have no graphics state")
+	}
+}
+
+func TestObjectRef(t *testing.T) {
+	type face struct {
+		fontobject *struct{ ObjectNumber int }
+	}
+	if _, err := objectRef(&face{}, "fontobject"); err == nil {
+		t.Error("objectRef() returns no error for an object which is not written yet")
+	}
+	if _, err := objectRef(&face{}, "pdfobject"); err == nil {
+		t.Error("objectRef() returns no error for an unknown field")
+	}
+	if ref, err := objectRef(&face{fontobject: &struct{ ObjectNumber int }{12}}, "fontobject"); err != nil || ref != "12 0 R" {
+		t.Errorf("objectRef() = %q, %v, want 12 0 R", ref, err)
+	}
+}
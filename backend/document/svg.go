@@ -0,0 +1,73 @@
+package document
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	pdf "github.com/speedata/baseline-pdf"
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/svg"
+)
+
+// LoadSVG reads an SVG graphic from r and converts it to a PDF image file. The
+// graphic keeps its vector form, text is converted to paths with the fonts
+// from the CallbackSVGFont callback.
+func (d *PDFDocument) LoadSVG(r io.Reader) (*pdf.Imagefile, error) {
+	img, err := svg.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	// The PDF writer can only load image files from the disc, so the converted
+	// graphic is stored in a temporary directory until the document is
+	// finished.
+	if d.svgDir == "" {
+		if d.svgDir, err = os.MkdirTemp("", "boxesandglue-svg"); err != nil {
+			return nil, err
+		}
+	}
+	d.svgCount++
+	filename := filepath.Join(d.svgDir, fmt.Sprintf("svg%05d.pdf", d.svgCount))
+	w, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err = img.WritePDF(w, d.svgFontCallback); err != nil {
+		w.Close()
+		os.Remove(filename)
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		os.Remove(filename)
+		return nil, err
+	}
+	imgf, err := pdf.LoadImageFile(d.PDFWriter, filename)
+	if err != nil {
+		os.Remove(filename)
+		return nil, err
+	}
+	return imgf, nil
+}
+
+// loadSVGFile converts the SVG file to a PDF image file.
+func (d *PDFDocument) loadSVGFile(filename string) (*pdf.Imagefile, error) {
+	bag.Logger.Info("Load SVG", "filename", filename)
+	r, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return d.LoadSVG(r)
+}
+
+// removeSVGFiles removes the temporary files of the converted SVG graphics.
+// It is called when the document is finished, even if finishing fails.
+func (d *PDFDocument) removeSVGFiles() error {
+	if d.svgDir == "" {
+		return nil
+	}
+	err := os.RemoveAll(d.svgDir)
+	d.svgDir = ""
+	return err
+}
@@ -0,0 +1,40 @@
+package document
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+)
+
+func TestLoadSVG(t *testing.T) {
+	var w bytes.Buffer
+	d := NewDocument(&w)
+	filename := filepath.Join(t.TempDir(), "logo.svg")
+	if err := os.WriteFile(filename, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="40" height="20"><rect width="40" height="20" fill="red"/></svg>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imgf, err := d.LoadImageFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imgf.Format != "pdf" {
+		t.Errorf("Format = %s, want pdf", imgf.Format)
+	}
+	img := d.CreateImage(imgf, 1, "/MediaBox")
+	if img.Width != bag.MustSp("30pt") || img.Height != bag.MustSp("15pt") {
+		t.Errorf("image size = %s × %s, want 30pt × 15pt", img.Width, img.Height)
+	}
+	dir := d.svgDir
+	// the temporary files are removed even if the document can not be
+	// finished (PDF/X requires a color profile)
+	d.Format = FormatPDFX4
+	if err = d.Finish(); err == nil {
+		t.Error("expected an error for PDF/X without a color profile")
+	}
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("temporary directory %s not removed", dir)
+	}
+}
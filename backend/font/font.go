@@ -18,6 +18,10 @@ type Atom struct {
 	Codepoint  int
 	Hyphenate  bool
 	Kernafter  bag.ScaledPoint
+	// XOffset and YOffset are the glyph displacement from the current pen
+	// position, for example for mark positioning.
+	XOffset bag.ScaledPoint
+	YOffset bag.ScaledPoint
 }
 
 // Font is the main structure of a font instance
@@ -59,8 +63,17 @@ func NewFont(face *pdf.Face, size bag.ScaledPoint) *Font {
 	return fnt
 }
 
-// Shape transforms the text into a slice of code points.
+// Shape transforms the text into a slice of code points. The text direction
+// is guessed from the script of the text.
 func (f *Font) Shape(text string, features []harfbuzz.Feature) []Atom {
+	return f.ShapeDirection(text, features, 0)
+}
+
+// ShapeDirection transforms the text into a slice of code points using the
+// text direction dir (harfbuzz.LeftToRight or harfbuzz.RightToLeft, 0 to guess
+// the direction). The atoms are always returned in logical order, right to
+// left text must be reordered after line breaking.
+func (f *Font) ShapeDirection(text string, features []harfbuzz.Feature, dir harfbuzz.Direction) []Atom {
 	// empty paragraphs have ZERO WIDTH SPACE as a marker
 	if text == "\u200B" {
 		return []Atom{
@@ -77,8 +90,17 @@ func (f *Font) Shape(text string, features []harfbuzz.Feature) []Atom {
 	buf.Flags = harfbuzz.RemoveDefaultIgnorables
 	ha := f.Face.HarfbuzzFont.Face().HorizontalAdvance
 
+	buf.Props.Direction = dir
 	buf.GuessSegmentProperties()
 	buf.Shape(f.Face.HarfbuzzFont, features)
+	rtl := buf.Props.Direction == harfbuzz.RightToLeft
+	if rtl {
+		// harfbuzz returns the glyphs in visual order
+		for i, j := 0, len(buf.Info)-1; i < j; i, j = i+1, j-1 {
+			buf.Info[i], buf.Info[j] = buf.Info[j], buf.Info[i]
+			buf.Pos[i], buf.Pos[j] = buf.Pos[j], buf.Pos[i]
+		}
+	}
 	runes := []rune(text)
 	glyphs := make([]Atom, 0, len(buf.Info))
 	space := f.Face.Codepoint(' ')
@@ -101,17 +123,31 @@ func (f *Font) Shape(text string, features []harfbuzz.Feature) []Atom {
 
 			// only add kern if the next item is not a space
 			if i < len(buf.Info)-1 {
-				if buf.Info[i+1].Glyph != space {
-					bdelta = bag.ScaledPoint(float32(advanceCalculated) - advanceWant)
+				if next := buf.Info[i+1].Glyph; next != space {
+					if rtl {
+						// in right to left text the advance of the next
+						// (visually left) glyph contains the kerning.
+						bdelta = bag.ScaledPoint(float32(buf.Pos[i+1].XAdvance*int32(f.Mag)) - ha(next)*float32(f.Mag))
+					} else {
+						bdelta = bag.ScaledPoint(float32(advanceCalculated) - advanceWant)
+					}
 				}
 			}
+			height, depth := f.Size-f.Depth, f.Depth
+			if ext, ok := f.Face.HarfbuzzFont.GlyphExtents(r.Glyph); ok {
+				// the height of the extents is negative (top to bottom)
+				height = bag.Max(0, bag.ScaledPoint(ext.YBearing*int32(f.Mag)))
+				depth = bag.Max(0, bag.ScaledPoint(-(ext.YBearing+ext.Height)*int32(f.Mag)))
+			}
 			g := Atom{
 				Advance:   bag.ScaledPoint(advanceWant),
-				Height:    f.Size - f.Depth,
-				Depth:     f.Depth,
+				Height:    height,
+				Depth:     depth,
 				Hyphenate: unicode.IsLetter(char),
 				Codepoint: int(r.Glyph),
 				Kernafter: bdelta,
+				XOffset:   bag.ScaledPoint(buf.Pos[i].XOffset * int32(f.Mag)),
+				YOffset:   bag.ScaledPoint(buf.Pos[i].YOffset * int32(f.Mag)),
 			}
 			if i == lenBufInfo-1 {
 				// last element
@@ -1,10 +1,8 @@
 package node
 
 func getAttribute(a H, attr string) (any, bool) {
-	if a == nil {
-		return nil, false
-	}
-	return a[attr], true
+	v, ok := a[attr]
+	return v, ok
 }
 
 func setAttribute(a H, attr string, value any) H {
@@ -29,6 +27,8 @@ func GetAttribute(n Node, attr string) (any, bool) {
 		return getAttribute(t.Attributes, attr)
 	case *Image:
 		return getAttribute(t.Attributes, attr)
+	case *Insert:
+		return getAttribute(t.Attributes, attr)
 	case *Kern:
 		return getAttribute(t.Attributes, attr)
 	case *Lang:
@@ -58,6 +58,8 @@ func SetAttribute(n Node, attr string, val any) {
 		t.Attributes = setAttribute(t.Attributes, attr, val)
 	case *Image:
 		t.Attributes = setAttribute(t.Attributes, attr, val)
+	case *Insert:
+		t.Attributes = setAttribute(t.Attributes, attr, val)
 	case *Kern:
 		t.Attributes = setAttribute(t.Attributes, attr, val)
 	case *Lang:
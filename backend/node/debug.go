@@ -131,6 +131,14 @@ func debugNode(n Node, enc *xml.Encoder) {
 				{"id", v.ID},
 				{"filename", filename},
 			}, v.Attributes)
+		case *Insert:
+			err = encodeAttributes(enc, &start, []kv{
+				{"id", v.ID},
+				{"class", v.Class},
+			}, v.Attributes)
+			if v.List != nil {
+				debugNode(v.List, enc)
+			}
 		case *Kern:
 			err = encodeAttributes(enc, &start, []kv{
 				{"id", v.ID},
@@ -534,6 +534,15 @@ func Linebreak(n Node, settings *LinebreakSettings) (*VList, []*Breakpoint) {
 			leftskip.Width += lb.getIndent(e.Line)
 			startPos = InsertBefore(startPos, startPos, leftskip)
 			hl := HpackToWithEnd(startPos, endNode.Prev(), lb.settings.HSize, FontExpansion(lb.settings.FontExpansion))
+			// The line is as high as the fonts in the line (like a strut), so
+			// that the distance between the lines does not depend on the
+			// glyph extents.
+			for e := hl.List; e != nil; e = e.Next() {
+				if g, ok := e.(*Glyph); ok && g.Font != nil {
+					hl.Height = bag.Max(hl.Height, g.Font.Size-g.Font.Depth)
+					hl.Depth = bag.Max(hl.Depth, g.Font.Depth)
+				}
+			}
 			if hl.Attributes == nil {
 				hl.Attributes = H{"origin": "line"}
 			} else {
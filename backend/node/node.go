@@ -30,6 +30,8 @@ const (
 	TypeHList
 	// TypeImage is a Image node.
 	TypeImage
+	// TypeInsert is an Insert node.
+	TypeInsert
 	// TypeKern is a Kern node.
 	TypeKern
 	// TypeLang is a Lang node.
@@ -58,6 +60,8 @@ func (t Type) String() string {
 		return "HList"
 	case TypeImage:
 		return "Image"
+	case TypeInsert:
+		return "Insert"
 	case TypeKern:
 		return "Kern"
 	case TypeLang:
@@ -211,7 +215,7 @@ func init() {
 // IsNode returns true if the argument is a Node.
 func IsNode(arg any) bool {
 	switch arg.(type) {
-	case *Disc, *Glyph, *Glue, *Image, *HList, *Kern, *Lang, *StartStop, *VList:
+	case *Disc, *Glyph, *Glue, *Image, *HList, *Insert, *Kern, *Lang, *StartStop, *VList:
 		return true
 	}
 	return false
@@ -313,6 +317,9 @@ type Glyph struct {
 	// The Depth is the length below the base line. For example the letter g has
 	// a depth > 0.
 	Depth bag.ScaledPoint
+	// Horizontal displacement. Positive values move the glyph to the right
+	// without changing the advance width.
+	XOffset bag.ScaledPoint
 	// Vertical displacement. Positive values move the glyph towards the top of
 	// the page.
 	YOffset bag.ScaledPoint
@@ -369,6 +376,7 @@ func (g *Glyph) Copy() Node {
 	n.Height = g.Height
 	n.Depth = g.Depth
 	n.Hyphenate = g.Hyphenate
+	n.XOffset = g.XOffset
 	n.YOffset = g.YOffset
 	n.Hyphenate = g.Hyphenate
 	return n
@@ -424,6 +432,10 @@ type Glue struct {
 	Shrink       bag.ScaledPoint // The shrinkability of the glue, where width minus shrink = minimum width.
 	StretchOrder GlueOrder       // The order of infinity of stretching.
 	ShrinkOrder  GlueOrder       // The order of infinity of shrinking.
+	// Leader is repeated to fill the space of the glue in a horizontal list
+	// (for example dot leaders in a table of contents). The copies are aligned
+	// on a grid of the leader width starting at the beginning of the hlist.
+	Leader *HList
 }
 
 func (g *Glue) String() string {
@@ -473,6 +485,9 @@ func (g *Glue) Copy() Node {
 	n.Shrink = g.Shrink
 	n.StretchOrder = g.StretchOrder
 	n.ShrinkOrder = g.ShrinkOrder
+	if g.Leader != nil {
+		n.Leader = g.Leader.Copy().(*HList)
+	}
 	return n
 }
 
@@ -574,6 +589,84 @@ func IsHList(elt Node) (*HList, bool) {
 	return hlist, ok
 }
 
+// An Insert carries material which is not typeset where it appears in the node
+// list but collected by the page builder, for example the body of a footnote.
+// The insert has no dimensions.
+type Insert struct {
+	basenode
+	// Class distinguishes the kinds of inserts such as "footnote".
+	Class string
+	// List is the material to be placed. It can be nil if the material is
+	// built by the page builder from Value.
+	List *VList
+	// Value contains class specific contents
+	Value any
+}
+
+func (ins *Insert) String() string {
+	return String(ins)
+}
+
+// Next returns the following node or nil if no such node exists.
+func (ins *Insert) Next() Node {
+	return ins.next
+}
+
+// Prev returns the node preceding this node or nil if no such node exists.
+func (ins *Insert) Prev() Node {
+	return ins.prev
+}
+
+// SetNext sets the following node.
+func (ins *Insert) SetNext(n Node) {
+	ins.next = n
+}
+
+// SetPrev sets the preceding node.
+func (ins *Insert) SetPrev(n Node) {
+	ins.prev = n
+}
+
+// GetID returns the node id
+func (ins *Insert) GetID() int {
+	return ins.ID
+}
+
+// Name returns the name of the node
+func (ins *Insert) Name() string {
+	return "insert"
+}
+
+// Type returns the type of the node
+func (ins *Insert) Type() Type {
+	return TypeInsert
+}
+
+// Copy creates a deep copy of the node.
+func (ins *Insert) Copy() Node {
+	n := NewInsert()
+	n.Class = ins.Class
+	if ins.List != nil {
+		n.List = ins.List.Copy().(*VList)
+	}
+	n.Value = ins.Value
+	return n
+}
+
+// NewInsert creates an initialized Insert node
+func NewInsert() *Insert {
+	n := &Insert{}
+	n.ID = <-ids
+	return n
+}
+
+// IsInsert returns the value of the element and true, if the element is an
+// Insert node.
+func IsInsert(elt Node) (*Insert, bool) {
+	n, ok := elt.(*Insert)
+	return n, ok
+}
+
 // A Kern is a small space between glyphs.
 type Kern struct {
 	// The displacement in progression direction.
@@ -982,6 +1075,7 @@ type VList struct {
 	Width    bag.ScaledPoint
 	Height   bag.ScaledPoint
 	Depth    bag.ScaledPoint
+	Badness  int
 	GlueSet  float64
 	GlueSign uint8
 	ShiftX   bag.ScaledPoint
@@ -1033,6 +1127,7 @@ func (v *VList) Copy() Node {
 	n.Width = v.Width
 	n.Height = v.Height
 	n.Depth = v.Depth
+	n.Badness = v.Badness
 	n.GlueSet = v.GlueSet
 	n.GlueSign = v.GlueSign
 	n.ShiftX = v.ShiftX
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/font"
 )
 
 type gluTestData struct {
@@ -250,3 +251,205 @@ func TestLinebreakOneWord(t *testing.T) {
 		}
 	}
 }
+
+func TestLinebreakStrut(t *testing.T) {
+	fnt := &font.Font{Size: 10 * bag.Factor, Depth: 2 * bag.Factor}
+	var cur, head Node
+	for _, r := range "ace" {
+		g := NewGlyph()
+		g.Font = fnt
+		g.Width = 5 * bag.Factor
+		g.Height = 4 * bag.Factor
+		g.Components = string(r)
+		head = InsertAfter(head, cur, g)
+		cur = g
+	}
+	AppendLineEndAfter(head, cur)
+
+	settings := NewLinebreakSettings()
+	settings.HSize = 100 * bag.Factor
+	settings.LineHeight = 12 * bag.Factor
+
+	vl, _ := Linebreak(head, settings)
+	hl, ok := vl.List.(*HList)
+	if !ok {
+		t.Fatalf("first item of the paragraph is %T, want *HList", vl.List)
+	}
+	if got, want := hl.Height, 8*bag.Factor; got != want {
+		t.Errorf("hl.Height = %s, want %s", got, want)
+	}
+	if got, want := hl.Depth, 2*bag.Factor; got != want {
+		t.Errorf("hl.Depth = %s, want %s", got, want)
+	}
+}
+
+// mkParagraph returns a vlist with lines of 10pt height and 2pt lineskip glue.
+func mkParagraph(lines int) *VList {
+	var head, cur Node
+	for i := 0; i < lines; i++ {
+		if i > 0 {
+			g := NewGlue()
+			g.Width = 2 * bag.Factor
+			head = InsertAfter(head, cur, g)
+			cur = g
+		}
+		hl := NewHList()
+		hl.Height = 10 * bag.Factor
+		hl.Attributes = H{"origin": "line"}
+		head = InsertAfter(head, cur, hl)
+		cur = hl
+	}
+	return Vpack(head)
+}
+
+func TestVSplit(t *testing.T) {
+	// 10 lines, 118pt total
+	vl := mkParagraph(10)
+	settings := NewPagebreakSettings()
+	settings.WidowPenalty = 0
+	settings.ClubPenalty = 0
+	first, rest := VSplit(vl, 50*bag.Factor, settings)
+	if rest == nil {
+		t.Fatal("rest is nil, want remaining lines")
+	}
+	// four lines fit: 4 * 10pt + 3 * 2pt
+	if got, want := first.Height+first.Depth, 50*bag.Factor; got != want {
+		t.Errorf("first.Height = %s, want %s", got, want)
+	}
+	if got, want := first.Badness, 10000; got != want {
+		t.Errorf("first.Badness = %d, want %d", got, want)
+	}
+	if got, want := rest.Height, 70*bag.Factor; got != want {
+		t.Errorf("rest.Height = %s, want %s", got, want)
+	}
+	if _, ok := rest.List.(*HList); !ok {
+		t.Errorf("rest starts with %s, want hlist", rest.List.Name())
+	}
+
+	first, rest = VSplit(mkParagraph(2), 50*bag.Factor, settings)
+	if rest != nil {
+		t.Errorf("rest = %v, want nil", rest)
+	}
+	if got, want := first.Height, 22*bag.Factor; got != want {
+		t.Errorf("first.Height = %s, want %s", got, want)
+	}
+}
+
+func TestPageBreakWidow(t *testing.T) {
+	// 5 lines, a page holds 4 lines. The widow penalty moves the break up by
+	// one line.
+	settings := NewPagebreakSettings()
+	settings.PageHeight = 48 * bag.Factor
+	pages := PageBreak(mkParagraph(5), settings)
+	if len(pages) != 2 {
+		t.Fatalf("len(pages) = %d, want 2", len(pages))
+	}
+	lines := []int{}
+	for _, pg := range pages {
+		c := 0
+		for e := pg.List; e != nil; e = e.Next() {
+			if isLine(e) {
+				c++
+			}
+		}
+		lines = append(lines, c)
+	}
+	if lines[0] != 3 || lines[1] != 2 {
+		t.Errorf("lines per page = %v, want [3 2]", lines)
+	}
+
+	settings.WidowPenalty = 0
+	pages = PageBreak(mkParagraph(5), settings)
+	if len(pages) != 2 {
+		t.Fatalf("len(pages) = %d, want 2", len(pages))
+	}
+	if pages[1].Height != 10*bag.Factor {
+		t.Errorf("pages[1].Height = %s, want 10pt", pages[1].Height)
+	}
+}
+
+func TestPageBreakPenalty(t *testing.T) {
+	settings := NewPagebreakSettings()
+	settings.PageHeight = 100 * bag.Factor
+	p := NewPenalty()
+	p.Penalty = -10000
+	first := mkParagraph(2)
+	second := mkParagraph(2)
+	var head Node
+	head = InsertAfter(head, head, first)
+	head = InsertAfter(head, first, p)
+	head = InsertAfter(head, p, second)
+	pages := PageBreak(Vpack(head), settings)
+	if len(pages) != 2 {
+		t.Errorf("len(pages) = %d, want 2", len(pages))
+	}
+}
+
+func TestColumnBreak(t *testing.T) {
+	settings := NewPagebreakSettings()
+	settings.WidowPenalty = 0
+	settings.ClubPenalty = 0
+	// 10 lines, 118pt total: balanced into three columns of 4, 3 and 3 lines
+	cols, rest := ColumnBreak(mkParagraph(10), 3, 100*bag.Factor, true, settings)
+	if rest != nil {
+		t.Errorf("rest = %v, want nil", rest)
+	}
+	if len(cols) != 3 {
+		t.Fatalf("len(cols) = %d, want 3", len(cols))
+	}
+	for i, want := range []bag.ScaledPoint{46 * bag.Factor, 34 * bag.Factor, 34 * bag.Factor} {
+		if got := cols[i].Height + cols[i].Depth; got != want {
+			t.Errorf("cols[%d].Height = %s, want %s", i, got, want)
+		}
+	}
+
+	// not enough room: two full columns and the rest
+	cols, rest = ColumnBreak(mkParagraph(10), 2, 34*bag.Factor, true, settings)
+	if len(cols) != 2 {
+		t.Fatalf("len(cols) = %d, want 2", len(cols))
+	}
+	if rest == nil {
+		t.Fatal("rest is nil, want remaining lines")
+	}
+	if got, want := rest.Height, 46*bag.Factor; got != want {
+		t.Errorf("rest.Height = %s, want %s", got, want)
+	}
+}
+
+func TestGetAttribute(t *testing.T) {
+	ss := NewStartStop()
+	if _, ok := GetAttribute(ss, "origin"); ok {
+		t.Error("GetAttribute() on a node without attributes should fail")
+	}
+	SetAttribute(ss, "origin", "test")
+	if v, ok := GetAttribute(ss, "origin"); !ok || v != "test" {
+		t.Errorf("GetAttribute(origin) = %v, %t, want test, true", v, ok)
+	}
+	if _, ok := GetAttribute(ss, "textdecoration"); ok {
+		t.Error("GetAttribute() of a missing attribute should fail")
+	}
+}
+
+func TestCollectInserts(t *testing.T) {
+	fn1 := NewInsert()
+	fn1.Class = "footnote"
+	other := NewInsert()
+	other.Class = "float"
+	fn2 := NewInsert()
+	fn2.Class = "footnote"
+
+	r := NewRule()
+	r.Width = 10 * bag.Factor
+	line1 := Hpack(InsertAfter(r, r, fn1))
+	line2 := Hpack(InsertAfter(other, other, fn2))
+	vl := NewVList()
+	vl.List = InsertAfter(line1, line1, line2)
+	if got, want := line1.Width, 10*bag.Factor; got != want {
+		t.Errorf("line1.Width = %s, want %s", got, want)
+	}
+
+	got := CollectInserts(vl.List, "footnote")
+	if len(got) != 2 || got[0] != fn1 || got[1] != fn2 {
+		t.Errorf("CollectInserts() = %v, want [%v %v]", got, fn1, fn2)
+	}
+}
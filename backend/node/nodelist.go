@@ -141,6 +141,25 @@ func CopyList(nl Node) Node {
 	return copied
 }
 
+// CollectInserts returns the Insert nodes of the class in the node list
+// starting at n and in all nested lists in list order.
+func CollectInserts(n Node, class string) []*Insert {
+	var ret []*Insert
+	for e := n; e != nil; e = e.Next() {
+		switch t := e.(type) {
+		case *Insert:
+			if t.Class == class {
+				ret = append(ret, t)
+			}
+		case *HList:
+			ret = append(ret, CollectInserts(t.List, class)...)
+		case *VList:
+			ret = append(ret, CollectInserts(t.List, class)...)
+		}
+	}
+	return ret
+}
+
 // Dimensions returns the width of the node list starting at n. If dir is
 // Horizontal, then calculate in horizontal mode, otherwise in vertical mode.
 func Dimensions(start Node, stop Node, dir Direction) bag.ScaledPoint {
@@ -193,7 +212,7 @@ func Hpack(firstNode Node) *HList {
 			}
 		case *Kern:
 			sumwd += v.Kern
-		case *Lang:
+		case *Insert, *Lang:
 		case *Penalty:
 			sumwd += v.Width
 		case *VList:
@@ -399,6 +418,87 @@ func Vpack(firstNode Node) *VList {
 	return vl
 }
 
+// VpackTo creates a vertical list with the given height. The glue in the list
+// is stretched or shrunk to reach the desired height and the badness of the
+// resulting box is stored in the VList.
+func VpackTo(firstNode Node, height bag.ScaledPoint) *VList {
+	glues := []*Glue{}
+	sumht := bag.ScaledPoint(0)
+	maxwd := bag.ScaledPoint(0)
+	totalStretchability := [4]bag.ScaledPoint{0, 0, 0, 0}
+	totalShrinkability := [4]bag.ScaledPoint{0, 0, 0, 0}
+
+	var lastNode Node
+	for e := firstNode; e != nil; e = e.Next() {
+		if g, ok := e.(*Glue); ok {
+			totalStretchability[g.StretchOrder] += g.Stretch
+			totalShrinkability[g.ShrinkOrder] += g.Shrink
+			glues = append(glues, g)
+		}
+		ht, dp := getHeight(e, Vertical)
+		sumht += ht + dp
+		if wd := getWidth(e, Vertical); wd > maxwd {
+			maxwd = wd
+		}
+		lastNode = e
+	}
+
+	var highestOrderStretch, highestOrderShrink GlueOrder
+	stretchability, shrinkability := totalStretchability[0], totalShrinkability[0]
+	for i := GlueOrder(3); i > 0; i-- {
+		if totalStretchability[i] != 0 && highestOrderStretch < i {
+			highestOrderStretch = i
+			stretchability = totalStretchability[i]
+		}
+		if totalShrinkability[i] != 0 && highestOrderShrink < i {
+			highestOrderShrink = i
+			shrinkability = totalShrinkability[i]
+		}
+	}
+	var r float64
+	var glueSign uint8
+	if height == sumht {
+		r = 0
+	} else if sumht < height {
+		glueSign = 1
+		r = float64(height-sumht) / float64(stretchability)
+	} else {
+		glueSign = 2
+		r = float64(height-sumht) / float64(shrinkability)
+	}
+	badness := 10000
+	if r < -1 {
+		badness = 1000000
+	} else if !math.IsInf(r, 0) && !math.IsNaN(r) {
+		badness = int(math.Round(math.Pow(math.Abs(r), 3) * 100.0))
+		if badness > 10000 {
+			badness = 10000
+		}
+	}
+	if height == sumht || highestOrderShrink > 0 && glueSign == 2 || highestOrderStretch > 0 && glueSign == 1 {
+		badness = 0
+	}
+	if !math.IsInf(r, 0) && !math.IsNaN(r) {
+		for _, g := range glues {
+			if r > 0 && highestOrderStretch == g.StretchOrder {
+				g.Width += bag.ScaledPoint(r * float64(g.Stretch))
+			} else if r < 0 && highestOrderShrink == g.ShrinkOrder {
+				g.Width += bag.ScaledPoint(math.Max(r, -1) * float64(g.Shrink))
+			}
+		}
+	}
+
+	vl := NewVList()
+	vl.List = firstNode
+	vl.Depth = getDepth(lastNode)
+	vl.Height = height - vl.Depth
+	vl.Width = maxwd
+	vl.GlueSet = r
+	vl.GlueSign = glueSign
+	vl.Badness = badness
+	return vl
+}
+
 // Boxit draws a thin rectangle around the box.
 func Boxit(n Node) Node {
 	r := NewRule()
@@ -435,7 +535,7 @@ func getWidth(n Node, dir Direction) bag.ScaledPoint {
 		return t.Kern
 	case *VList:
 		return t.Width
-	case *StartStop, *Disc, *Lang:
+	case *StartStop, *Disc, *Insert, *Lang:
 		return 0
 	default:
 		// logger.Error(fmt.Sprintf("getWidth: unknown node type %T", n))
@@ -461,7 +561,7 @@ func getHeight(n Node, dir Direction) (bag.ScaledPoint, bag.ScaledPoint) {
 			return t.Width, 0
 		}
 		return 0, 0
-	case *StartStop, *Disc, *Lang, *Penalty, *Kern:
+	case *StartStop, *Disc, *Insert, *Lang, *Penalty, *Kern:
 		return 0, 0
 	default:
 		// logger.Error("getHeight: unknown node type %T", n)
@@ -477,7 +577,7 @@ func getDepth(n Node) bag.ScaledPoint {
 		return t.Depth
 	case *Rule:
 		return t.Depth
-	case *StartStop, *Disc, *Lang, *Glue, *Penalty, *Kern:
+	case *StartStop, *Disc, *Insert, *Lang, *Glue, *Penalty, *Kern:
 		return 0
 	case *VList:
 		return t.Depth
@@ -0,0 +1,428 @@
+package node
+
+import (
+	"math"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+)
+
+const (
+	// infBad is the badness of a box that cannot be stretched enough.
+	infBad = 10000
+	// awfulBad is the cost of a break that must not be taken (overfull pages).
+	awfulBad = math.MaxInt32
+	// deplorable is the cost of a page that is underfull but acceptable.
+	deplorable = 100000
+	// ejectPenalty is a penalty that forces a page break.
+	ejectPenalty = -10000
+)
+
+// PagebreakSettings controls the page breaking algorithm.
+type PagebreakSettings struct {
+	// ClubPenalty is added to a break after the first line of a paragraph.
+	ClubPenalty int
+	// WidowPenalty is added to a break before the last line of a paragraph.
+	WidowPenalty int
+	// FirstPageHeight is the height of the first page. If 0, PageHeight is used.
+	FirstPageHeight bag.ScaledPoint
+	// PageHeight is the height of all pages (or all but the first page, see
+	// FirstPageHeight).
+	PageHeight bag.ScaledPoint
+}
+
+// NewPagebreakSettings returns a settings struct with defaults initialized.
+func NewPagebreakSettings() *PagebreakSettings {
+	ps := &PagebreakSettings{
+		ClubPenalty:  150,
+		WidowPenalty: 150,
+	}
+	return ps
+}
+
+// vBreakItem is an item in a flattened vertical list. Paragraphs (vertical
+// lists of lines) are expanded so that a page can end between two lines.
+type vBreakItem struct {
+	n Node
+	// penalty is the additional penalty for breaking at this item (club and
+	// widow penalties).
+	penalty int
+}
+
+func isLine(n Node) bool {
+	if hl, ok := n.(*HList); ok {
+		if origin, ok := hl.Attributes["origin"]; ok && origin == "line" {
+			return true
+		}
+	}
+	return false
+}
+
+// isParagraph returns true if the vlist contains the lines of a paragraph and
+// can be broken between two lines without losing information.
+func isParagraph(vl *VList) bool {
+	if vl.ShiftX != 0 || vl.Attributes != nil {
+		return false
+	}
+	for e := vl.List; e != nil; e = e.Next() {
+		if isLine(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenVList collects the items of the node list starting at n and expands
+// paragraphs. The nodes are unlinked from each other.
+func flattenVList(n Node, settings *PagebreakSettings) []vBreakItem {
+	var items []vBreakItem
+	var lines []int
+	for e := n; e != nil; {
+		next := e.Next()
+		e.SetNext(nil)
+		e.SetPrev(nil)
+		if vl, ok := e.(*VList); ok && isParagraph(vl) {
+			items = append(items, flattenVList(vl.List, settings)...)
+		} else {
+			if isLine(e) {
+				lines = append(lines, len(items))
+			}
+			items = append(items, vBreakItem{n: e})
+		}
+		e = next
+	}
+	// The item after line i is a possible break point (usually the lineskip
+	// glue).
+	if l := len(lines); l > 1 {
+		for i, pos := range lines[:l-1] {
+			bp := pos + 1
+			if bp >= len(items) {
+				continue
+			}
+			if i == 0 {
+				items[bp].penalty += settings.ClubPenalty
+			}
+			if i == l-2 {
+				items[bp].penalty += settings.WidowPenalty
+			}
+		}
+	}
+	return items
+}
+
+func isDiscardable(n Node) bool {
+	switch n.(type) {
+	case *Glue, *Penalty, *Kern:
+		return true
+	}
+	return false
+}
+
+// linkItems links the nodes of the items and returns the head of the list.
+func linkItems(items []vBreakItem) Node {
+	var head, cur Node
+	for _, itm := range items {
+		if head == nil {
+			head = itm.n
+		} else {
+			cur.SetNext(itm.n)
+			itm.n.SetPrev(cur)
+		}
+		cur = itm.n
+	}
+	return head
+}
+
+// verticalBadness returns the badness of a vertical list with the natural
+// height ht and the given stretchability and shrinkability when set to height.
+func verticalBadness(ht, height bag.ScaledPoint, stretch [4]bag.ScaledPoint, shrink [4]bag.ScaledPoint) int {
+	if ht < height {
+		if stretch[StretchFil] != 0 || stretch[StretchFill] != 0 || stretch[StretchFilll] != 0 {
+			return 0
+		}
+		if stretch[StretchNormal] <= 0 {
+			return infBad
+		}
+		r := float64(height-ht) / float64(stretch[StretchNormal])
+		return int(math.Min(infBad, math.Round(math.Pow(r, 3)*100.0)))
+	} else if ht > height {
+		if shrink[StretchFil] != 0 || shrink[StretchFill] != 0 || shrink[StretchFilll] != 0 {
+			return 0
+		}
+		if ht-shrink[StretchNormal] > height {
+			return awfulBad
+		}
+		r := float64(ht-height) / float64(shrink[StretchNormal])
+		return int(math.Min(infBad, math.Round(math.Pow(r, 3)*100.0)))
+	}
+	return 0
+}
+
+// findVBreak returns the number of items that should go on a page with the
+// given height. The break item itself (glue or penalty) is the item at the
+// returned position. The second return value is the badness of the page.
+func findVBreak(items []vBreakItem, height bag.ScaledPoint) (int, int) {
+	var ht bag.ScaledPoint
+	var stretch, shrink [4]bag.ScaledPoint
+	bestPos := -1
+	bestBadness := 0
+	leastCost := awfulBad
+	prevBox := false
+	complete := true
+itemloop:
+	for i, itm := range items {
+		pi := 0
+		legal := false
+		switch t := itm.n.(type) {
+		case *Glue:
+			legal = prevBox
+			pi = itm.penalty
+		case *Penalty:
+			legal = t.Penalty < 10000
+			pi = t.Penalty
+			if pi > ejectPenalty {
+				pi += itm.penalty
+			}
+		}
+		if legal && i > 0 {
+			b := verticalBadness(ht, height, stretch, shrink)
+			var c int
+			switch {
+			case b >= awfulBad:
+				c = awfulBad
+			case pi <= ejectPenalty:
+				c = pi
+			case b < infBad:
+				c = b + pi
+			default:
+				// Unlike TeX the penalty is taken into account for
+				// underfull pages, otherwise club and widow penalties have
+				// no effect on lists without stretchable glue.
+				c = deplorable + pi
+			}
+			if c <= leastCost {
+				bestPos = i
+				bestBadness = b
+				leastCost = c
+			}
+			if c == awfulBad || pi <= ejectPenalty {
+				complete = false
+				break itemloop
+			}
+		}
+		switch t := itm.n.(type) {
+		case *Glue:
+			ht += t.Width
+			stretch[t.StretchOrder] += t.Stretch
+			shrink[t.ShrinkOrder] += t.Shrink
+		case *Kern:
+			ht += t.Kern
+		default:
+			h, d := getHeight(t, Vertical)
+			ht += h + d
+		}
+		prevBox = !isDiscardable(itm.n)
+	}
+	if complete {
+		// The end of the list is a forced break.
+		if b := verticalBadness(ht, height, stretch, shrink); b < awfulBad {
+			return len(items), b
+		}
+	}
+	if bestPos < 0 {
+		// Overfull box without a legal breakpoint before, take the first
+		// legal breakpoint after the first box or everything.
+		prevBox = false
+		for i, itm := range items {
+			switch t := itm.n.(type) {
+			case *Glue:
+				if prevBox {
+					return i, awfulBad
+				}
+			case *Penalty:
+				if prevBox && t.Penalty < 10000 {
+					return i, awfulBad
+				}
+			}
+			prevBox = prevBox || !isDiscardable(itm.n)
+		}
+		return len(items), awfulBad
+	}
+	return bestPos, bestBadness
+}
+
+// vsplitItems splits the items at the best break point for a page of the given
+// height and returns the vlist of the page and the remaining items.
+func vsplitItems(items []vBreakItem, height bag.ScaledPoint) (*VList, []vBreakItem) {
+	pos, badness := findVBreak(items, height)
+	page := items[:pos]
+	rest := items[pos:]
+	// remove discardable items at the top of the next page
+	for len(rest) > 0 && isDiscardable(rest[0].n) {
+		rest = rest[1:]
+	}
+	var vl *VList
+	if len(rest) == 0 {
+		vl = Vpack(linkItems(page))
+	} else {
+		vl = VpackTo(linkItems(page), height)
+	}
+	vl.Badness = badness
+	return vl, rest
+}
+
+// VSplit breaks the vertical list vl so that the first part fits into height.
+// The first return value is the part that fits (packed to height if there is
+// a remainder), the second value is the remaining vertical list or nil if the
+// whole list fits. Breaks are possible at glue nodes which follow a box and at
+// penalty nodes. Paragraphs in vl are broken between lines, subject to the
+// club and widow penalties of the settings. The badness of the first part is
+// stored in its Badness field.
+func VSplit(vl *VList, height bag.ScaledPoint, settings *PagebreakSettings) (*VList, *VList) {
+	if vl == nil {
+		return nil, nil
+	}
+	if settings == nil {
+		settings = NewPagebreakSettings()
+	}
+	items := flattenVList(vl.List, settings)
+	vl.List = nil
+	first, rest := vsplitItems(items, height)
+	if len(rest) == 0 {
+		return first, nil
+	}
+	return first, Vpack(linkItems(rest))
+}
+
+// PageBreak breaks the vertical list vl into parts with the page height given
+// in the settings. All but the last part are packed to the page height. The
+// badness of each part is stored in the Badness field of the VList.
+func PageBreak(vl *VList, settings *PagebreakSettings) []*VList {
+	if vl == nil {
+		return nil
+	}
+	if settings == nil {
+		settings = NewPagebreakSettings()
+	}
+	items := flattenVList(vl.List, settings)
+	vl.List = nil
+	height := settings.PageHeight
+	if settings.FirstPageHeight != 0 {
+		height = settings.FirstPageHeight
+	}
+	var pages []*VList
+	var page *VList
+	for len(items) > 0 {
+		page, items = vsplitItems(items, height)
+		pages = append(pages, page)
+		height = settings.PageHeight
+	}
+	return pages
+}
+
+// itemsHeight returns the natural height of the items.
+func itemsHeight(items []vBreakItem) bag.ScaledPoint {
+	var ht bag.ScaledPoint
+	for _, itm := range items {
+		switch t := itm.n.(type) {
+		case *Glue:
+			ht += t.Width
+		case *Kern:
+			ht += t.Kern
+		default:
+			h, d := getHeight(t, Vertical)
+			ht += h + d
+		}
+	}
+	return ht
+}
+
+// splitColumns returns the positions in items where the columns end if each
+// column has the given height. The last column takes the remaining items. The
+// second return value is false if the remaining items do not fit into the last
+// column.
+func splitColumns(items []vBreakItem, cols int, height bag.ScaledPoint) ([]int, bool) {
+	var ends []int
+	start := 0
+	for i := 0; i < cols-1 && start < len(items); i++ {
+		pos, badness := findVBreak(items[start:], height)
+		if badness == awfulBad {
+			return nil, false
+		}
+		pos += start
+		ends = append(ends, pos)
+		// remove discardable items at the top of the next column
+		for pos < len(items) && isDiscardable(items[pos].n) {
+			pos++
+		}
+		start = pos
+	}
+	ends = append(ends, len(items))
+	return ends, itemsHeight(items[start:]) <= height
+}
+
+// balanceColumns distributes the items into at most cols columns with the
+// smallest height that holds all items. The columns are packed to their natural
+// height.
+func balanceColumns(items []vBreakItem, cols int, height bag.ScaledPoint) []*VList {
+	for len(items) > 0 && isDiscardable(items[0].n) {
+		items = items[1:]
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	if cols == 1 {
+		return []*VList{Vpack(linkItems(items))}
+	}
+	// binary search for the smallest column height that holds the material
+	lo, hi := itemsHeight(items)/bag.ScaledPoint(cols), height
+	for hi-lo > bag.Factor/10 {
+		mid := (lo + hi) / 2
+		if _, ok := splitColumns(items, cols, mid); ok {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	// The first column gets the minimal height, the remaining material is
+	// balanced again to avoid a short last column.
+	pos, _ := findVBreak(items, hi)
+	rest := items[pos:]
+	columns := []*VList{Vpack(linkItems(items[:pos]))}
+	return append(columns, balanceColumns(rest, cols-1, hi)...)
+}
+
+// ColumnBreak distributes the vertical list vl into at most cols columns of the
+// given height. If balance is true and the whole list fits into the columns,
+// the columns are made approximately equally high and packed to their natural
+// height. A height of 0 means that the height is not limited, so all material
+// is balanced. The second return value is the remaining list which does not
+// fit into the columns or nil.
+func ColumnBreak(vl *VList, cols int, height bag.ScaledPoint, balance bool, settings *PagebreakSettings) ([]*VList, *VList) {
+	if vl == nil || cols < 1 {
+		return nil, vl
+	}
+	if settings == nil {
+		settings = NewPagebreakSettings()
+	}
+	items := flattenVList(vl.List, settings)
+	vl.List = nil
+	if height == 0 {
+		height = itemsHeight(items)
+		balance = true
+	}
+	if balance {
+		if _, fits := splitColumns(items, cols, height); fits {
+			return balanceColumns(items, cols, height), nil
+		}
+	}
+	var columns []*VList
+	var col *VList
+	for i := 0; i < cols && len(items) > 0; i++ {
+		col, items = vsplitItems(items, height)
+		columns = append(columns, col)
+	}
+	if len(items) == 0 {
+		return columns, nil
+	}
+	return columns, Vpack(linkItems(items))
+}
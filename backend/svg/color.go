@@ -0,0 +1,155 @@
+package svg
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// rgb is an RGB color with components from 0 to 1.
+type rgb struct {
+	r, g, b float64
+}
+
+func (c rgb) components() string {
+	return num(c.r) + " " + num(c.g) + " " + num(c.b)
+}
+
+type paintKind int
+
+const (
+	paintNone paintKind = iota
+	paintColor
+	paintURL
+)
+
+// paint is the value of the fill and stroke properties. For a reference to a
+// gradient the color is the fallback.
+type paint struct {
+	kind paintKind
+	col  rgb
+	ref  string
+}
+
+// parsePaint interprets a fill or stroke value. currentColor is the value of
+// the color property. The boolean is false for unknown values.
+func parsePaint(s string, currentColor rgb) (paint, bool) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "none":
+		return paint{kind: paintNone}, true
+	case strings.HasPrefix(s, "url("):
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return paint{}, false
+		}
+		p := paint{kind: paintURL, ref: strings.Trim(strings.TrimSpace(s[4:end]), `"'#`)}
+		// the fallback is used when the reference is invalid
+		if fallback, ok := parsePaint(s[end+1:], currentColor); ok && fallback.kind == paintColor {
+			p.col = fallback.col
+		}
+		return p, true
+	}
+	col, ok := parseColor(s, currentColor)
+	if !ok {
+		return paint{}, false
+	}
+	return paint{kind: paintColor, col: col}, true
+}
+
+// parseColor interprets an SVG color (keyword, #rgb, #rrggbb or rgb()).
+func parseColor(s string, currentColor rgb) (rgb, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "currentcolor" {
+		return currentColor, true
+	}
+	if hex, ok := colorKeywords[s]; ok {
+		s = hex
+	}
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		switch len(hex) {
+		case 3, 4:
+			hex = hex[:3]
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		case 6, 8:
+			hex = hex[:6]
+		default:
+			return rgb{}, false
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return rgb{}, false
+		}
+		return rgb{float64(v>>16) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255}, true
+	}
+	if strings.HasPrefix(s, "rgb(") || strings.HasPrefix(s, "rgba(") {
+		start := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if end < start {
+			return rgb{}, false
+		}
+		args := strings.FieldsFunc(s[start+1:end], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '/'
+		})
+		if len(args) < 3 {
+			return rgb{}, false
+		}
+		var c [3]float64
+		for i := range c {
+			a := args[i]
+			max := 255.0
+			if strings.HasSuffix(a, "%") {
+				a, max = a[:len(a)-1], 100
+			}
+			f, err := strconv.ParseFloat(a, 64)
+			if err != nil {
+				return rgb{}, false
+			}
+			c[i] = math.Max(0, math.Min(1, f/max))
+		}
+		return rgb{c[0], c[1], c[2]}, true
+	}
+	return rgb{}, false
+}
+
+// colorKeywords are the SVG color keywords.
+var colorKeywords = map[string]string{
+	"aliceblue": "#f0f8ff", "antiquewhite": "#faebd7", "aqua": "#00ffff", "aquamarine": "#7fffd4",
+	"azure": "#f0ffff", "beige": "#f5f5dc", "bisque": "#ffe4c4", "black": "#000000",
+	"blanchedalmond": "#ffebcd", "blue": "#0000ff", "blueviolet": "#8a2be2", "brown": "#a52a2a",
+	"burlywood": "#deb887", "cadetblue": "#5f9ea0", "chartreuse": "#7fff00", "chocolate": "#d2691e",
+	"coral": "#ff7f50", "cornflowerblue": "#6495ed", "cornsilk": "#fff8dc", "crimson": "#dc143c",
+	"cyan": "#00ffff", "darkblue": "#00008b", "darkcyan": "#008b8b", "darkgoldenrod": "#b8860b",
+	"darkgray": "#a9a9a9", "darkgreen": "#006400", "darkgrey": "#a9a9a9", "darkkhaki": "#bdb76b",
+	"darkmagenta": "#8b008b", "darkolivegreen": "#556b2f", "darkorange": "#ff8c00", "darkorchid": "#9932cc",
+	"darkred": "#8b0000", "darksalmon": "#e9967a", "darkseagreen": "#8fbc8f", "darkslateblue": "#483d8b",
+	"darkslategray": "#2f4f4f", "darkslategrey": "#2f4f4f", "darkturquoise": "#00ced1", "darkviolet": "#9400d3",
+	"deeppink": "#ff1493", "deepskyblue": "#00bfff", "dimgray": "#696969", "dimgrey": "#696969",
+	"dodgerblue": "#1e90ff", "firebrick": "#b22222", "floralwhite": "#fffaf0", "forestgreen": "#228b22",
+	"fuchsia": "#ff00ff", "gainsboro": "#dcdcdc", "ghostwhite": "#f8f8ff", "gold": "#ffd700",
+	"goldenrod": "#daa520", "gray": "#808080", "grey": "#808080", "green": "#008000",
+	"greenyellow": "#adff2f", "honeydew": "#f0fff0", "hotpink": "#ff69b4", "indianred": "#cd5c5c",
+	"indigo": "#4b0082", "ivory": "#fffff0", "khaki": "#f0e68c", "lavender": "#e6e6fa",
+	"lavenderblush": "#fff0f5", "lawngreen": "#7cfc00", "lemonchiffon": "#fffacd", "lightblue": "#add8e6",
+	"lightcoral": "#f08080", "lightcyan": "#e0ffff", "lightgoldenrodyellow": "#fafad2", "lightgray": "#d3d3d3",
+	"lightgreen": "#90ee90", "lightgrey": "#d3d3d3", "lightpink": "#ffb6c1", "lightsalmon": "#ffa07a",
+	"lightseagreen": "#20b2aa", "lightskyblue": "#87cefa", "lightslategray": "#778899", "lightslategrey": "#778899",
+	"lightsteelblue": "#b0c4de", "lightyellow": "#ffffe0", "lime": "#00ff00", "limegreen": "#32cd32",
+	"linen": "#faf0e6", "magenta": "#ff00ff", "maroon": "#800000", "mediumaquamarine": "#66cdaa",
+	"mediumblue": "#0000cd", "mediumorchid": "#ba55d3", "mediumpurple": "#9370db", "mediumseagreen": "#3cb371",
+	"mediumslateblue": "#7b68ee", "mediumspringgreen": "#00fa9a", "mediumturquoise": "#48d1cc", "mediumvioletred": "#c71585",
+	"midnightblue": "#191970", "mintcream": "#f5fffa", "mistyrose": "#ffe4e1", "moccasin": "#ffe4b5",
+	"navajowhite": "#ffdead", "navy": "#000080", "oldlace": "#fdf5e6", "olive": "#808000",
+	"olivedrab": "#6b8e23", "orange": "#ffa500", "orangered": "#ff4500", "orchid": "#da70d6",
+	"palegoldenrod": "#eee8aa", "palegreen": "#98fb98", "paleturquoise": "#afeeee", "palevioletred": "#db7093",
+	"papayawhip": "#ffefd5", "peachpuff": "#ffdab9", "peru": "#cd853f", "pink": "#ffc0cb",
+	"plum": "#dda0dd", "powderblue": "#b0e0e6", "purple": "#800080", "rebeccapurple": "#663399",
+	"red": "#ff0000", "rosybrown": "#bc8f8f", "royalblue": "#4169e1", "saddlebrown": "#8b4513",
+	"salmon": "#fa8072", "sandybrown": "#f4a460", "seagreen": "#2e8b57", "seashell": "#fff5ee",
+	"sienna": "#a0522d", "silver": "#c0c0c0", "skyblue": "#87ceeb", "slateblue": "#6a5acd",
+	"slategray": "#708090", "slategrey": "#708090", "snow": "#fffafa", "springgreen": "#00ff7f",
+	"steelblue": "#4682b4", "tan": "#d2b48c", "teal": "#008080", "thistle": "#d8bfd8",
+	"tomato": "#ff6347", "turquoise": "#40e0d0", "violet": "#ee82ee", "wheat": "#f5deb3",
+	"white": "#ffffff", "whitesmoke": "#f5f5f5", "yellow": "#ffff00", "yellowgreen": "#9acd32",
+}
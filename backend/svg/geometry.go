@@ -0,0 +1,187 @@
+package svg
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseLength returns the length in user units (CSS pixels). Percentages are
+// relative to ref, em to fontSize. Invalid lengths are 0.
+func parseLength(s string, ref float64, fontSize float64) float64 {
+	s = strings.TrimSpace(s)
+	unit := 1.0
+	switch {
+	case strings.HasSuffix(s, "%"):
+		s, unit = s[:len(s)-1], ref/100
+	case strings.HasSuffix(s, "px"):
+		s = s[:len(s)-2]
+	case strings.HasSuffix(s, "pt"):
+		s, unit = s[:len(s)-2], 4.0/3.0
+	case strings.HasSuffix(s, "pc"):
+		s, unit = s[:len(s)-2], 16
+	case strings.HasSuffix(s, "mm"):
+		s, unit = s[:len(s)-2], 96/25.4
+	case strings.HasSuffix(s, "cm"):
+		s, unit = s[:len(s)-2], 96/2.54
+	case strings.HasSuffix(s, "in"):
+		s, unit = s[:len(s)-2], 96
+	case strings.HasSuffix(s, "em"):
+		s, unit = s[:len(s)-2], fontSize
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return f * unit
+}
+
+// parseNumbers returns the numbers in a list separated by white space and
+// commas.
+func parseNumbers(s string) []float64 {
+	p := pathParser{s: s}
+	var ret []float64
+	for {
+		p.skipSeparators()
+		f, ok := p.number()
+		if !ok {
+			break
+		}
+		ret = append(ret, f)
+	}
+	return ret
+}
+
+// parseViewBox returns min-x, min-y, width and height of the viewBox
+// attribute.
+func parseViewBox(s string) ([4]float64, bool) {
+	var vb [4]float64
+	nums := parseNumbers(s)
+	if len(nums) != 4 || nums[2] <= 0 || nums[3] <= 0 {
+		return vb, false
+	}
+	copy(vb[:], nums)
+	return vb, true
+}
+
+// matrix is a transformation matrix in PDF order (a b c d e f).
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// String returns the matrix as the operands of the cm operator.
+func (m matrix) String() string {
+	s := make([]string, 6)
+	for i, f := range m {
+		s[i] = num(f)
+	}
+	return strings.Join(s, " ")
+}
+
+// multiply returns the matrix that applies n first and then m.
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		n[0]*m[0] + n[1]*m[2],
+		n[0]*m[1] + n[1]*m[3],
+		n[2]*m[0] + n[3]*m[2],
+		n[2]*m[1] + n[3]*m[3],
+		n[4]*m[0] + n[5]*m[2] + m[4],
+		n[4]*m[1] + n[5]*m[3] + m[5],
+	}
+}
+
+// parseTransform interprets the transform attribute.
+func parseTransform(s string) matrix {
+	m := identity
+	for {
+		open := strings.IndexByte(s, '(')
+		closing := strings.IndexByte(s, ')')
+		if open < 0 || closing < open {
+			break
+		}
+		name := strings.TrimSpace(strings.Trim(s[:open], " \t\r\n,"))
+		args := parseNumbers(s[open+1 : closing])
+		s = s[closing+1:]
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+		var t matrix
+		switch name {
+		case "matrix":
+			if len(args) != 6 {
+				continue
+			}
+			copy(t[:], args)
+		case "translate":
+			t = matrix{1, 0, 0, 1, arg(0, 0), arg(1, 0)}
+		case "scale":
+			sx := arg(0, 1)
+			t = matrix{sx, 0, 0, arg(1, sx), 0, 0}
+		case "rotate":
+			a := arg(0, 0) * math.Pi / 180
+			cx, cy := arg(1, 0), arg(2, 0)
+			t = matrix{1, 0, 0, 1, cx, cy}.
+				multiply(matrix{math.Cos(a), math.Sin(a), -math.Sin(a), math.Cos(a), 0, 0}).
+				multiply(matrix{1, 0, 0, 1, -cx, -cy})
+		case "skewX":
+			t = matrix{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			t = matrix{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			continue
+		}
+		m = m.multiply(t)
+	}
+	return m
+}
+
+// viewBoxTransform returns the transformation from the view box to a viewport
+// with the size w × h according to the preserveAspectRatio attribute par.
+func viewBoxTransform(vb [4]float64, w, h float64, par string) matrix {
+	sx, sy := w/vb[2], h/vb[3]
+	fields := strings.Fields(par)
+	align, slice := "xMidYMid", false
+	if len(fields) > 0 && fields[0] == "defer" {
+		fields = fields[1:]
+	}
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+	if len(fields) > 1 {
+		slice = fields[1] == "slice"
+	}
+	if align == "none" {
+		return matrix{sx, 0, 0, sy, -vb[0] * sx, -vb[1] * sy}
+	}
+	s := math.Min(sx, sy)
+	if slice {
+		s = math.Max(sx, sy)
+	}
+	tx, ty := -vb[0]*s, -vb[1]*s
+	switch {
+	case strings.HasPrefix(align, "xMid"):
+		tx += (w - vb[2]*s) / 2
+	case strings.HasPrefix(align, "xMax"):
+		tx += w - vb[2]*s
+	}
+	switch {
+	case strings.HasSuffix(align, "YMid"):
+		ty += (h - vb[3]*s) / 2
+	case strings.HasSuffix(align, "YMax"):
+		ty += h - vb[3]*s
+	}
+	return matrix{s, 0, 0, s, tx, ty}
+}
+
+// num formats a number for the PDF content stream.
+func num(f float64) string {
+	f = math.Round(f*10000) / 10000
+	if f == 0 {
+		// no negative zero
+		return "0"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
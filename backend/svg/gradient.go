@@ -0,0 +1,169 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type gradientStop struct {
+	offset float64
+	col    rgb
+}
+
+// gradientRef returns the gradient referenced by the href attribute of g or
+// nil.
+func (r *renderer) gradientRef(g *element) *element {
+	if g == nil {
+		return nil
+	}
+	if ref := r.img.ids[strings.TrimPrefix(g.attr("href", ""), "#")]; ref != g {
+		return ref
+	}
+	return nil
+}
+
+// gradientAttr returns the attribute of the gradient. Missing attributes are
+// taken from the referenced gradients.
+func (r *renderer) gradientAttr(g *element, name, def string) string {
+	for i := 0; g != nil && i < maxUseDepth; i++ {
+		if v, ok := g.attrs[name]; ok {
+			return v
+		}
+		g = r.gradientRef(g)
+	}
+	return def
+}
+
+// gradientStops returns the stops of the gradient or of the first referenced
+// gradient with stops. The offsets are sorted and between 0 and 1.
+func (r *renderer) gradientStops(g *element) []gradientStop {
+	for i := 0; g != nil && i < maxUseDepth; i++ {
+		var stops []gradientStop
+		last := 0.0
+		for _, c := range g.children {
+			if c.name != "stop" {
+				continue
+			}
+			offset, _ := parseOpacity(c.attr("offset", "0"))
+			// offsets must not decrease
+			offset = math.Max(offset, last)
+			last = offset
+			col, ok := parseColor(c.props["stop-color"], rgb{})
+			if !ok {
+				col = rgb{}
+			}
+			stops = append(stops, gradientStop{offset: offset, col: col})
+		}
+		if len(stops) > 0 {
+			return stops
+		}
+		g = r.gradientRef(g)
+	}
+	return nil
+}
+
+// gradientExtension is the length of the gradient extension beyond the
+// start and the end of the gradient vector (in multiples of the vector).
+// Boolean arrays are not preserved when the PDF is imported into the
+// document, so the /Extend entry of the shading can't be used.
+const gradientExtension = 100
+
+// stopFunction returns a PDF function that interpolates the colors of the
+// gradient stops between 0 and 1. Outside of this range the colors of the
+// first and the last stop are used up to lo and hi.
+func stopFunction(stops []gradientStop, lo, hi float64) string {
+	if first := stops[0]; first.offset > lo {
+		stops = append([]gradientStop{{offset: lo, col: first.col}}, stops...)
+	}
+	if last := stops[len(stops)-1]; last.offset < hi {
+		stops = append(stops, gradientStop{offset: hi, col: last.col})
+	}
+	var functions, bounds, encode []string
+	for i := 1; i < len(stops); i++ {
+		from, to := stops[i-1], stops[i]
+		if to.offset <= from.offset {
+			// hard color change
+			continue
+		}
+		if len(functions) > 0 {
+			bounds = append(bounds, num(from.offset))
+		}
+		functions = append(functions, fmt.Sprintf("<< /FunctionType 2 /Domain [0 1] /C0 [%s] /C1 [%s] /N 1 >>", from.col.components(), to.col.components()))
+		encode = append(encode, "0 1")
+	}
+	return fmt.Sprintf("<< /FunctionType 3 /Domain [%s %s] /Functions [%s] /Bounds [%s] /Encode [%s] >>",
+		num(lo), num(hi), strings.Join(functions, " "), strings.Join(bounds, " "), strings.Join(encode, " "))
+}
+
+// shading returns the operators to paint the gradient with the id ref into
+// the current clipping path. p is the path to fill, its bounding box is used
+// for gradients with objectBoundingBox units. The boolean is false if there
+// is no such gradient.
+func (r *renderer) shading(ref string, p path) (string, bool) {
+	g := r.img.ids[ref]
+	if g == nil || (g.name != "linearGradient" && g.name != "radialGradient") {
+		r.warn("SVG paint server not supported", "id", ref)
+		return "", false
+	}
+	stops := r.gradientStops(g)
+	if len(stops) == 0 {
+		return "", false
+	}
+	m := identity
+	boundingBox := r.gradientAttr(g, "gradientUnits", "objectBoundingBox") == "objectBoundingBox"
+	if boundingBox {
+		x, y, w, h := p.bbox()
+		if w == 0 || h == 0 {
+			return "", false
+		}
+		m = matrix{w, 0, 0, h, x, y}
+	}
+	if gt := r.gradientAttr(g, "gradientTransform", ""); gt != "" {
+		m = m.multiply(parseTransform(gt))
+	}
+	vw, vh := r.viewport()
+	coord := func(name, def string, ref float64) float64 {
+		v := r.gradientAttr(g, name, def)
+		if boundingBox {
+			if strings.HasSuffix(v, "%") {
+				f, _ := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+				return f / 100
+			}
+			f, _ := strconv.ParseFloat(v, 64)
+			return f
+		}
+		return parseLength(v, ref, 16)
+	}
+	var shadingType int
+	var coords []float64
+	lo, hi := 0.0, 1.0+gradientExtension
+	if g.name == "linearGradient" {
+		shadingType = 2
+		x1, y1 := coord("x1", "0%", vw), coord("y1", "0%", vh)
+		x2, y2 := coord("x2", "100%", vw), coord("y2", "0%", vh)
+		lo = -gradientExtension
+		coords = []float64{
+			x1 + lo*(x2-x1), y1 + lo*(y2-y1),
+			x1 + hi*(x2-x1), y1 + hi*(y2-y1),
+		}
+	} else {
+		shadingType = 3
+		diag := r.diagonal()
+		cx, cy := coord("cx", "50%", vw), coord("cy", "50%", vh)
+		fx, fy := coord("fx", num(cx), vw), coord("fy", num(cy), vh)
+		fr, rad := coord("fr", "0%", diag), coord("r", "50%", diag)
+		coords = []float64{
+			fx, fy, fr,
+			fx + hi*(cx-fx), fy + hi*(cy-fy), fr + hi*(rad-fr),
+		}
+	}
+	c := make([]string, len(coords))
+	for i, f := range coords {
+		c[i] = num(f)
+	}
+	r.shadings = append(r.shadings, fmt.Sprintf("<< /ShadingType %d /ColorSpace /DeviceRGB /Coords [%s] /Domain [%s %s] /Function %s >>",
+		shadingType, strings.Join(c, " "), num(lo), num(hi), stopFunction(stops, lo, hi)))
+	return fmt.Sprintf("%s cm /Sh%d sh", m, len(r.shadings)), true
+}
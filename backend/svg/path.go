@@ -0,0 +1,390 @@
+package svg
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+type segmentOp int
+
+const (
+	opMoveTo segmentOp = iota
+	opLineTo
+	opCurveTo
+	opClose
+)
+
+// segment is a part of a path with absolute coordinates. Curves are cubic
+// Bézier curves with the control points in pts[0] and pts[1] and the end
+// point in pts[2].
+type segment struct {
+	op  segmentOp
+	pts [3]point
+}
+
+type point struct {
+	x, y float64
+}
+
+// path is a list of segments.
+type path []segment
+
+func (p *path) moveTo(x, y float64) {
+	*p = append(*p, segment{op: opMoveTo, pts: [3]point{{x, y}}})
+}
+
+func (p *path) lineTo(x, y float64) {
+	*p = append(*p, segment{op: opLineTo, pts: [3]point{{x, y}}})
+}
+
+func (p *path) curveTo(x1, y1, x2, y2, x, y float64) {
+	*p = append(*p, segment{op: opCurveTo, pts: [3]point{{x1, y1}, {x2, y2}, {x, y}}})
+}
+
+func (p *path) close() {
+	*p = append(*p, segment{op: opClose})
+}
+
+// String returns the path construction operators.
+func (p path) String() string {
+	var sb strings.Builder
+	for _, seg := range p {
+		switch seg.op {
+		case opMoveTo:
+			sb.WriteString(num(seg.pts[0].x) + " " + num(seg.pts[0].y) + " m ")
+		case opLineTo:
+			sb.WriteString(num(seg.pts[0].x) + " " + num(seg.pts[0].y) + " l ")
+		case opCurveTo:
+			for _, pt := range seg.pts {
+				sb.WriteString(num(pt.x) + " " + num(pt.y) + " ")
+			}
+			sb.WriteString("c ")
+		case opClose:
+			sb.WriteString("h ")
+		}
+	}
+	return sb.String()
+}
+
+// bbox returns the bounding box of the points of the path (including control
+// points) as x, y, width, height.
+func (p path) bbox() (float64, float64, float64, float64) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, seg := range p {
+		n := 1
+		switch seg.op {
+		case opClose:
+			n = 0
+		case opCurveTo:
+			n = 3
+		}
+		for _, pt := range seg.pts[:n] {
+			minX, maxX = math.Min(minX, pt.x), math.Max(maxX, pt.x)
+			minY, maxY = math.Min(minY, pt.y), math.Max(maxY, pt.y)
+		}
+	}
+	if minX > maxX {
+		return 0, 0, 0, 0
+	}
+	return minX, minY, maxX - minX, maxY - minY
+}
+
+// kappa is the distance of the control points for a quarter circle with
+// radius 1.
+const kappa = 0.5522847498
+
+// ellipse appends an ellipse with the center cx, cy.
+func (p *path) ellipse(cx, cy, rx, ry float64) {
+	kx, ky := rx*kappa, ry*kappa
+	p.moveTo(cx+rx, cy)
+	p.curveTo(cx+rx, cy+ky, cx+kx, cy+ry, cx, cy+ry)
+	p.curveTo(cx-kx, cy+ry, cx-rx, cy+ky, cx-rx, cy)
+	p.curveTo(cx-rx, cy-ky, cx-kx, cy-ry, cx, cy-ry)
+	p.curveTo(cx+kx, cy-ry, cx+rx, cy-ky, cx+rx, cy)
+	p.close()
+}
+
+// rect appends a rectangle with optional rounded corners.
+func (p *path) rect(x, y, w, h, rx, ry float64) {
+	if rx <= 0 || ry <= 0 {
+		p.moveTo(x, y)
+		p.lineTo(x+w, y)
+		p.lineTo(x+w, y+h)
+		p.lineTo(x, y+h)
+		p.close()
+		return
+	}
+	kx, ky := rx*kappa, ry*kappa
+	p.moveTo(x+rx, y)
+	p.lineTo(x+w-rx, y)
+	p.curveTo(x+w-rx+kx, y, x+w, y+ry-ky, x+w, y+ry)
+	p.lineTo(x+w, y+h-ry)
+	p.curveTo(x+w, y+h-ry+ky, x+w-rx+kx, y+h, x+w-rx, y+h)
+	p.lineTo(x+rx, y+h)
+	p.curveTo(x+rx-kx, y+h, x, y+h-ry+ky, x, y+h-ry)
+	p.lineTo(x, y+ry)
+	p.curveTo(x, y+ry-ky, x+rx-kx, y, x+rx, y)
+	p.close()
+}
+
+// arcTo appends an elliptical arc from the current point (x0, y0) to (x, y) as
+// described in the SVG specification (implementation notes, F.6).
+func (p *path) arcTo(x0, y0, rx, ry, angle float64, largeArc, sweep bool, x, y float64) {
+	if x0 == x && y0 == y {
+		return
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		p.lineTo(x, y)
+		return
+	}
+	phi := angle * math.Pi / 180
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+	dx, dy := (x0-x)/2, (y0-y)/2
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+	// scale up radii that are too small
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	numerator := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	denominator := rx*rx*y1*y1 + ry*ry*x1*x1
+	f := math.Sqrt(math.Max(0, numerator/denominator))
+	if largeArc == sweep {
+		f = -f
+	}
+	cxp, cyp := f*rx*y1/ry, -f*ry*x1/rx
+	cx := cosPhi*cxp - sinPhi*cyp + (x0+x)/2
+	cy := sinPhi*cxp + cosPhi*cyp + (y0+y)/2
+	vecAngle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta1 := vecAngle(1, 0, (x1-cxp)/rx, (y1-cyp)/ry)
+	delta := vecAngle((x1-cxp)/rx, (y1-cyp)/ry, (-x1-cxp)/rx, (-y1-cyp)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+	// split into segments of at most 90 degrees
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	d := delta / float64(n)
+	t := 4.0 / 3.0 * math.Tan(d/4)
+	pt := func(a float64) (float64, float64) {
+		ex, ey := rx*math.Cos(a), ry*math.Sin(a)
+		return cosPhi*ex - sinPhi*ey + cx, sinPhi*ex + cosPhi*ey + cy
+	}
+	deriv := func(a float64) (float64, float64) {
+		ex, ey := -rx*math.Sin(a), ry*math.Cos(a)
+		return cosPhi*ex - sinPhi*ey, sinPhi*ex + cosPhi*ey
+	}
+	a := theta1
+	for i := 0; i < n; i++ {
+		sx, sy := pt(a)
+		dsx, dsy := deriv(a)
+		ex, ey := pt(a + d)
+		dex, dey := deriv(a + d)
+		if i == n-1 {
+			ex, ey = x, y
+		}
+		p.curveTo(sx+t*dsx, sy+t*dsy, ex-t*dex, ey-t*dey, ex, ey)
+		a += d
+	}
+}
+
+// pathParser reads path data and number lists.
+type pathParser struct {
+	s   string
+	pos int
+}
+
+func (pp *pathParser) skipSeparators() {
+	for pp.pos < len(pp.s) && strings.IndexByte(" \t\r\n,", pp.s[pp.pos]) >= 0 {
+		pp.pos++
+	}
+}
+
+// number reads a number. Numbers do not need a separator if the next one
+// starts with a sign or a second decimal point ("1-2", "0.5.5").
+func (pp *pathParser) number() (float64, bool) {
+	start := pp.pos
+	i := pp.pos
+	if i < len(pp.s) && (pp.s[i] == '+' || pp.s[i] == '-') {
+		i++
+	}
+	digits, dot := false, false
+	for i < len(pp.s) {
+		c := pp.s[i]
+		if c >= '0' && c <= '9' {
+			digits = true
+		} else if c == '.' && !dot {
+			dot = true
+		} else {
+			break
+		}
+		i++
+	}
+	if !digits {
+		return 0, false
+	}
+	if i < len(pp.s) && (pp.s[i] == 'e' || pp.s[i] == 'E') {
+		j := i + 1
+		if j < len(pp.s) && (pp.s[j] == '+' || pp.s[j] == '-') {
+			j++
+		}
+		if j < len(pp.s) && pp.s[j] >= '0' && pp.s[j] <= '9' {
+			for j < len(pp.s) && pp.s[j] >= '0' && pp.s[j] <= '9' {
+				j++
+			}
+			i = j
+		}
+	}
+	f, err := strconv.ParseFloat(pp.s[start:i], 64)
+	if err != nil {
+		return 0, false
+	}
+	pp.pos = i
+	return f, true
+}
+
+// flag reads an arc flag which can be written without a separator.
+func (pp *pathParser) flag() (bool, bool) {
+	pp.skipSeparators()
+	if pp.pos < len(pp.s) && (pp.s[pp.pos] == '0' || pp.s[pp.pos] == '1') {
+		pp.pos++
+		return pp.s[pp.pos-1] == '1', true
+	}
+	return false, false
+}
+
+// numbers reads n numbers into args and reports whether this was
+// successful.
+func (pp *pathParser) numbers(args []float64) bool {
+	for i := range args {
+		pp.skipSeparators()
+		f, ok := pp.number()
+		if !ok {
+			return false
+		}
+		args[i] = f
+	}
+	return true
+}
+
+// parsePath interprets the path data (d attribute). Parsing stops at the
+// first error, the path up to this point is returned.
+func parsePath(d string) path {
+	var p path
+	pp := &pathParser{s: d}
+	var cx, cy float64   // current point
+	var sx, sy float64   // start of the sub path
+	var lcx, lcy float64 // last control point
+	var lastCmd byte
+	var cmd byte
+	args := make([]float64, 7)
+	for {
+		pp.skipSeparators()
+		if pp.pos >= len(pp.s) {
+			break
+		}
+		if c := pp.s[pp.pos]; strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) >= 0 {
+			cmd = c
+			pp.pos++
+		} else if cmd == 0 || cmd == 'Z' || cmd == 'z' {
+			break
+		} else if cmd == 'M' {
+			// coordinates after a moveto are implicit lineto commands
+			cmd = 'L'
+		} else if cmd == 'm' {
+			cmd = 'l'
+		}
+		rel := cmd >= 'a'
+		ox, oy := 0.0, 0.0
+		if rel {
+			ox, oy = cx, cy
+		}
+		ok := true
+		switch cmd {
+		case 'M', 'm':
+			if ok = pp.numbers(args[:2]); ok {
+				cx, cy = ox+args[0], oy+args[1]
+				sx, sy = cx, cy
+				p.moveTo(cx, cy)
+			}
+		case 'L', 'l':
+			if ok = pp.numbers(args[:2]); ok {
+				cx, cy = ox+args[0], oy+args[1]
+				p.lineTo(cx, cy)
+			}
+		case 'H', 'h':
+			if ok = pp.numbers(args[:1]); ok {
+				cx = ox + args[0]
+				p.lineTo(cx, cy)
+			}
+		case 'V', 'v':
+			if ok = pp.numbers(args[:1]); ok {
+				cy = oy + args[0]
+				p.lineTo(cx, cy)
+			}
+		case 'C', 'c':
+			if ok = pp.numbers(args[:6]); ok {
+				lcx, lcy = ox+args[2], oy+args[3]
+				p.curveTo(ox+args[0], oy+args[1], lcx, lcy, ox+args[4], oy+args[5])
+				cx, cy = ox+args[4], oy+args[5]
+			}
+		case 'S', 's':
+			if ok = pp.numbers(args[:4]); ok {
+				x1, y1 := cx, cy
+				if strings.IndexByte("CcSs", lastCmd) >= 0 {
+					x1, y1 = 2*cx-lcx, 2*cy-lcy
+				}
+				lcx, lcy = ox+args[0], oy+args[1]
+				p.curveTo(x1, y1, lcx, lcy, ox+args[2], oy+args[3])
+				cx, cy = ox+args[2], oy+args[3]
+			}
+		case 'Q', 'q', 'T', 't':
+			var qx, qy, x, y float64
+			if cmd == 'Q' || cmd == 'q' {
+				if ok = pp.numbers(args[:4]); ok {
+					qx, qy, x, y = ox+args[0], oy+args[1], ox+args[2], oy+args[3]
+				}
+			} else if ok = pp.numbers(args[:2]); ok {
+				qx, qy = cx, cy
+				if strings.IndexByte("QqTt", lastCmd) >= 0 {
+					qx, qy = 2*cx-lcx, 2*cy-lcy
+				}
+				x, y = ox+args[0], oy+args[1]
+			}
+			if ok {
+				// quadratic to cubic curve
+				p.curveTo(cx+2.0/3.0*(qx-cx), cy+2.0/3.0*(qy-cy), x+2.0/3.0*(qx-x), y+2.0/3.0*(qy-y), x, y)
+				lcx, lcy = qx, qy
+				cx, cy = x, y
+			}
+		case 'A', 'a':
+			var largeArc, sweep bool
+			if ok = pp.numbers(args[:3]); ok {
+				if largeArc, ok = pp.flag(); ok {
+					if sweep, ok = pp.flag(); ok {
+						ok = pp.numbers(args[3:5])
+					}
+				}
+			}
+			if ok {
+				x, y := ox+args[3], oy+args[4]
+				p.arcTo(cx, cy, args[0], args[1], args[2], largeArc, sweep, x, y)
+				cx, cy = x, y
+			}
+		case 'Z', 'z':
+			p.close()
+			cx, cy = sx, sy
+		}
+		if !ok {
+			break
+		}
+		lastCmd = cmd
+	}
+	return p
+}
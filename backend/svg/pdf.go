@@ -0,0 +1,54 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	pdf "github.com/speedata/baseline-pdf"
+)
+
+// WritePDF writes the graphic as a single page PDF file to w. The size of the
+// page is the size of the graphic. Text is converted to paths with the fonts
+// returned by fonts. If fonts is nil, text is not rendered.
+func (img *Image) WritePDF(w io.Writer, fonts FontFunc) error {
+	r := &renderer{
+		img:     img,
+		fonts:   fonts,
+		faces:   make(map[string]*pdf.Face),
+		gsNames: make(map[string]string),
+		warned:  make(map[string]bool),
+	}
+	// CSS pixels
+	wd, ht := img.Width/0.75, img.Height/0.75
+	// the y axis of SVG points downwards
+	r.sb.WriteString(matrix{0.75, 0, 0, -0.75, 0, img.Height}.String() + " cm\n")
+	r.viewportChildren(img.root, r.computeStyle(defaultStyle(), img.root), wd, ht)
+
+	pw := pdf.NewPDFWriter(w)
+	content := pw.NewObject()
+	content.Data.WriteString(r.sb.String())
+	content.SetCompression(9)
+	page := pw.AddPage(content, pw.NextObject())
+	page.Width = img.Width
+	page.Height = img.Height
+	resources := pdf.Dict{}
+	if len(r.extGStates) > 0 {
+		gs := make([]string, len(r.extGStates))
+		for i, dict := range r.extGStates {
+			gs[i] = fmt.Sprintf("/GS%d %s", i+1, dict)
+		}
+		resources["ExtGState"] = "<< " + strings.Join(gs, " ") + " >>"
+	}
+	if len(r.shadings) > 0 {
+		sh := make([]string, len(r.shadings))
+		for i, dict := range r.shadings {
+			sh[i] = fmt.Sprintf("/Sh%d %s", i+1, dict)
+		}
+		resources["Shading"] = "<< " + strings.Join(sh, " ") + " >>"
+	}
+	if len(resources) > 0 {
+		page.Dict = pdf.Dict{"Resources": resources}
+	}
+	return pw.Finish()
+}
@@ -0,0 +1,661 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	pdf "github.com/speedata/baseline-pdf"
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/font"
+	"github.com/speedata/textlayout/fonts"
+)
+
+// FontFunc returns the font face for a font family with the given weight
+// (100 to 900) and style. It returns nil if there is no such font family.
+type FontFunc func(family string, weight int, italic bool) *pdf.Face
+
+// maxUseDepth limits nested use elements to prevent endless loops.
+const maxUseDepth = 20
+
+// maxElements limits the number of rendered elements. Use elements can
+// reference groups with use elements, so the output can grow exponentially
+// with the nesting depth.
+const maxElements = 100000
+
+// style contains the computed properties of an element.
+type style struct {
+	fill          paint
+	stroke        paint
+	fillOpacity   float64
+	strokeOpacity float64
+	// opacity is the product of the opacity of the element and its
+	// ancestors. Group opacity is applied to each element.
+	opacity     float64
+	strokeWidth float64
+	lineCap     int
+	lineJoin    int
+	miterLimit  float64
+	dashArray   []float64
+	dashOffset  float64
+	evenOdd     bool
+	color       rgb
+	fontFamily  string
+	fontSize    float64
+	fontWeight  int
+	italic      bool
+	textAnchor  string
+	visible     bool
+}
+
+func defaultStyle() style {
+	return style{
+		fill:          paint{kind: paintColor},
+		fillOpacity:   1,
+		strokeOpacity: 1,
+		opacity:       1,
+		strokeWidth:   1,
+		miterLimit:    4,
+		fontFamily:    "serif",
+		fontSize:      16,
+		fontWeight:    400,
+		textAnchor:    "start",
+		visible:       true,
+	}
+}
+
+// renderer creates the PDF content stream and the resources.
+type renderer struct {
+	img        *Image
+	fonts      FontFunc
+	faces      map[string]*pdf.Face
+	sb         strings.Builder
+	extGStates []string
+	gsNames    map[string]string
+	shadings   []string
+	viewports  [][2]float64
+	useDepth   int
+	elements   int
+	warned     map[string]bool
+}
+
+func (r *renderer) warn(msg string, args ...any) {
+	key := fmt.Sprint(msg, args)
+	if r.warned[key] {
+		return
+	}
+	r.warned[key] = true
+	bag.Logger.Warn(msg, args...)
+}
+
+// viewport returns the size of the current viewport in user units.
+func (r *renderer) viewport() (float64, float64) {
+	vp := r.viewports[len(r.viewports)-1]
+	return vp[0], vp[1]
+}
+
+// length resolves a length attribute. Percentages refer to the width of the
+// viewport for horizontal values and to the height for vertical values.
+func (r *renderer) length(e *element, name string, horizontal bool, st style) float64 {
+	w, h := r.viewport()
+	ref := h
+	if horizontal {
+		ref = w
+	}
+	return parseLength(e.attr(name, "0"), ref, st.fontSize)
+}
+
+// diagonal is the reference for percentages that are neither horizontal nor
+// vertical.
+func (r *renderer) diagonal() float64 {
+	w, h := r.viewport()
+	return math.Sqrt((w*w + h*h) / 2)
+}
+
+func parseOpacity(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	div := 1.0
+	if strings.HasSuffix(s, "%") {
+		s, div = s[:len(s)-1], 100
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return math.Max(0, math.Min(1, f/div)), true
+}
+
+// computeStyle returns the style of the element that inherits from parent.
+func (r *renderer) computeStyle(parent style, e *element) style {
+	st := parent
+	// group opacity is not inherited but accumulated
+	st.opacity = parent.opacity
+	props := e.props
+	if v, ok := props["color"]; ok && v != "inherit" {
+		if col, ok := parseColor(v, parent.color); ok {
+			st.color = col
+		}
+	}
+	for k, v := range props {
+		if v == "inherit" {
+			continue
+		}
+		switch k {
+		case "fill", "stroke":
+			if v == "transparent" {
+				v = "none"
+			}
+			p, ok := parsePaint(v, st.color)
+			if !ok {
+				r.warn("unsupported SVG paint", "value", v)
+				continue
+			}
+			if k == "fill" {
+				st.fill = p
+			} else {
+				st.stroke = p
+			}
+		case "fill-opacity", "stroke-opacity", "opacity":
+			o, ok := parseOpacity(v)
+			if !ok {
+				continue
+			}
+			switch k {
+			case "fill-opacity":
+				st.fillOpacity = o
+			case "stroke-opacity":
+				st.strokeOpacity = o
+			default:
+				st.opacity *= o
+			}
+		case "fill-rule":
+			st.evenOdd = v == "evenodd"
+		case "stroke-width":
+			st.strokeWidth = parseLength(v, r.diagonal(), parent.fontSize)
+		case "stroke-linecap":
+			st.lineCap = map[string]int{"butt": 0, "round": 1, "square": 2}[v]
+		case "stroke-linejoin":
+			st.lineJoin = map[string]int{"miter": 0, "round": 1, "bevel": 2}[v]
+		case "stroke-miterlimit":
+			if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 1 {
+				st.miterLimit = f
+			}
+		case "stroke-dasharray":
+			st.dashArray = nil
+			sum := 0.0
+			for _, f := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' }) {
+				l := parseLength(f, r.diagonal(), parent.fontSize)
+				sum += l
+				st.dashArray = append(st.dashArray, l)
+			}
+			if sum <= 0 {
+				st.dashArray = nil
+			} else if len(st.dashArray)%2 == 1 {
+				st.dashArray = append(st.dashArray, st.dashArray...)
+			}
+		case "stroke-dashoffset":
+			st.dashOffset = parseLength(v, r.diagonal(), parent.fontSize)
+		case "font-family":
+			st.fontFamily = v
+		case "font-size":
+			if fs := parseLength(v, parent.fontSize, parent.fontSize); fs > 0 {
+				st.fontSize = fs
+			}
+		case "font-weight":
+			switch v {
+			case "normal":
+				st.fontWeight = 400
+			case "bold":
+				st.fontWeight = 700
+			case "bolder":
+				st.fontWeight = int(math.Min(900, float64(parent.fontWeight+300)))
+			case "lighter":
+				st.fontWeight = int(math.Max(100, float64(parent.fontWeight-300)))
+			default:
+				if w, err := strconv.Atoi(v); err == nil {
+					st.fontWeight = w
+				}
+			}
+		case "font-style":
+			st.italic = v == "italic" || v == "oblique"
+		case "text-anchor":
+			st.textAnchor = v
+		case "visibility":
+			st.visible = v == "visible"
+		}
+	}
+	return st
+}
+
+// extGState returns the name of a graphics state with the fill opacity ca and
+// the stroke opacity sa.
+func (r *renderer) extGState(ca, sa float64) string {
+	dict := fmt.Sprintf("<< /ca %s /CA %s >>", num(ca), num(sa))
+	if name, ok := r.gsNames[dict]; ok {
+		return name
+	}
+	r.extGStates = append(r.extGStates, dict)
+	name := fmt.Sprintf("/GS%d", len(r.extGStates))
+	r.gsNames[dict] = name
+	return name
+}
+
+// transform writes the transform attribute of the element.
+func (r *renderer) transform(e *element) {
+	if t, ok := e.attrs["transform"]; ok {
+		r.sb.WriteString(parseTransform(t).String() + " cm\n")
+	}
+}
+
+// render writes the element and its children.
+func (r *renderer) render(e *element, parent style) {
+	if e.name == "" || e.props["display"] == "none" {
+		return
+	}
+	if r.elements >= maxElements {
+		r.warn("SVG has too many elements, the rest is not rendered", "max", maxElements)
+		return
+	}
+	r.elements++
+	for _, a := range []string{"clip-path", "mask", "filter"} {
+		if v, ok := e.attrs[a]; ok && v != "none" {
+			r.warn("SVG attribute not supported", "attribute", a)
+		}
+	}
+	st := r.computeStyle(parent, e)
+	switch e.name {
+	case "g", "a", "switch":
+		r.sb.WriteString("q\n")
+		r.transform(e)
+		for _, c := range e.children {
+			r.render(c, st)
+		}
+		r.sb.WriteString("Q\n")
+	case "svg":
+		r.nestedSVG(e, st)
+	case "use":
+		r.use(e, st)
+	case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+		r.shape(e, st)
+	case "text":
+		r.text(e, st)
+	case "defs", "symbol", "linearGradient", "radialGradient", "stop", "title", "desc",
+		"metadata", "style", "script", "clipPath", "mask", "marker", "pattern", "filter":
+		// not rendered directly
+	default:
+		r.warn("SVG element not supported", "element", e.name)
+	}
+}
+
+// viewportChildren renders the children of the element (svg or symbol) in a
+// new viewport with the size w × h.
+func (r *renderer) viewportChildren(e *element, st style, w, h float64) {
+	if vb, ok := parseViewBox(e.attr("viewBox", "")); ok {
+		r.sb.WriteString(viewBoxTransform(vb, w, h, e.attr("preserveAspectRatio", "")).String() + " cm\n")
+		w, h = vb[2], vb[3]
+	}
+	r.viewports = append(r.viewports, [2]float64{w, h})
+	for _, c := range e.children {
+		r.render(c, st)
+	}
+	r.viewports = r.viewports[:len(r.viewports)-1]
+}
+
+func (r *renderer) nestedSVG(e *element, st style) {
+	vw, vh := r.viewport()
+	x, y := r.length(e, "x", true, st), r.length(e, "y", false, st)
+	w := parseLength(e.attr("width", "100%"), vw, st.fontSize)
+	h := parseLength(e.attr("height", "100%"), vh, st.fontSize)
+	r.sb.WriteString("q\n")
+	r.sb.WriteString(matrix{1, 0, 0, 1, x, y}.String() + " cm\n")
+	r.viewportChildren(e, st, w, h)
+	r.sb.WriteString("Q\n")
+}
+
+func (r *renderer) use(e *element, st style) {
+	id := strings.TrimPrefix(e.attr("href", ""), "#")
+	ref := r.img.ids[id]
+	if ref == nil {
+		r.warn("SVG use element references unknown element", "href", id)
+		return
+	}
+	if r.useDepth >= maxUseDepth {
+		r.warn("SVG use elements nested too deeply", "href", id)
+		return
+	}
+	r.useDepth++
+	defer func() { r.useDepth-- }()
+	r.sb.WriteString("q\n")
+	r.transform(e)
+	x, y := r.length(e, "x", true, st), r.length(e, "y", false, st)
+	r.sb.WriteString(matrix{1, 0, 0, 1, x, y}.String() + " cm\n")
+	if ref.name == "symbol" {
+		vw, vh := r.viewport()
+		w := parseLength(e.attr("width", "100%"), vw, st.fontSize)
+		h := parseLength(e.attr("height", "100%"), vh, st.fontSize)
+		r.viewportChildren(ref, r.computeStyle(st, ref), w, h)
+	} else {
+		r.render(ref, st)
+	}
+	r.sb.WriteString("Q\n")
+}
+
+// shape writes a basic shape or a path.
+func (r *renderer) shape(e *element, st style) {
+	var p path
+	fillable := true
+	switch e.name {
+	case "path":
+		p = parsePath(e.attr("d", ""))
+	case "rect":
+		x, y := r.length(e, "x", true, st), r.length(e, "y", false, st)
+		w, h := r.length(e, "width", true, st), r.length(e, "height", false, st)
+		if w <= 0 || h <= 0 {
+			return
+		}
+		_, hasRx := e.attrs["rx"]
+		_, hasRy := e.attrs["ry"]
+		rx, ry := r.length(e, "rx", true, st), r.length(e, "ry", false, st)
+		if !hasRx {
+			rx = ry
+		}
+		if !hasRy {
+			ry = rx
+		}
+		p.rect(x, y, w, h, math.Min(rx, w/2), math.Min(ry, h/2))
+	case "circle":
+		rad := parseLength(e.attr("r", "0"), r.diagonal(), st.fontSize)
+		if rad <= 0 {
+			return
+		}
+		p.ellipse(r.length(e, "cx", true, st), r.length(e, "cy", false, st), rad, rad)
+	case "ellipse":
+		rx, ry := r.length(e, "rx", true, st), r.length(e, "ry", false, st)
+		if rx <= 0 || ry <= 0 {
+			return
+		}
+		p.ellipse(r.length(e, "cx", true, st), r.length(e, "cy", false, st), rx, ry)
+	case "line":
+		p.moveTo(r.length(e, "x1", true, st), r.length(e, "y1", false, st))
+		p.lineTo(r.length(e, "x2", true, st), r.length(e, "y2", false, st))
+		fillable = false
+	case "polyline", "polygon":
+		pts := parseNumbers(e.attr("points", ""))
+		for i := 0; i+1 < len(pts); i += 2 {
+			if i == 0 {
+				p.moveTo(pts[i], pts[i+1])
+			} else {
+				p.lineTo(pts[i], pts[i+1])
+			}
+		}
+		if e.name == "polygon" && len(p) > 0 {
+			p.close()
+		}
+	}
+	r.sb.WriteString("q\n")
+	r.transform(e)
+	r.draw(p, st, fillable)
+	r.sb.WriteString("Q\n")
+}
+
+// draw fills and strokes the path.
+func (r *renderer) draw(p path, st style, fillable bool) {
+	fill := fillable && st.fill.kind != paintNone
+	stroke := st.stroke.kind != paintNone && st.strokeWidth > 0
+	if !st.visible || len(p) == 0 || (!fill && !stroke) {
+		return
+	}
+	ca, sa := st.fillOpacity*st.opacity, st.strokeOpacity*st.opacity
+	if ca < 1 || sa < 1 {
+		r.sb.WriteString(r.extGState(ca, sa) + " gs\n")
+	}
+	pathString := p.String()
+	evenOdd := ""
+	if st.evenOdd {
+		evenOdd = "*"
+	}
+	if fill && st.fill.kind == paintURL {
+		if sh, ok := r.shading(st.fill.ref, p); ok {
+			r.sb.WriteString("q " + pathString + "W" + evenOdd + " n " + sh + " Q\n")
+			fill = false
+		} else {
+			st.fill.kind = paintColor
+		}
+	}
+	if stroke && st.stroke.kind == paintURL {
+		// gradients on strokes use the first color of the gradient
+		if stops := r.gradientStops(r.img.ids[st.stroke.ref]); len(stops) > 0 {
+			st.stroke.col = stops[0].col
+		}
+		st.stroke.kind = paintColor
+	}
+	if !fill && !stroke {
+		return
+	}
+	if fill {
+		r.sb.WriteString(st.fill.col.components() + " rg\n")
+	}
+	if stroke {
+		r.sb.WriteString(st.stroke.col.components() + " RG\n")
+		r.sb.WriteString(fmt.Sprintf("%s w %d J %d j %s M\n", num(st.strokeWidth), st.lineCap, st.lineJoin, num(st.miterLimit)))
+		if len(st.dashArray) > 0 {
+			dashes := make([]string, len(st.dashArray))
+			for i, d := range st.dashArray {
+				dashes[i] = num(d)
+			}
+			r.sb.WriteString(fmt.Sprintf("[%s] %s d\n", strings.Join(dashes, " "), num(st.dashOffset)))
+		}
+	}
+	r.sb.WriteString(pathString)
+	switch {
+	case fill && stroke:
+		r.sb.WriteString("B" + evenOdd + "\n")
+	case fill:
+		r.sb.WriteString("f" + evenOdd + "\n")
+	default:
+		r.sb.WriteString("S\n")
+	}
+}
+
+// face returns the first available font face of the font family list.
+func (r *renderer) face(st style) *pdf.Face {
+	if r.fonts == nil {
+		return nil
+	}
+	key := fmt.Sprintf("%s/%d/%t", st.fontFamily, st.fontWeight, st.italic)
+	if f, ok := r.faces[key]; ok {
+		return f
+	}
+	var f *pdf.Face
+	for _, family := range append(strings.Split(st.fontFamily, ","), "serif") {
+		if f = r.fonts(strings.Trim(strings.TrimSpace(family), `"'`), st.fontWeight, st.italic); f != nil {
+			break
+		}
+	}
+	r.faces[key] = f
+	return f
+}
+
+// textRun is a part of a text element with the same style. The position
+// attributes are applied before the text.
+type textRun struct {
+	text         string
+	st           style
+	setX, setY   bool
+	x, y, dx, dy float64
+}
+
+// collectText appends the text runs of the text or tspan element to runs.
+func (r *renderer) collectText(e *element, st style, runs []textRun) []textRun {
+	pos := textRun{st: st}
+	first := func(name string, horizontal bool) (float64, bool) {
+		values := strings.FieldsFunc(e.attr(name, ""), func(r rune) bool { return r == ',' || r == ' ' })
+		if len(values) == 0 {
+			return 0, false
+		}
+		w, h := r.viewport()
+		ref := h
+		if horizontal {
+			ref = w
+		}
+		return parseLength(values[0], ref, st.fontSize), true
+	}
+	pos.x, pos.setX = first("x", true)
+	pos.y, pos.setY = first("y", false)
+	pos.dx, _ = first("dx", true)
+	pos.dy, _ = first("dy", false)
+	runs = append(runs, pos)
+	for _, c := range e.children {
+		switch c.name {
+		case "":
+			runs = append(runs, textRun{text: c.text, st: st})
+		case "tspan":
+			if c.props["display"] != "none" {
+				runs = r.collectText(c, r.computeStyle(st, c), runs)
+			}
+		}
+	}
+	return runs
+}
+
+// glyphPath appends the outlines of the shaped text at the position x, y to
+// p and returns the advance of the text.
+func glyphPath(p *path, face *pdf.Face, text string, size, x, y float64) float64 {
+	fnt := font.NewFont(face, bag.ScaledPointFromFloat(size))
+	hbface := face.HarfbuzzFont.Face()
+	scale := size / float64(face.UnitsPerEM)
+	start := x
+	for _, a := range fnt.Shape(text, nil) {
+		if !a.IsSpace {
+			gx, gy := x+a.XOffset.ToPT(), y-a.YOffset.ToPT()
+			var outline fonts.GlyphOutline
+			switch gd := hbface.GlyphData(fonts.GID(a.Codepoint), 0, 0).(type) {
+			case fonts.GlyphOutline:
+				outline = gd
+			case *fonts.GlyphOutline:
+				outline = *gd
+			}
+			var cur point
+			contour := false
+			for _, seg := range outline.Segments {
+				// font units have the y axis upwards
+				pts := make([]point, len(seg.ArgsSlice()))
+				for i, sp := range seg.ArgsSlice() {
+					pts[i] = point{gx + float64(sp.X)*scale, gy - float64(sp.Y)*scale}
+				}
+				switch seg.Op {
+				case fonts.SegmentOpMoveTo:
+					if contour {
+						p.close()
+					}
+					p.moveTo(pts[0].x, pts[0].y)
+					contour = true
+				case fonts.SegmentOpLineTo:
+					p.lineTo(pts[0].x, pts[0].y)
+				case fonts.SegmentOpQuadTo:
+					q, end := pts[0], pts[1]
+					p.curveTo(cur.x+2.0/3.0*(q.x-cur.x), cur.y+2.0/3.0*(q.y-cur.y), end.x+2.0/3.0*(q.x-end.x), end.y+2.0/3.0*(q.y-end.y), end.x, end.y)
+				case fonts.SegmentOpCubeTo:
+					p.curveTo(pts[0].x, pts[0].y, pts[1].x, pts[1].y, pts[2].x, pts[2].y)
+				}
+				cur = pts[len(pts)-1]
+			}
+			if contour {
+				p.close()
+			}
+		}
+		x += (a.Advance + a.Kernafter).ToPT()
+	}
+	return x - start
+}
+
+// text writes the text element. The glyphs are drawn as paths with the fonts
+// returned by the FontFunc of the renderer.
+func (r *renderer) text(e *element, st style) {
+	runs := r.collectText(e, st, nil)
+	// collapse white space, leading and trailing spaces of the element are
+	// removed
+	lastSpace := true
+	last := -1
+	for i := range runs {
+		var sb strings.Builder
+		for _, c := range runs[i].text {
+			if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+				if !lastSpace {
+					sb.WriteRune(' ')
+				}
+				lastSpace = true
+				continue
+			}
+			lastSpace = false
+			sb.WriteRune(c)
+		}
+		runs[i].text = sb.String()
+		if runs[i].text != "" {
+			last = i
+		}
+	}
+	if last >= 0 {
+		runs[last].text = strings.TrimSuffix(runs[last].text, " ")
+	}
+
+	// A text chunk starts at each absolute x position. The chunks are aligned
+	// according to the text-anchor property.
+	type chunk struct {
+		paths  []path
+		styles []style
+		width  float64
+		anchor string
+	}
+	var chunks []*chunk
+	var pen point
+	for i, run := range runs {
+		if run.setX || i == 0 {
+			chunks = append(chunks, &chunk{anchor: run.st.textAnchor})
+		}
+		if run.setX {
+			pen.x = run.x
+		}
+		if run.setY {
+			pen.y = run.y
+		}
+		pen.x += run.dx
+		pen.y += run.dy
+		if run.text == "" {
+			continue
+		}
+		face := r.face(run.st)
+		if face == nil {
+			r.warn("no font for SVG text", "font-family", run.st.fontFamily)
+			continue
+		}
+		var p path
+		adv := glyphPath(&p, face, run.text, run.st.fontSize, pen.x, pen.y)
+		pen.x += adv
+		c := chunks[len(chunks)-1]
+		c.width += adv
+		c.paths = append(c.paths, p)
+		c.styles = append(c.styles, run.st)
+	}
+	r.sb.WriteString("q\n")
+	r.transform(e)
+	for _, c := range chunks {
+		shift := 0.0
+		switch c.anchor {
+		case "middle":
+			shift = -c.width / 2
+		case "end":
+			shift = -c.width
+		}
+		for i, p := range c.paths {
+			r.sb.WriteString("q\n")
+			if shift != 0 {
+				r.sb.WriteString(matrix{1, 0, 0, 1, shift, 0}.String() + " cm\n")
+			}
+			r.draw(p, c.styles[i], true)
+			r.sb.WriteString("Q\n")
+		}
+	}
+	r.sb.WriteString("Q\n")
+}
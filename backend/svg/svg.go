@@ -0,0 +1,291 @@
+// Package svg converts SVG graphics to PDF. The graphic is written as a
+// single page PDF file which can be placed in a document like any other PDF
+// image.
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// element is a node in the SVG tree. Character data is stored in elements
+// without a name.
+type element struct {
+	name     string
+	attrs    map[string]string
+	props    map[string]string
+	children []*element
+	text     string
+}
+
+// attr returns the attribute value or def if the attribute is not set.
+func (e *element) attr(name, def string) string {
+	if v, ok := e.attrs[name]; ok {
+		return v
+	}
+	return def
+}
+
+// Image is a parsed SVG graphic.
+type Image struct {
+	// Width and Height are the dimensions of the graphic in PDF points.
+	Width  float64
+	Height float64
+	root   *element
+	ids    map[string]*element
+}
+
+// Parse reads an SVG graphic from r.
+func Parse(r io.Reader) (*Image, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	img := &Image{
+		ids: make(map[string]*element),
+	}
+	var stack []*element
+	var stylesheets []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &element{
+				name:  t.Name.Local,
+				attrs: make(map[string]string, len(t.Attr)),
+			}
+			for _, a := range t.Attr {
+				// xlink:href and href are the same
+				e.attrs[a.Name.Local] = a.Value
+			}
+			if id, ok := e.attrs["id"]; ok {
+				img.ids[id] = e
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			} else if img.root == nil {
+				if e.name != "svg" {
+					return nil, fmt.Errorf("root element is %s, not svg", e.name)
+				}
+				img.root = e
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) == 0 {
+				continue
+			}
+			parent := stack[len(stack)-1]
+			switch parent.name {
+			case "style":
+				stylesheets = append(stylesheets, string(t))
+			case "text", "tspan":
+				parent.children = append(parent.children, &element{text: string(t)})
+			}
+		}
+	}
+	if img.root == nil {
+		return nil, fmt.Errorf("no svg element found")
+	}
+	rules := parseStylesheet(strings.Join(stylesheets, "\n"))
+	applyStyles(img.root, rules)
+	img.Width, img.Height = img.size()
+	return img, nil
+}
+
+// size returns the size of the graphic in PDF points.
+func (img *Image) size() (float64, float64) {
+	vb, hasViewBox := parseViewBox(img.root.attr("viewBox", ""))
+	// default size for graphics without size information
+	w, h := 300.0, 150.0
+	if hasViewBox {
+		w, h = vb[2], vb[3]
+	}
+	wd := img.root.attr("width", "")
+	ht := img.root.attr("height", "")
+	switch {
+	case wd != "" && ht != "" && !strings.HasSuffix(wd, "%") && !strings.HasSuffix(ht, "%"):
+		w, h = parseLength(wd, w, 16), parseLength(ht, h, 16)
+	case wd != "" && !strings.HasSuffix(wd, "%"):
+		nw := parseLength(wd, w, 16)
+		if hasViewBox && vb[2] > 0 {
+			h = nw * vb[3] / vb[2]
+		}
+		w = nw
+	case ht != "" && !strings.HasSuffix(ht, "%"):
+		nh := parseLength(ht, h, 16)
+		if hasViewBox && vb[3] > 0 {
+			w = nh * vb[2] / vb[3]
+		}
+		h = nh
+	}
+	// CSS pixels to PDF points
+	return w * 0.75, h * 0.75
+}
+
+// presentationAttributes are the attributes that can be set in style sheets
+// and style attributes.
+var presentationAttributes = map[string]bool{
+	"color":             true,
+	"display":           true,
+	"fill":              true,
+	"fill-opacity":      true,
+	"fill-rule":         true,
+	"font-family":       true,
+	"font-size":         true,
+	"font-style":        true,
+	"font-weight":       true,
+	"opacity":           true,
+	"stop-color":        true,
+	"stop-opacity":      true,
+	"stroke":            true,
+	"stroke-dasharray":  true,
+	"stroke-dashoffset": true,
+	"stroke-linecap":    true,
+	"stroke-linejoin":   true,
+	"stroke-miterlimit": true,
+	"stroke-opacity":    true,
+	"stroke-width":      true,
+	"text-anchor":       true,
+	"visibility":        true,
+}
+
+// styleRule is a rule of an SVG style sheet. Only simple selectors (element
+// name, class and id) are supported.
+type styleRule struct {
+	selector string
+	decls    map[string]string
+}
+
+// parseDeclarations parses CSS declarations such as "fill: red; stroke: none".
+func parseDeclarations(s string) map[string]string {
+	decls := make(map[string]string)
+	for _, decl := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "!important"))
+		decls[strings.TrimSpace(k)] = v
+	}
+	return decls
+}
+
+func parseStylesheet(s string) []styleRule {
+	var rules []styleRule
+	// remove comments
+	for {
+		start := strings.Index(s, "/*")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "*/")
+		if end < 0 {
+			s = s[:start]
+			break
+		}
+		s = s[:start] + s[start+end+2:]
+	}
+	for _, block := range strings.Split(s, "}") {
+		selectors, decls, ok := strings.Cut(block, "{")
+		if !ok {
+			continue
+		}
+		d := parseDeclarations(decls)
+		for _, sel := range strings.Split(selectors, ",") {
+			if sel = strings.TrimSpace(sel); sel != "" {
+				rules = append(rules, styleRule{selector: sel, decls: d})
+			}
+		}
+	}
+	return rules
+}
+
+// matches reports whether the simple selector sel (for example rect,
+// .highlight, rect.highlight or #logo) matches the element.
+func (e *element) matches(sel string) bool {
+	if strings.ContainsAny(sel, " >+~[:") {
+		return false
+	}
+	name := sel
+	if i := strings.IndexAny(sel, ".#"); i >= 0 {
+		name = sel[:i]
+		sel = sel[i:]
+	} else {
+		sel = ""
+	}
+	if name != "" && name != "*" && name != e.name {
+		return false
+	}
+	for sel != "" {
+		kind := sel[0]
+		sel = sel[1:]
+		value := sel
+		if i := strings.IndexAny(sel, ".#"); i >= 0 {
+			value = sel[:i]
+			sel = sel[i:]
+		} else {
+			sel = ""
+		}
+		switch kind {
+		case '.':
+			found := false
+			for _, class := range strings.Fields(e.attrs["class"]) {
+				if class == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case '#':
+			if e.attrs["id"] != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// applyStyles sets the props of the element and its children. Presentation
+// attributes have the lowest priority, followed by the style sheet and the
+// style attribute.
+func applyStyles(e *element, rules []styleRule) {
+	if e.name == "" {
+		return
+	}
+	e.props = make(map[string]string)
+	for k, v := range e.attrs {
+		if presentationAttributes[k] {
+			e.props[k] = strings.TrimSpace(v)
+		}
+	}
+	for _, r := range rules {
+		if e.matches(r.selector) {
+			for k, v := range r.decls {
+				e.props[k] = v
+			}
+		}
+	}
+	if style, ok := e.attrs["style"]; ok {
+		for k, v := range parseDeclarations(style) {
+			e.props[k] = v
+		}
+	}
+	for _, c := range e.children {
+		applyStyles(c, rules)
+	}
+}
@@ -0,0 +1,206 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	testdata := []struct {
+		d    string
+		want string
+	}{
+		{"M10 20 L30 40z", "10 20 m 30 40 l h "},
+		{"m10,20 5-5 h10 v-10", "10 20 m 15 15 l 25 15 l 25 5 l "},
+		{"M0 0Q10 0 10 10T20 20", "0 0 m 6.6667 0 10 3.3333 10 10 c 10 16.6667 13.3333 20 20 20 c "},
+		{"M0 0C0 5 5 10 10 10S20 5 20 0", "0 0 m 0 5 5 10 10 10 c 15 10 20 5 20 0 c "},
+		{"M.5.5.5.5", "0.5 0.5 m 0.5 0.5 l "},
+		{"M0 0 L10 0 x 3", "0 0 m 10 0 l "},
+	}
+	for _, td := range testdata {
+		if got := parsePath(td.d).String(); got != td.want {
+			t.Errorf("parsePath(%q) = %q, want %q", td.d, got, td.want)
+		}
+	}
+	// a half circle with flags without separators
+	p := parsePath("M0 0a10 10 0 01 20 0")
+	if len(p) != 3 {
+		t.Fatalf("len(arc) = %d, want 3", len(p))
+	}
+	if end := p[2].pts[2]; end.x != 20 || end.y != 0 {
+		t.Errorf("arc ends at %v, want 20,0", end)
+	}
+	if _, y, _, h := p.bbox(); num(y) != "-10" || num(h) != "10" {
+		t.Errorf("unexpected arc control points y=%v h=%v", y, h)
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	testdata := []struct {
+		transform string
+		want      string
+	}{
+		{"translate(10 20)", "1 0 0 1 10 20"},
+		{"translate(10,20) scale(2)", "2 0 0 2 10 20"},
+		{"scale(2) translate(10,20)", "2 0 0 2 20 40"},
+		{"rotate(90)", "0 1 -1 0 0 0"},
+		{"rotate(180 10 10)", "-1 0 0 -1 20 20"},
+		{"matrix(1 2 3 4 5 6)", "1 2 3 4 5 6"},
+	}
+	for _, td := range testdata {
+		if got := parseTransform(td.transform).String(); got != td.want {
+			t.Errorf("parseTransform(%q) = %s, want %s", td.transform, got, td.want)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	testdata := []struct {
+		color string
+		want  string
+	}{
+		{"red", "1 0 0"},
+		{"#0f0", "0 1 0"},
+		{"#000080", "0 0 0.502"},
+		{"rgb(255, 0, 51)", "1 0 0.2"},
+		{"rgb(100% 50% 0%)", "1 0.5 0"},
+		{"currentColor", "0.1 0.2 0.3"},
+	}
+	for _, td := range testdata {
+		col, ok := parseColor(td.color, rgb{0.1, 0.2, 0.3})
+		if !ok {
+			t.Errorf("parseColor(%q) failed", td.color)
+			continue
+		}
+		if got := col.components(); got != td.want {
+			t.Errorf("parseColor(%q) = %s, want %s", td.color, got, td.want)
+		}
+	}
+	if _, ok := parseColor("nocolor", rgb{}); ok {
+		t.Error("parseColor(nocolor) should fail")
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	testdata := []struct {
+		attributes string
+		wd, ht     float64
+	}{
+		{`width="200" height="100"`, 150, 75},
+		{`width="2cm" height="10mm"`, 56.6929, 28.3465},
+		{`viewBox="0 0 40 20"`, 30, 15},
+		{`width="80" viewBox="0 0 40 20"`, 60, 30},
+		{`width="100%" viewBox="0 0 40 20"`, 30, 15},
+		{``, 225, 112.5},
+	}
+	for _, td := range testdata {
+		img, err := Parse(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" ` + td.attributes + `/>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if num(img.Width) != num(td.wd) || num(img.Height) != num(td.ht) {
+			t.Errorf("size(%s) = %v × %v, want %v × %v", td.attributes, img.Width, img.Height, td.wd, td.ht)
+		}
+	}
+	if _, err := Parse(strings.NewReader(`<html/>`)); err == nil {
+		t.Error("Parse(html) should fail")
+	}
+}
+
+func TestStyles(t *testing.T) {
+	img, err := Parse(strings.NewReader(`<svg>
+	<style>/* comment */ rect.a, #b { fill: blue; stroke: red } .c { fill: green }</style>
+	<rect id="r" class="a c" fill="yellow" style="stroke: black"/>
+	<circle id="b"/>
+	</svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := img.ids["r"].props
+	if props["fill"] != "green" || props["stroke"] != "black" {
+		t.Errorf("unexpected properties %v", props)
+	}
+	if props := img.ids["b"].props; props["fill"] != "blue" {
+		t.Errorf("unexpected properties %v", props)
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	img, err := Parse(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="100" height="100">
+	<defs>
+		<linearGradient id="lg"><stop offset="0" stop-color="red"/><stop offset="1" stop-color="blue"/></linearGradient>
+		<rect id="box" width="10" height="10"/>
+	</defs>
+	<g fill="green" opacity="0.5">
+		<use xlink:href="#box" x="5"/>
+	</g>
+	<circle cx="50" cy="50" r="10" fill="url(#lg)" stroke="black" stroke-dasharray="2"/>
+	</svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r = &renderer{
+		img:       img,
+		faces:     nil,
+		gsNames:   make(map[string]string),
+		warned:    make(map[string]bool),
+		viewports: [][2]float64{{100, 100}},
+	}
+	r.viewportChildren(img.root, r.computeStyle(defaultStyle(), img.root), 100, 100)
+	content := r.sb.String()
+	for _, want := range []string{
+		"1 0 0 1 5 0 cm",
+		"/GS1 gs\n0 0.502 0 rg\n0 0 m 10 0 l 10 10 l 0 10 l h f",
+		"W n 20 0 0 20 40 40 cm /Sh1 sh Q",
+		"[2 2] 0 d",
+		" S\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content stream does not contain %q:\n%s", want, content)
+		}
+	}
+	if len(r.extGStates) != 1 || r.extGStates[0] != "<< /ca 0.5 /CA 0.5 >>" {
+		t.Errorf("unexpected graphics states %v", r.extGStates)
+	}
+	if len(r.shadings) != 1 || !strings.Contains(r.shadings[0], "/ShadingType 2") {
+		t.Errorf("unexpected shadings %v", r.shadings)
+	}
+
+	var w bytes.Buffer
+	if err = img.WritePDF(&w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out := w.String(); !strings.HasPrefix(out, "%PDF") || !strings.Contains(out, "/Shading") || !strings.Contains(out, "/MediaBox [0 0 75 75]") {
+		t.Errorf("unexpected PDF output:\n%s", out)
+	}
+}
+
+func TestMaxElements(t *testing.T) {
+	// each group uses the previous group ten times
+	var sb strings.Builder
+	sb.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"><defs><rect id="g0" width="1" height="1"/>`)
+	for i := 1; i <= 6; i++ {
+		fmt.Fprintf(&sb, `<g id="g%d">`, i)
+		for j := 0; j < 10; j++ {
+			fmt.Fprintf(&sb, `<use href="#g%d"/>`, i-1)
+		}
+		sb.WriteString("</g>")
+	}
+	sb.WriteString(`</defs><use href="#g6"/></svg>`)
+	img, err := Parse(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &renderer{
+		img:       img,
+		gsNames:   make(map[string]string),
+		warned:    make(map[string]bool),
+		viewports: [][2]float64{{10, 10}},
+	}
+	r.viewportChildren(img.root, r.computeStyle(defaultStyle(), img.root), 10, 10)
+	if r.elements != maxElements {
+		t.Errorf("rendered %d elements, want %d", r.elements, maxElements)
+	}
+}
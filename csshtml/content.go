@@ -0,0 +1,422 @@
+package csshtml
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ContentResolver provides the values of the functions in a content property.
+// The boolean results are false if a value is not known (yet).
+type ContentResolver interface {
+	// Counter returns the values of the nested counters with the name, the
+	// outermost counter first.
+	Counter(name string) ([]int, bool)
+	// Function returns the value of other functions such as attr() or
+	// string(). The arguments are already evaluated.
+	Function(name string, args []string) (string, bool)
+}
+
+// contentItem is a string, a keyword or a function in a content value.
+type contentItem struct {
+	str     string
+	literal bool
+	fn      string
+	args    [][]contentItem
+}
+
+// String returns the item in the syntax of the content property.
+func (ci contentItem) String() string {
+	if ci.literal {
+		return strconv.Quote(ci.str)
+	}
+	if ci.fn == "" {
+		return ci.str
+	}
+	args := make([]string, len(ci.args))
+	for i, arg := range ci.args {
+		args[i] = contentString(arg)
+	}
+	if len(args) == 1 && args[0] == "" {
+		return ci.fn + "( )"
+	}
+	return ci.fn + "( " + strings.Join(args, " , ") + " )"
+}
+
+func contentString(items []contentItem) string {
+	s := make([]string, len(items))
+	for i, itm := range items {
+		s[i] = itm.String()
+	}
+	return strings.Join(s, " ")
+}
+
+// contentTokens splits a content value into quoted strings, keywords,
+// function starts ("name("), commas and closing parentheses.
+func contentTokens(s string) []string {
+	var toks []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(s) && s[j] != c {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				j = len(s) - 1
+			}
+			toks = append(toks, s[i:j+1])
+			i = j + 1
+		case c == ',' || c == ')':
+			toks = append(toks, s[i:i+1])
+			i++
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n,)\"'", rune(s[j])) {
+				j++
+				if s[j-1] == '(' {
+					break
+				}
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		}
+	}
+	return toks
+}
+
+// parseContentItems reads items until the end of the tokens or a closing
+// parenthesis or comma on the same level.
+func parseContentItems(toks []string) ([]contentItem, []string) {
+	var items []contentItem
+	for len(toks) > 0 {
+		tok := toks[0]
+		switch {
+		case tok == "," || tok == ")":
+			return items, toks
+		case tok[0] == '"' || tok[0] == '\'':
+			toks = toks[1:]
+			str := tok[1 : len(tok)-1]
+			if tok[0] == '"' {
+				if s, err := strconv.Unquote(tok); err == nil {
+					str = s
+				}
+			}
+			items = append(items, contentItem{str: str, literal: true})
+		case strings.HasSuffix(tok, "("):
+			fn := contentItem{fn: strings.TrimSuffix(tok, "(")}
+			toks = toks[1:]
+			for len(toks) > 0 {
+				var arg []contentItem
+				arg, toks = parseContentItems(toks)
+				fn.args = append(fn.args, arg)
+				if len(toks) == 0 {
+					break
+				}
+				sep := toks[0]
+				toks = toks[1:]
+				if sep == ")" {
+					break
+				}
+			}
+			items = append(items, fn)
+		default:
+			toks = toks[1:]
+			items = append(items, contentItem{str: tok})
+		}
+	}
+	return items, toks
+}
+
+func parseContent(s string) []contentItem {
+	items, _ := parseContentItems(contentTokens(s))
+	return items
+}
+
+// argument returns the text of the i-th argument or "".
+func argument(args [][]contentItem, i int) string {
+	if i >= len(args) {
+		return ""
+	}
+	var sb strings.Builder
+	for _, itm := range args[i] {
+		sb.WriteString(itm.str)
+	}
+	return sb.String()
+}
+
+// evaluate resolves the item. The boolean is false if the item is not known.
+func (ci contentItem) evaluate(r ContentResolver) (contentItem, bool) {
+	switch {
+	case ci.literal:
+		return ci, true
+	case ci.fn == "":
+		switch ci.str {
+		case "open-quote":
+			return contentItem{str: "“", literal: true}, true
+		case "close-quote":
+			return contentItem{str: "”", literal: true}, true
+		case "none", "normal", "no-open-quote", "no-close-quote":
+			return contentItem{literal: true}, true
+		}
+		return ci, false
+	}
+	// evaluate nested functions first
+	args := make([][]contentItem, len(ci.args))
+	for i, arg := range ci.args {
+		args[i] = make([]contentItem, len(arg))
+		for j, itm := range arg {
+			if itm.fn != "" {
+				var ok bool
+				if itm, ok = itm.evaluate(r); !ok {
+					return ci, false
+				}
+			}
+			args[i][j] = itm
+		}
+	}
+	var str string
+	switch ci.fn {
+	case "counter", "counters":
+		values, ok := r.Counter(argument(args, 0))
+		if !ok {
+			return ci, false
+		}
+		style := argument(args, 1)
+		if ci.fn == "counters" {
+			style = argument(args, 2)
+		}
+		if style == "" {
+			style = "decimal"
+		}
+		if ci.fn == "counter" && len(values) > 1 {
+			values = values[len(values)-1:]
+		}
+		str = formatCounters(values, argument(args, 1), style)
+	default:
+		strArgs := make([]string, len(args))
+		for i := range args {
+			strArgs[i] = argument(args, i)
+		}
+		var ok bool
+		if str, ok = r.Function(ci.fn, strArgs); !ok {
+			return ci, false
+		}
+	}
+	return contentItem{str: str, literal: true}, true
+}
+
+// ResolveContent evaluates the functions of the content value s which r can
+// resolve. Functions which can not be resolved (such as counter(pages) before
+// the end of the document) are kept, so the result can be resolved again
+// later.
+func ResolveContent(s string, r ContentResolver) string {
+	var items []contentItem
+	for _, itm := range parseContent(s) {
+		if res, ok := itm.evaluate(r); ok {
+			itm = res
+		}
+		// merge adjacent strings
+		if n := len(items); n > 0 && itm.literal && items[n-1].literal {
+			items[n-1].str += itm.str
+			continue
+		}
+		items = append(items, itm)
+	}
+	return contentString(items)
+}
+
+// ContentText returns the text of the content value s. Unresolved functions
+// are ignored.
+func ContentText(s string) string {
+	var sb strings.Builder
+	for _, itm := range parseContent(s) {
+		if itm.literal {
+			sb.WriteString(itm.str)
+		}
+	}
+	return sb.String()
+}
+
+// ContentPart is a text, an image or a leader of a content value.
+type ContentPart struct {
+	Text string
+	// URL is the location of an image given with url().
+	URL string
+	// Leader is the text of a leader given with leader().
+	Leader string
+}
+
+// leaderText returns the text of the leader() argument, which is a string or
+// one of the keywords dotted, solid and space.
+func leaderText(arg string) string {
+	switch arg {
+	case "dotted":
+		return ". "
+	case "solid":
+		return "_"
+	case "space":
+		return " "
+	}
+	return strings.Trim(arg, `"'`)
+}
+
+// ContentParts splits the content value s into texts, images and leaders.
+// Adjacent strings are merged, unresolved functions are ignored.
+func ContentParts(s string) []ContentPart {
+	var ret []ContentPart
+	for _, itm := range parseContent(s) {
+		switch {
+		case itm.literal:
+			if n := len(ret); n > 0 && ret[n-1].URL == "" && ret[n-1].Leader == "" {
+				ret[n-1].Text += itm.str
+				continue
+			}
+			ret = append(ret, ContentPart{Text: itm.str})
+		case itm.fn == "url":
+			if url := strings.Trim(argument(itm.args, 0), `"'`); url != "" {
+				ret = append(ret, ContentPart{URL: url})
+			}
+		case itm.fn == "leader":
+			if leader := leaderText(argument(itm.args, 0)); leader != "" {
+				ret = append(ret, ContentPart{Leader: leader})
+			}
+		}
+	}
+	return ret
+}
+
+// StringSet is an assignment of the string-set property.
+type StringSet struct {
+	Name string
+	// Value is a content value, see ContentText.
+	Value string
+}
+
+// ParseStringSet interprets the value of the string-set property.
+func ParseStringSet(s string) []StringSet {
+	var ret []StringSet
+	toks := contentTokens(s)
+	for len(toks) > 0 {
+		name := toks[0]
+		if name == "none" || name == "," {
+			toks = toks[1:]
+			continue
+		}
+		var items []contentItem
+		items, toks = parseContentItems(toks[1:])
+		ret = append(ret, StringSet{Name: name, Value: contentString(items)})
+		if len(toks) > 0 {
+			toks = toks[1:]
+		}
+	}
+	return ret
+}
+
+// ParseCounterList interprets the value of counter-reset, counter-increment
+// and counter-set: pairs of counter names and optional integers. dflt is the
+// value for counters without a number.
+func ParseCounterList(s string, dflt int) []Counter {
+	var ret []Counter
+	for _, f := range strings.Fields(s) {
+		if n, err := strconv.Atoi(f); err == nil {
+			if len(ret) > 0 {
+				ret[len(ret)-1].Value = n
+			}
+			continue
+		}
+		if f == "none" {
+			continue
+		}
+		ret = append(ret, Counter{Name: f, Value: dflt})
+	}
+	return ret
+}
+
+// Counter is a counter name with a value.
+type Counter struct {
+	Name  string
+	Value int
+}
+
+var romanNumerals = []struct {
+	value  int
+	symbol string
+}{
+	{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"},
+	{50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+}
+
+// alphabetic returns the number in a bijective base system with the digits
+// (a, b, …, z, aa, ab, …).
+func alphabetic(n int, digits []rune) string {
+	if n < 1 {
+		return strconv.Itoa(n)
+	}
+	var ret []rune
+	for n > 0 {
+		n--
+		ret = append([]rune{digits[n%len(digits)]}, ret...)
+		n /= len(digits)
+	}
+	return string(ret)
+}
+
+// formatCounters formats the values of nested counters and joins them with
+// the separator.
+func formatCounters(values []int, separator, style string) string {
+	if style == "" {
+		style = "decimal"
+	}
+	formatted := make([]string, len(values))
+	for i, v := range values {
+		formatted[i] = FormatCounter(v, style)
+	}
+	return strings.Join(formatted, separator)
+}
+
+// FormatCounter returns the counter value n in the CSS list style (decimal,
+// lower-roman, upper-alpha, disc, ...). Unknown styles are formatted as
+// decimal.
+func FormatCounter(n int, style string) string {
+	switch style {
+	case "none":
+		return ""
+	case "disc":
+		return "•"
+	case "circle":
+		return "◦"
+	case "square":
+		return "▪"
+	case "decimal-leading-zero":
+		if n >= 0 && n < 10 {
+			return "0" + strconv.Itoa(n)
+		}
+	case "lower-roman", "upper-roman":
+		if n < 1 || n > 3999 {
+			break
+		}
+		var sb strings.Builder
+		for _, r := range romanNumerals {
+			for n >= r.value {
+				sb.WriteString(r.symbol)
+				n -= r.value
+			}
+		}
+		if style == "upper-roman" {
+			return strings.ToUpper(sb.String())
+		}
+		return sb.String()
+	case "lower-alpha", "lower-latin":
+		return alphabetic(n, []rune("abcdefghijklmnopqrstuvwxyz"))
+	case "upper-alpha", "upper-latin":
+		return alphabetic(n, []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ"))
+	case "lower-greek":
+		return alphabetic(n, []rune("αβγδεζηθικλμνξοπρστυφχψω"))
+	}
+	return strconv.Itoa(n)
+}
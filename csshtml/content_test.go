@@ -0,0 +1,162 @@
+package csshtml
+
+import (
+	"testing"
+)
+
+func TestFormatCounter(t *testing.T) {
+	testdata := []struct {
+		n     int
+		style string
+		want  string
+	}{
+		{3, "decimal", "3"},
+		{3, "decimal-leading-zero", "03"},
+		{1994, "upper-roman", "MCMXCIV"},
+		{4, "lower-roman", "iv"},
+		{28, "lower-alpha", "ab"},
+		{2, "lower-greek", "β"},
+		{5, "none", ""},
+	}
+	for _, td := range testdata {
+		if got := FormatCounter(td.n, td.style); got != td.want {
+			t.Errorf("FormatCounter(%d, %s) = %q, want %q", td.n, td.style, got, td.want)
+		}
+	}
+}
+
+type testResolver map[string][]int
+
+func (tr testResolver) Counter(name string) ([]int, bool) {
+	v, ok := tr[name]
+	return v, ok
+}
+
+func (tr testResolver) Function(name string, args []string) (string, bool) {
+	if name == "attr" {
+		return "<" + args[0] + ">", true
+	}
+	return "", false
+}
+
+func TestResolveContent(t *testing.T) {
+	r := testResolver{"page": {7}, "section": {2, 3}}
+	testdata := []struct {
+		content string
+		want    string
+	}{
+		{`"page " counter( page ) " of " counter( pages )`, `"page 7 of " counter( pages )`},
+		{`counters( section , "." ) ". "`, `"2.3. "`},
+		{`counter( section , upper-roman )`, `"III"`},
+		{`open-quote attr( title ) close-quote`, `"“<title>”"`},
+		{`string( chapter , first )`, `string( chapter , first )`},
+	}
+	for _, td := range testdata {
+		if got := ResolveContent(td.content, r); got != td.want {
+			t.Errorf("ResolveContent(%s) = %s, want %s", td.content, got, td.want)
+		}
+	}
+	if got, want := ContentText(`"a" counter( pages ) "b"`), "ab"; got != want {
+		t.Errorf("ContentText() = %q, want %q", got, want)
+	}
+}
+
+func TestParseStringSet(t *testing.T) {
+	got := ParseStringSet(`chapter "1. " content( ) , title attr( title )`)
+	if len(got) != 2 || got[0].Name != "chapter" || got[0].Value != `"1. " content( )` || got[1].Name != "title" {
+		t.Errorf("ParseStringSet() = %v", got)
+	}
+}
+
+func TestApplyCounters(t *testing.T) {
+	c := NewCSSParser()
+	err := c.AddCSSText(`
+	body { counter-reset: chapter }
+	h1 { counter-increment: chapter; counter-reset: section; string-set: title counter(chapter) ". " content() }
+	h2 { counter-increment: section }
+	h2::before { content: counter(chapter) "." counter(section) " (p. " counter(page) ") " }
+	li::marker { content: counters(list-item, ".") }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := c.ReadHTMLChunk(`<html><body><h1>One</h1><h2>A</h2><h1>Two</h1><h2>B</h2><h2>C</h2><ol start="3"><li>x<ol><li>y</li></ol></li></ol></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, err = c.ApplyCSS(doc); err != nil {
+		t.Fatal(err)
+	}
+	h1 := doc.Find("h1").Nodes
+	if got, _ := styleAttr(h1[1], "", "string-set"); got != `title "2. Two"` {
+		t.Errorf("string-set = %s", got)
+	}
+	want := []string{`"1.1 (p. " counter( page ) ") "`, `"2.1 (p. " counter( page ) ") "`, `"2.2 (p. " counter( page ) ") "`}
+	for i, h2 := range doc.Find("h2").Nodes {
+		if got, _ := styleAttr(h2, "before", "content"); got != want[i] {
+			t.Errorf("h2 %d ::before content = %s, want %s", i, got, want[i])
+		}
+	}
+	want = []string{`"3"`, `"3.1"`}
+	for i, li := range doc.Find("li").Nodes {
+		if got, _ := styleAttr(li, "marker", "content"); got != want[i] {
+			t.Errorf("li %d ::marker content = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestContentParts(t *testing.T) {
+	got := ContentParts(`"a" "b" url( icon.png ) counter( pages ) "c" leader( dotted ) "7" leader( "-" )`)
+	want := []ContentPart{{Text: "ab"}, {URL: "icon.png"}, {Text: "c"}, {Leader: ". "}, {Text: "7"}, {Leader: "-"}}
+	if len(got) != len(want) {
+		t.Fatalf("ContentParts() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ContentParts()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTargetContent(t *testing.T) {
+	c := NewCSSParser()
+	err := c.AddCSSText(`
+	body { counter-reset: chapter }
+	h1 { counter-increment: chapter }
+	a::after { content: " (" target-text(attr(href)) ", " target-counter(attr(href), chapter, upper-roman) ", p. " target-counter(attr(href), page) ")" }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := `<html><body><a href="#two">see</a><h1 id="one">One</h1><h1 id="two">Two</h1><a href="#one">back</a></body></html>`
+	resolve := func() []string {
+		doc, err := c.ReadHTMLChunk(html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc, err = c.ApplyCSS(doc); err != nil {
+			t.Fatal(err)
+		}
+		var ret []string
+		for _, a := range doc.Find("a").Nodes {
+			content, _ := styleAttr(a, "after", "content")
+			ret = append(ret, ContentText(content))
+		}
+		return ret
+	}
+	// the page numbers are not known before the layout
+	got := resolve()
+	if want := " (Two, II, p. )"; got[0] != want {
+		t.Errorf("forward reference = %q, want %q", got[0], want)
+	}
+	if refs := c.PageReferences(); len(refs) != 2 || refs[0] != "one" || refs[1] != "two" {
+		t.Errorf("PageReferences() = %v, want [one two]", refs)
+	}
+	c.TargetPages = map[string]int{"one": 3, "two": 5}
+	got = resolve()
+	for i, want := range []string{" (Two, II, p. 5)", " (One, I, p. 3)"} {
+		if got[i] != want {
+			t.Errorf("reference %d = %q, want %q", i, got[i], want)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package csshtml
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// counter is an instance of a CSS counter. outer is the counter with the same
+// name of an enclosing element.
+type counter struct {
+	value int
+	level int
+	outer *counter
+}
+
+// counterScope contains the counters visible to an element and its following
+// siblings.
+type counterScope map[string]*counter
+
+func (cs counterScope) copy() counterScope {
+	ret := make(counterScope, len(cs))
+	for k, v := range cs {
+		ret[k] = v
+	}
+	return ret
+}
+
+// reset instantiates a new counter. A counter of a preceding sibling is
+// replaced, a counter of an ancestor is nested.
+func (cs counterScope) reset(name string, value int, level int) {
+	c := &counter{value: value, level: level}
+	if old := cs[name]; old != nil {
+		c.outer = old
+		if old.level == level {
+			c.outer = old.outer
+		}
+	}
+	cs[name] = c
+}
+
+func (cs counterScope) increment(name string, by int, level int) {
+	if cs[name] == nil {
+		cs.reset(name, 0, level)
+	}
+	cs[name].value += by
+}
+
+func (cs counterScope) set(name string, value int, level int) {
+	if cs[name] == nil {
+		cs.reset(name, value, level)
+	}
+	cs[name].value = value
+}
+
+// values returns the values of the nested counters with the name, the
+// outermost counter first.
+func (cs counterScope) values(name string) []int {
+	var values []int
+	for c := cs[name]; c != nil; c = c.outer {
+		values = append([]int{c.value}, values...)
+	}
+	return values
+}
+
+// crossReferences contains the targets of target-counter() and target-text().
+// The targets are collected in a first pass through the document, so forward
+// references can be resolved.
+type crossReferences struct {
+	css *CSS
+	// collect is true during the first pass.
+	collect bool
+	// elements are the elements with an id.
+	elements map[string]*html.Node
+	// counters are the counter values at the elements with an id.
+	counters map[string]map[string][]int
+}
+
+func newCrossReferences(c *CSS) *crossReferences {
+	return &crossReferences{
+		css:      c,
+		collect:  true,
+		elements: make(map[string]*html.Node),
+		counters: make(map[string]map[string][]int),
+	}
+}
+
+// addTarget remembers the element and its counters if it has an id.
+func (cr *crossReferences) addTarget(n *html.Node, cs counterScope) {
+	id := htmlAttr(n, "id")
+	if id == "" {
+		return
+	}
+	cr.elements[id] = n
+	counters := make(map[string][]int, len(cs))
+	for name := range cs {
+		counters[name] = cs.values(name)
+	}
+	cr.counters[id] = counters
+}
+
+// targetCounter returns the values of the counter at the element with the id.
+// The page counter is taken from the page numbers of the last layout pass.
+func (cr *crossReferences) targetCounter(id, name string) ([]int, bool) {
+	if name == "page" {
+		if cr.css.pageReferences == nil {
+			cr.css.pageReferences = make(map[string]bool)
+		}
+		cr.css.pageReferences[id] = true
+		page, ok := cr.css.TargetPages[id]
+		if !ok {
+			return nil, false
+		}
+		return []int{page}, true
+	}
+	values, ok := cr.counters[id][name]
+	if !ok {
+		if _, ok = cr.elements[id]; !ok {
+			return nil, false
+		}
+		values = []int{0}
+	}
+	return values, true
+}
+
+// targetID returns the id of a local link such as #intro.
+func targetID(url string) string {
+	url = strings.Trim(strings.TrimSpace(url), `"'`)
+	return strings.TrimPrefix(url, "#")
+}
+
+// styleAttr returns the resolved CSS property of the element or the pseudo
+// element (such as "before").
+func styleAttr(n *html.Node, pseudo, key string) (string, bool) {
+	if pseudo != "" {
+		key = pseudo + "::" + key
+	}
+	key = "!" + key
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func setStyleAttr(n *html.Node, pseudo, key, value string) {
+	if pseudo != "" {
+		key = pseudo + "::" + key
+	}
+	key = "!" + key
+	for i, attr := range n.Attr {
+		if attr.Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
+
+// applyCounterProperties changes the counters in cs according to the
+// counter-reset, counter-increment and counter-set properties of the element
+// or pseudo element. List items increment the list-item counter which is
+// reset by lists.
+func applyCounterProperties(n *html.Node, pseudo string, cs counterScope, level int) {
+	reset, _ := styleAttr(n, pseudo, "counter-reset")
+	resets := ParseCounterList(reset, 0)
+	increment, _ := styleAttr(n, pseudo, "counter-increment")
+	increments := ParseCounterList(increment, 1)
+	if pseudo == "" {
+		switch n.Data {
+		case "ol", "ul":
+			if !strings.Contains(reset, "list-item") {
+				start := 0
+				if s, err := strconv.Atoi(htmlAttr(n, "start")); err == nil {
+					start = s - 1
+				}
+				resets = append(resets, Counter{Name: "list-item", Value: start})
+			}
+		case "li":
+			if !strings.Contains(increment, "list-item") {
+				increments = append(increments, Counter{Name: "list-item", Value: 1})
+			}
+		}
+	}
+	for _, c := range resets {
+		cs.reset(c.Name, c.Value, level)
+	}
+	for _, c := range increments {
+		cs.increment(c.Name, c.Value, level)
+	}
+	set, _ := styleAttr(n, pseudo, "counter-set")
+	for _, c := range ParseCounterList(set, 0) {
+		cs.set(c.Name, c.Value, level)
+	}
+}
+
+// htmlAttr returns the HTML attribute of the element.
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// textContent returns the text of the element with normalized white space.
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.Type {
+			case html.TextNode:
+				sb.WriteString(c.Data)
+			case html.ElementNode:
+				collect(c)
+			}
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// elementContent resolves the content functions of an element.
+type elementContent struct {
+	n     *html.Node
+	scope counterScope
+	refs  *crossReferences
+	// before and after are the texts of the pseudo elements
+	before string
+	after  string
+}
+
+func (ec *elementContent) Counter(name string) ([]int, bool) {
+	if ec.scope[name] == nil {
+		// page and footnote counters are resolved when the page is built
+		if name == "page" || name == "pages" || name == "footnote" {
+			return nil, false
+		}
+		return []int{0}, true
+	}
+	return ec.scope.values(name), true
+}
+
+func (ec *elementContent) Function(name string, args []string) (string, bool) {
+	var arg string
+	if len(args) > 0 {
+		arg = strings.TrimSpace(args[0])
+	}
+	switch name {
+	case "attr":
+		return htmlAttr(ec.n, arg), true
+	case "content":
+		switch arg {
+		case "", "text", "contents":
+			return textContent(ec.n), true
+		case "before":
+			return ec.before, true
+		case "after":
+			return ec.after, true
+		case "first-letter":
+			for _, r := range textContent(ec.n) {
+				return string(r), true
+			}
+			return "", true
+		}
+	case "target-counter", "target-counters":
+		if ec.refs == nil || len(args) < 2 {
+			return "", false
+		}
+		values, ok := ec.refs.targetCounter(targetID(arg), strings.TrimSpace(args[1]))
+		if !ok {
+			return "", false
+		}
+		var separator, style string
+		if name == "target-counter" {
+			if len(args) > 2 {
+				style = strings.TrimSpace(args[2])
+			}
+			values = values[len(values)-1:]
+		} else {
+			if len(args) > 2 {
+				separator = args[2]
+			}
+			if len(args) > 3 {
+				style = strings.TrimSpace(args[3])
+			}
+		}
+		return formatCounters(values, separator, style), true
+	case "target-text":
+		if ec.refs == nil {
+			return "", false
+		}
+		target := ec.refs.elements[targetID(arg)]
+		if target == nil {
+			return "", false
+		}
+		what := "content"
+		if len(args) > 1 {
+			what = strings.TrimSpace(args[1])
+		}
+		switch what {
+		case "before", "after":
+			content, _ := styleAttr(target, what, "content")
+			return ContentText(content), true
+		case "first-letter":
+			for _, r := range textContent(target) {
+				return string(r), true
+			}
+			return "", true
+		}
+		return textContent(target), true
+	}
+	return "", false
+}
+
+// resolvePseudoContent applies the counter properties of the pseudo element
+// and resolves its content property. It returns the text of the pseudo
+// element.
+func resolvePseudoContent(n *html.Node, pseudo string, cs counterScope, level int, refs *crossReferences) string {
+	content, ok := styleAttr(n, pseudo, "content")
+	if !ok {
+		return ""
+	}
+	applyCounterProperties(n, pseudo, cs, level)
+	if refs.collect {
+		return ""
+	}
+	content = ResolveContent(content, &elementContent{n: n, scope: cs, refs: refs})
+	setStyleAttr(n, pseudo, "content", content)
+	return ContentText(content)
+}
+
+// applyCounters walks through the children of n in document order, updates
+// the counters and resolves the content of pseudo elements and the string-set
+// property. Functions which can only be resolved during page building (such as
+// counter(page)) are kept in the values. If refs.collect is true, only the
+// targets of cross references are collected.
+func applyCounters(n *html.Node, cs counterScope, level int, refs *crossReferences) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		applyCounterProperties(c, "", cs, level)
+		if refs.collect {
+			refs.addTarget(c, cs)
+		}
+		// the pseudo elements are the first and the last child of the element
+		childScope := cs.copy()
+		ec := &elementContent{n: c, scope: cs, refs: refs}
+		ec.before = resolvePseudoContent(c, "before", childScope, level+1, refs)
+		applyCounters(c, childScope, level+1, refs)
+		ec.after = resolvePseudoContent(c, "after", childScope, level+1, refs)
+		if refs.collect {
+			continue
+		}
+		for _, pseudo := range []string{"marker", "footnote-call", "footnote-marker"} {
+			if content, ok := styleAttr(c, pseudo, "content"); ok {
+				setStyleAttr(c, pseudo, "content", ResolveContent(content, &elementContent{n: c, scope: cs, refs: refs}))
+			}
+		}
+		if stringSet, ok := styleAttr(c, "", "string-set"); ok {
+			var assignments []string
+			for _, s := range ParseStringSet(stringSet) {
+				assignments = append(assignments, s.Name+" "+ResolveContent(s.Value, ec))
+			}
+			setStyleAttr(c, "", "string-set", strings.Join(assignments, " , "))
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -48,7 +49,24 @@ type CSS struct {
 	Stylesheet       []sBlock
 	Pages            map[string]Page
 	FileFinder       func(string) (string, error)
-	dirstack         []string
+	// TargetPages contains the page numbers of the elements with an id. It is
+	// used for target-counter(..., page) and usually filled with the result of
+	// a previous layout pass.
+	TargetPages map[string]int
+	dirstack    []string
+	// pageReferences are the ids of the targets of target-counter(..., page).
+	pageReferences map[string]bool
+}
+
+// PageReferences returns the sorted ids of the elements whose page numbers are
+// requested with target-counter(..., page) in the documents read so far.
+func (c *CSS) PageReferences() []string {
+	ret := make([]string, 0, len(c.pageReferences))
+	for id := range c.pageReferences {
+		ret = append(ret, id)
+	}
+	sort.Strings(ret)
+	return ret
 }
 
 // PushDir adds a directory to the dir stack. When a file is opened, all new
@@ -112,7 +130,7 @@ h4, p,
 blockquote, ul,
 fieldset, form,
 ol, dl, dir,
-h5              { font-size: 1em; margin: 1.5em 0; text-align: left; }
+h5              { font-size: 1em; margin: 1.5em 0; text-align: start; }
 h6              { font-size: .75em; margin: 1.67em 0 }
 h1, h2, h3, h4,
 h5, h6, b,
@@ -142,6 +160,15 @@ ol ul, ul ol,
 ul ul, ol ol    { margin-top: 0; margin-bottom: 0 }
 u, ins          { text-decoration: underline }
 center          { text-align: center }
+[dir=rtl]       { direction: rtl }
+[dir=ltr]       { direction: ltr }
+.toc-entry      { display: block; text-align: start }
+.toc-entry a::after { content: leader(dotted) target-counter(attr(href), page) }
+.toc-level-2    { margin-left: 1em }
+.toc-level-3    { margin-left: 2em }
+.toc-level-4    { margin-left: 3em }
+.toc-level-5    { margin-left: 4em }
+.toc-level-6    { margin-left: 5em }
 `
 
 // :link           { text-decoration: underline }
@@ -6,7 +6,9 @@ import (
 	"io"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"golang.org/x/net/html"
 
@@ -60,7 +62,7 @@ func stringValue(toks tokenstream) string {
 			switch tok.Value {
 			case ";":
 				// ignore
-			case ",", ")":
+			case ",", ")", "/":
 				ret = append(ret, tok.Value)
 			case "-":
 				negative = true
@@ -190,6 +192,106 @@ func parseBorderAttribute(input string) (width string, style string, color strin
 	return
 }
 
+// parseTextDecoration splits the text-decoration shorthand into its line,
+// style, color and thickness parts. Empty strings are returned for the parts
+// that are not set.
+func parseTextDecoration(input string) (line string, style string, color string, thickness string) {
+	s := bufio.NewScanner(strings.NewReader(input))
+	s.Split(bufio.ScanWords)
+	var lines []string
+	for s.Scan() {
+		t := s.Text()
+		switch t {
+		case "none", "underline", "overline", "line-through":
+			lines = append(lines, t)
+			continue
+		case "solid", "double", "dotted", "dashed", "wavy":
+			style = t
+			continue
+		case "auto", "from-font":
+			thickness = t
+			continue
+		}
+		if ok, wd := isDimension(t); ok || strings.HasSuffix(t, "%") {
+			thickness = wd
+			continue
+		}
+		if colorMatcher.MatchString(t) {
+			color = t
+			for s.Scan() {
+				color += " " + s.Text()
+			}
+			break
+		}
+		color = t
+	}
+	line = strings.Join(lines, " ")
+	return
+}
+
+// splitFunctionFields splits the input at white space that is not inside of
+// parentheses.
+func splitFunctionFields(input string) []string {
+	var fields []string
+	var cur strings.Builder
+	depth := 0
+	for _, r := range input {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case unicode.IsSpace(r) && depth == 0:
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+// parseBackground splits the background shorthand into the longhand
+// properties. Only the properties that are set are returned.
+func parseBackground(input string) map[string]string {
+	ret := make(map[string]string)
+	var position, size, repeat []string
+	afterSlash := false
+	for _, t := range splitFunctionFields(input) {
+		switch {
+		case t == "/":
+			afterSlash = true
+		case t == "none" || strings.HasPrefix(t, "url(") || strings.Contains(t, "gradient("):
+			ret["background-image"] = t
+		case t == "repeat" || t == "repeat-x" || t == "repeat-y" || t == "no-repeat" || t == "space" || t == "round":
+			repeat = append(repeat, t)
+		case t == "scroll" || t == "fixed" || t == "local" || t == "border-box" || t == "padding-box" || t == "content-box":
+			// not supported
+		case afterSlash && (t == "cover" || t == "contain" || t == "auto" || dimen.MatchString(t) || strings.HasSuffix(t, "%")):
+			size = append(size, t)
+		case t == "left" || t == "right" || t == "top" || t == "bottom" || t == "center" || dimen.MatchString(t) || strings.HasSuffix(t, "%"):
+			position = append(position, t)
+		default:
+			ret["background-color"] = t
+		}
+	}
+	if len(position) > 0 {
+		ret["background-position"] = strings.Join(position, " ")
+	}
+	if len(size) > 0 {
+		ret["background-size"] = strings.Join(size, " ")
+	}
+	if len(repeat) > 0 {
+		ret["background-repeat"] = strings.Join(repeat, " ")
+	}
+	return ret
+}
+
 // ResolveAttributes returns the resolved styles and the attributes of the node.
 // It changes "margin: 1cm;" into "margin-left: 1cm; margin-right: 1cm; ...".
 func ResolveAttributes(attrs []html.Attribute) (resolved map[string]string, attributes map[string]string, newAttributes []html.Attribute) {
@@ -201,13 +303,26 @@ func ResolveAttributes(attrs []html.Attribute) (resolved map[string]string, attr
 	//    border-left-style: dotted;
 	//    border-left: thick green;
 	// because the second line overrides the first line (style defaults to "none")
+	//
+	// The properties of pseudo elements (before::margin) are resolved
+	// separately and get their prefix back.
+	pseudoAttributes := make(map[string][]html.Attribute)
+	var pseudoElements []string
 	for _, attr := range attrs {
 		key := attr.Key
 		if !strings.HasPrefix(key, "!") {
+			attributes[key] = attr.Val
 			newAttributes = append(newAttributes, attr)
 			continue
 		}
 		key = strings.TrimPrefix(key, "!")
+		if pe, prop, ok := strings.Cut(key, "::"); ok {
+			if _, found := pseudoAttributes[pe]; !found {
+				pseudoElements = append(pseudoElements, pe)
+			}
+			pseudoAttributes[pe] = append(pseudoAttributes[pe], html.Attribute{Key: "!" + prop, Val: attr.Val})
+			continue
+		}
 
 		switch key {
 		case "margin":
@@ -294,6 +409,29 @@ func ResolveAttributes(attrs []html.Attribute) (resolved map[string]string, attr
 				)
 			}
 			resolved[key] = attr.Val
+		case "column-rule":
+			wd, sty, col := parseBorderAttribute(attr.Val)
+			resolved[key+"-width"], resolved[key+"-style"], resolved[key+"-color"] = wd, sty, col
+			newAttributes = append(newAttributes,
+				html.Attribute{Key: "!" + key + "-width", Val: wd},
+				html.Attribute{Key: "!" + key + "-style", Val: sty},
+				html.Attribute{Key: "!" + key + "-color", Val: col},
+			)
+		case "columns":
+			// columns: <column-width> || <column-count>
+			count, width := "auto", "auto"
+			for _, part := range strings.Fields(attr.Val) {
+				if _, err := strconv.Atoi(part); err == nil {
+					count = part
+				} else if ok, wd := isDimension(part); ok {
+					width = wd
+				}
+			}
+			resolved["column-count"], resolved["column-width"] = count, width
+			newAttributes = append(newAttributes,
+				html.Attribute{Key: "!column-count", Val: count},
+				html.Attribute{Key: "!column-width", Val: width},
+			)
 		case "font":
 			fontstyle := "normal"
 			fontweight := "normal"
@@ -344,31 +482,29 @@ func ResolveAttributes(attrs []html.Attribute) (resolved map[string]string, attr
 		// semi-condensed; normal; semi-expanded; expanded; extra-expanded;
 		// ultra-expanded;
 		case "text-decoration":
-			for _, part := range strings.Split(attr.Val, " ") {
-				if part == "none" || part == "underline" || part == "overline" || part == "line-through" {
-					resolved["text-decoration-line"] = part
-					newAttributes = append(newAttributes,
-						html.Attribute{Key: "!text-decoration-line", Val: part},
-					)
-
-				} else if part == "solid" || part == "double" || part == "dotted" || part == "dashed" || part == "wavy" {
-					resolved["text-decoration-style"] = part
-					newAttributes = append(newAttributes,
-						html.Attribute{Key: "!text-decoration-style", Val: part},
-					)
+			line, sty, col, thickness := parseTextDecoration(attr.Val)
+			for _, part := range []struct{ name, value string }{{"line", line}, {"style", sty}, {"color", col}, {"thickness", thickness}} {
+				if part.value == "" {
+					continue
 				}
+				resolved["text-decoration-"+part.name] = part.value
+				newAttributes = append(newAttributes,
+					html.Attribute{Key: "!text-decoration-" + part.name, Val: part.value},
+				)
 			}
 
 		case "background":
 			// background-clip, background-color, background-image,
 			// background-origin, background-position, background-repeat,
 			// background-size, and background-attachment
-			for _, part := range strings.Split(attr.Val, " ") {
-				resolved["background-color"] = part
-				newAttributes = append(newAttributes,
-					html.Attribute{Key: "!background-color", Val: part},
-				)
-
+			parts := parseBackground(attr.Val)
+			for _, prop := range []string{"background-color", "background-image", "background-position", "background-size", "background-repeat"} {
+				if val, ok := parts[prop]; ok {
+					resolved[prop] = val
+					newAttributes = append(newAttributes,
+						html.Attribute{Key: "!" + prop, Val: val},
+					)
+				}
 			}
 		default:
 			resolved[key] = attr.Val
@@ -376,21 +512,80 @@ func ResolveAttributes(attrs []html.Attribute) (resolved map[string]string, attr
 		}
 	}
 
-	if str, ok := resolved["text-decoration-line"]; ok && str != "none" {
+	if str, ok := resolved["text-decoration-line"]; ok && str != "none" && resolved["text-decoration-style"] == "" {
 		resolved["text-decoration-style"] = "solid"
 		newAttributes = append(newAttributes,
 			html.Attribute{Key: "!text-decoration-style", Val: "solid"},
 		)
 	}
+	for _, pe := range pseudoElements {
+		res, _, pseudoAttrs := ResolveAttributes(pseudoAttributes[pe])
+		for k, v := range res {
+			resolved[pe+"::"+k] = v
+		}
+		for _, attr := range pseudoAttrs {
+			newAttributes = append(newAttributes, html.Attribute{Key: "!" + pe + "::" + strings.TrimPrefix(attr.Key, "!"), Val: attr.Val})
+		}
+	}
 	return
 }
 
+// footnotePseudoElements are the pseudo elements of the footnotes which the
+// selector parser does not know.
+var footnotePseudoElements = []string{"footnote-call", "footnote-marker"}
+
+// parseSelectorGroup parses the comma separated selectors and returns the
+// selectors and their pseudo elements.
+func parseSelectorGroup(selector string) ([]cascadia.Sel, []string, error) {
+	var hasFootnotePseudo bool
+	for _, pe := range footnotePseudoElements {
+		if strings.Contains(selector, "::"+pe) {
+			hasFootnotePseudo = true
+		}
+	}
+	if !hasFootnotePseudo {
+		group, err := cascadia.ParseGroupWithPseudoElements(selector)
+		if err != nil {
+			return nil, nil, err
+		}
+		pseudos := make([]string, len(group))
+		for i, sel := range group {
+			pseudos[i] = sel.PseudoElement()
+		}
+		return group, pseudos, nil
+	}
+	var selectors []cascadia.Sel
+	var pseudos []string
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		var pseudo string
+		for _, pe := range footnotePseudoElements {
+			if strings.HasSuffix(part, "::"+pe) {
+				part = strings.TrimSuffix(part, "::"+pe)
+				pseudo = pe
+			}
+		}
+		sel, err := cascadia.ParseWithPseudoElement(part)
+		if err != nil {
+			return nil, nil, err
+		}
+		if pseudo == "" {
+			pseudo = sel.PseudoElement()
+		}
+		selectors = append(selectors, sel)
+		pseudos = append(pseudos, pseudo)
+	}
+	return selectors, pseudos, nil
+}
+
 // ApplyCSS resolves CSS rules in the DOM. Each CSS rule is added to the
 // selection as an attribute (prefixed with a !). Pseudo elements are prefixed
-// with ::.
+// with ::. The counters are evaluated in document order and the content of
+// pseudo elements and the string-set property are resolved as far as possible.
 func (c *CSS) ApplyCSS(doc *goquery.Document) (*goquery.Document, error) {
 	type selRule struct {
 		selector cascadia.Sel
+		pseudo   string
 		rule     []qrule
 	}
 
@@ -399,14 +594,14 @@ func (c *CSS) ApplyCSS(doc *goquery.Document) (*goquery.Document, error) {
 	for _, stylesheet := range c.Stylesheet {
 		for _, block := range stylesheet.Blocks {
 			selector := block.ComponentValues.String()
-			selectors, err := cascadia.ParseGroupWithPseudoElements(selector)
+			selectors, pseudos, err := parseSelectorGroup(selector)
 			if err != nil {
 				return nil, err
 			}
-			for _, sel := range selectors {
+			for i, sel := range selectors {
 				selSpecificity := sel.Specificity()
 				s := selSpecificity[0]*100 + selSpecificity[1]*10 + selSpecificity[2]
-				rules[s] = append(rules[s], selRule{selector: sel, rule: block.Rules})
+				rules[s] = append(rules[s], selRule{selector: sel, pseudo: pseudos[i], rule: block.Rules})
 			}
 		}
 	}
@@ -423,7 +618,7 @@ func (c *CSS) ApplyCSS(doc *goquery.Document) (*goquery.Document, error) {
 			for _, singlerule := range r.rule {
 				for _, node := range cascadia.QueryAll(root, r.selector) {
 					var prefix string
-					if pe := r.selector.PseudoElement(); pe != "" {
+					if pe := r.pseudo; pe != "" {
 						prefix = pe + "::"
 					}
 					// remove attributes with the same name, since the new ones
@@ -444,6 +639,11 @@ func (c *CSS) ApplyCSS(doc *goquery.Document) (*goquery.Document, error) {
 	}
 
 	doc.Each(resolveStyle)
+	// the first pass collects the targets of cross references
+	refs := newCrossReferences(c)
+	applyCounters(root, counterScope{}, 0, refs)
+	refs.collect = false
+	applyCounters(root, counterScope{}, 0, refs)
 	return doc, nil
 }
 
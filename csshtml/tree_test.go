@@ -2,6 +2,8 @@ package csshtml
 
 import (
 	"testing"
+
+	"golang.org/x/net/html"
 )
 
 func TestParseBorder(t *testing.T) {
@@ -37,3 +39,98 @@ func TestParseBorder(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTextDecoration(t *testing.T) {
+	testCases := []struct {
+		input     string
+		line      string
+		style     string
+		color     string
+		thickness string
+	}{
+		{"underline", "underline", "", "", ""},
+		{"underline overline", "underline overline", "", "", ""},
+		{"line-through red", "line-through", "", "red", ""},
+		{"underline dotted 2pt", "underline", "dotted", "", "2pt"},
+		{"wavy underline #f00", "underline", "wavy", "#f00", ""},
+		{"overline 10% rgb(0, 0, 255)", "overline", "", "rgb(0, 0, 255)", "10%"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.input, func(t *testing.T) {
+			line, sty, col, thickness := parseTextDecoration(tC.input)
+			if line != tC.line || sty != tC.style || col != tC.color || thickness != tC.thickness {
+				t.Errorf(`parseTextDecoration(%s) got "%s|%s|%s|%s" want "%s|%s|%s|%s"`, tC.input, line, sty, col, thickness, tC.line, tC.style, tC.color, tC.thickness)
+			}
+		})
+	}
+}
+
+func TestParseBackground(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  map[string]string
+	}{
+		{"red", map[string]string{"background-color": "red"}},
+		{"url(img.png) no-repeat center / cover #eee", map[string]string{
+			"background-image":    "url(img.png)",
+			"background-repeat":   "no-repeat",
+			"background-position": "center",
+			"background-size":     "cover",
+			"background-color":    "#eee",
+		}},
+		{"linear-gradient(to right, red, blue) left 10pt", map[string]string{
+			"background-image":    "linear-gradient(to right, red, blue)",
+			"background-position": "left 10pt",
+		}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.input, func(t *testing.T) {
+			got := parseBackground(tC.input)
+			if len(got) != len(tC.want) {
+				t.Errorf("parseBackground(%s) got %v want %v", tC.input, got, tC.want)
+			}
+			for k, v := range tC.want {
+				if got[k] != v {
+					t.Errorf("parseBackground(%s)[%s] got %q want %q", tC.input, k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestResolvePseudoAttributes(t *testing.T) {
+	attrs := []html.Attribute{
+		{Key: "!margin", Val: "1pt"},
+		{Key: "!before::margin", Val: "2pt"},
+		{Key: "!before::content", Val: `"x"`},
+		{Key: "class", Val: "note"},
+	}
+	resolved, attributes, _ := ResolveAttributes(attrs)
+	if got := resolved["margin-left"]; got != "1pt" {
+		t.Errorf("margin-left = %q, want 1pt", got)
+	}
+	if got := resolved["before::margin-left"]; got != "2pt" {
+		t.Errorf("before::margin-left = %q, want 2pt", got)
+	}
+	if got := resolved["before::content"]; got != `"x"` {
+		t.Errorf("before::content = %q, want \"x\"", got)
+	}
+	if got := attributes["class"]; got != "note" {
+		t.Errorf("class = %q, want note", got)
+	}
+}
+
+func TestParseSelectorGroup(t *testing.T) {
+	selectors, pseudos, err := parseSelectorGroup("span.fn::footnote-call, li::marker, p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selectors) != 3 {
+		t.Fatalf("len(selectors) = %d, want 3", len(selectors))
+	}
+	for i, want := range []string{"footnote-call", "marker", ""} {
+		if pseudos[i] != want {
+			t.Errorf("pseudos[%d] = %q, want %q", i, pseudos[i], want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package frontend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	pdf "github.com/speedata/baseline-pdf"
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/color"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/frontend/pdfdraw"
+)
+
+// BackgroundRepeat determines if and how a background image is tiled.
+type BackgroundRepeat int
+
+const (
+	// BackgroundRepeatRepeat tiles the image horizontally and vertically.
+	BackgroundRepeatRepeat BackgroundRepeat = iota
+	// BackgroundRepeatRepeatX tiles the image horizontally.
+	BackgroundRepeatRepeatX
+	// BackgroundRepeatRepeatY tiles the image vertically.
+	BackgroundRepeatRepeatY
+	// BackgroundRepeatNoRepeat places the image once.
+	BackgroundRepeatNoRepeat
+)
+
+func (br BackgroundRepeat) String() string {
+	switch br {
+	case BackgroundRepeatRepeat:
+		return "repeat"
+	case BackgroundRepeatRepeatX:
+		return "repeat-x"
+	case BackgroundRepeatRepeatY:
+		return "repeat-y"
+	case BackgroundRepeatNoRepeat:
+		return "no-repeat"
+	}
+	return "???"
+}
+
+// ParseBackgroundRepeat interprets the CSS background-repeat value.
+func ParseBackgroundRepeat(s string) (BackgroundRepeat, error) {
+	switch strings.Join(strings.Fields(s), " ") {
+	case "repeat", "repeat repeat":
+		return BackgroundRepeatRepeat, nil
+	case "repeat-x", "repeat no-repeat":
+		return BackgroundRepeatRepeatX, nil
+	case "repeat-y", "no-repeat repeat":
+		return BackgroundRepeatRepeatY, nil
+	case "no-repeat", "no-repeat no-repeat":
+		return BackgroundRepeatNoRepeat, nil
+	}
+	return BackgroundRepeatRepeat, fmt.Errorf("unsupported background-repeat %q", s)
+}
+
+// BackgroundSize determines the size of a background image.
+type BackgroundSize int
+
+const (
+	// BackgroundSizeAuto uses the Width and Height fields of the background
+	// image.
+	BackgroundSizeAuto BackgroundSize = iota
+	// BackgroundSizeCover scales the image to cover the whole background area.
+	BackgroundSizeCover
+	// BackgroundSizeContain scales the image to fit into the background area.
+	BackgroundSizeContain
+)
+
+func (bs BackgroundSize) String() string {
+	switch bs {
+	case BackgroundSizeAuto:
+		return "auto"
+	case BackgroundSizeCover:
+		return "cover"
+	case BackgroundSizeContain:
+		return "contain"
+	}
+	return "???"
+}
+
+// BackgroundImage is an image in the background of a box. The background area
+// is the border box.
+type BackgroundImage struct {
+	ImageFile *pdf.Imagefile
+	// Size is the scaling of the image. With BackgroundSizeAuto, Width and
+	// Height are used.
+	Size BackgroundSize
+	// Width and Height are "auto", a length or a percentage of the background
+	// area. If one of them is auto (or empty), the aspect ratio of the image is
+	// kept.
+	Width  string
+	Height string
+	// PositionX and PositionY align the image in the background area, 0 is
+	// left (top) and 1 is right (bottom). The offsets are added.
+	PositionX float64
+	PositionY float64
+	OffsetX   bag.ScaledPoint
+	OffsetY   bag.ScaledPoint
+	Repeat    BackgroundRepeat
+}
+
+// SetSize sets the size from a CSS background-size value (cover, contain or
+// one or two lengths, percentages or auto).
+func (bi *BackgroundImage) SetSize(s string) error {
+	fields := strings.Fields(s)
+	switch {
+	case s == "cover":
+		bi.Size = BackgroundSizeCover
+	case s == "contain":
+		bi.Size = BackgroundSizeContain
+	case len(fields) == 1 || len(fields) == 2:
+		for _, f := range fields {
+			if f == "auto" || strings.HasSuffix(f, "%") {
+				continue
+			}
+			if _, err := bag.Sp(f); err != nil {
+				return fmt.Errorf("invalid background-size %q", s)
+			}
+		}
+		bi.Size = BackgroundSizeAuto
+		bi.Width = fields[0]
+		bi.Height = "auto"
+		if len(fields) == 2 {
+			bi.Height = fields[1]
+		}
+	default:
+		return fmt.Errorf("invalid background-size %q", s)
+	}
+	return nil
+}
+
+// SetPosition sets the position from a CSS background-position value.
+func (bi *BackgroundImage) SetPosition(s string) error {
+	pos, err := parseBackgroundPosition(strings.Fields(s))
+	if err != nil {
+		return err
+	}
+	bi.PositionX, bi.PositionY = pos.x, pos.y
+	bi.OffsetX, bi.OffsetY = pos.offsetX, pos.offsetY
+	return nil
+}
+
+// sizeValue resolves a background-size component. The boolean is false for
+// auto.
+func sizeValue(s string, area bag.ScaledPoint) (bag.ScaledPoint, bool) {
+	if s == "" || s == "auto" {
+		return 0, false
+	}
+	if strings.HasSuffix(s, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return 0, false
+		}
+		return bag.MultiplyFloat(area, p/100), true
+	}
+	sp, err := bag.Sp(s)
+	if err != nil {
+		return 0, false
+	}
+	return sp, true
+}
+
+// imageSize returns the size of one image tile in a background area with the
+// dimensions wd and ht. iw and ih are the natural dimensions of the image.
+func (bi *BackgroundImage) imageSize(iw, ih, wd, ht bag.ScaledPoint) (bag.ScaledPoint, bag.ScaledPoint) {
+	if iw <= 0 || ih <= 0 {
+		return 0, 0
+	}
+	ratio := iw.ToPT() / ih.ToPT()
+	switch bi.Size {
+	case BackgroundSizeCover, BackgroundSizeContain:
+		scale := math.Min(wd.ToPT()/iw.ToPT(), ht.ToPT()/ih.ToPT())
+		if bi.Size == BackgroundSizeCover {
+			scale = math.Max(wd.ToPT()/iw.ToPT(), ht.ToPT()/ih.ToPT())
+		}
+		return bag.MultiplyFloat(iw, scale), bag.MultiplyFloat(ih, scale)
+	}
+	w, hasWidth := sizeValue(bi.Width, wd)
+	h, hasHeight := sizeValue(bi.Height, ht)
+	switch {
+	case hasWidth && hasHeight:
+		return w, h
+	case hasWidth:
+		return w, bag.MultiplyFloat(w, 1/ratio)
+	case hasHeight:
+		return bag.MultiplyFloat(h, ratio), h
+	}
+	return iw, ih
+}
+
+// tileStart returns the position of the first tile so that the tiles starting
+// at pos cover the area from 0.
+func tileStart(pos, size bag.ScaledPoint) bag.ScaledPoint {
+	if pos <= 0 {
+		return pos
+	}
+	n := int(math.Ceil(float64(pos) / float64(size)))
+	return pos - bag.ScaledPoint(n)*size
+}
+
+// maxBackgroundTiles limits the number of image tiles of one background.
+const maxBackgroundTiles = 1000
+
+// imageNodes returns the image tiles for the background area with the top
+// left corner at (x, y) and the size wd × ht. Each tile is wrapped in a
+// vertical list without height so the surrounding list is not changed.
+func (bi *BackgroundImage) imageNodes(d *Document, x, y, wd, ht bag.ScaledPoint) node.Node {
+	// only used for the natural size
+	img := d.Doc.CreateImage(bi.ImageFile, 1, "/MediaBox")
+	if img == nil {
+		return nil
+	}
+	iw, ih := bi.imageSize(img.Width, img.Height, wd, ht)
+	if iw <= 0 || ih <= 0 {
+		return nil
+	}
+	posX := bag.MultiplyFloat(wd-iw, bi.PositionX) + bi.OffsetX
+	posY := bag.MultiplyFloat(ht-ih, bi.PositionY) + bi.OffsetY
+	endX, endY := posX+iw, posY+ih
+	if bi.Repeat == BackgroundRepeatRepeat || bi.Repeat == BackgroundRepeatRepeatX {
+		posX = tileStart(posX, iw)
+		endX = wd
+	}
+	if bi.Repeat == BackgroundRepeatRepeat || bi.Repeat == BackgroundRepeatRepeatY {
+		posY = tileStart(posY, ih)
+		endY = ht
+	}
+	var head node.Node
+	tiles := 0
+	for ty := posY; ty < endY; ty += ih {
+		for tx := posX; tx < endX; tx += iw {
+			if tiles == maxBackgroundTiles {
+				bag.Logger.Warn("too many background image tiles", "filename", bi.ImageFile.Filename)
+				return head
+			}
+			tiles++
+			imgNode := node.NewImage()
+			imgNode.Img = d.Doc.CreateImage(bi.ImageFile, 1, "/MediaBox")
+			imgNode.Width = iw
+			imgNode.Height = ih
+			imgVL := node.NewVList()
+			imgVL.List = imgNode
+			imgVL.Width = iw
+			imgVL.Height = ih
+
+			// images in a vertical list are placed at the top of the list, so
+			// the glue moves the inner list down
+			g := node.NewGlue()
+			g.Width = ty - y
+			g.Attributes = node.H{"origin": "background image position"}
+			wrapper := node.NewVList()
+			wrapper.List = node.InsertAfter(g, g, imgVL)
+			wrapper.ShiftX = x + tx
+			wrapper.Attributes = node.H{"origin": "background image"}
+			head = node.InsertAfter(head, node.Tail(head), wrapper)
+		}
+	}
+	return head
+}
+
+// backgroundRule returns a hidden rule with the PDF instructions.
+func backgroundRule(pre, origin string) *node.Rule {
+	r := node.NewRule()
+	r.Hide = true
+	r.Pre = pre
+	r.Attributes = node.H{"origin": origin}
+	return r
+}
+
+// backgroundNodes returns the nodes that draw the background color, gradient
+// and image of hv. The background area has the top left corner at (x, y) and
+// the size wd × ht. Everything is clipped to the path returned by clip.
+func (d *Document) backgroundNodes(hv HTMLValues, clip func() *pdfdraw.Object, x, y, wd, ht bag.ScaledPoint) node.Node {
+	var head node.Node
+	if hv.BackgroundColor != nil && hv.BackgroundColor.Space != color.ColorNone {
+		p := clip().Clip().Endpath()
+		p.ColorNonstroking(*hv.BackgroundColor).Rect(x, y-ht, wd, ht).Fill()
+		head = node.InsertAfter(head, node.Tail(head), backgroundRule("q "+p.String()+" Q", "html background color"))
+	}
+	if hv.BackgroundGradient != nil {
+		p := clip().Clip().Endpath()
+		hv.BackgroundGradient.Draw(d.Doc, p, x, y, wd, ht)
+		head = node.InsertAfter(head, node.Tail(head), backgroundRule("q "+p.String()+" Q", "html background gradient"))
+	}
+	if hv.BackgroundImage != nil {
+		if tiles := hv.BackgroundImage.imageNodes(d, x, y, wd, ht); tiles != nil {
+			// the clipping path is active until the Q in the last rule
+			head = node.InsertAfter(head, node.Tail(head), backgroundRule("q "+clip().Clip().Endpath().String(), "html background image clip"))
+			head = node.InsertAfter(head, node.Tail(head), tiles)
+			head = node.InsertAfter(head, node.Tail(head), backgroundRule("Q", "html background image clip end"))
+		}
+	}
+	return head
+}
+
+// BackgroundVList returns a vertical list without height that draws the
+// background (color, gradient and image) of hv in a rectangle with the size
+// wd × ht. The rectangle starts at the top left corner of the list.
+func (d *Document) BackgroundVList(hv HTMLValues, wd, ht bag.ScaledPoint) *node.VList {
+	clip := func() *pdfdraw.Object {
+		return pdfdraw.New().Rect(0, -ht, wd, ht)
+	}
+	vl := node.NewVList()
+	vl.List = d.backgroundNodes(hv, clip, 0, 0, wd, ht)
+	vl.Attributes = node.H{"origin": "background"}
+	return vl
+}
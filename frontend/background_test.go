@@ -0,0 +1,74 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+)
+
+func TestParseBackgroundRepeat(t *testing.T) {
+	testdata := []struct {
+		input string
+		want  BackgroundRepeat
+	}{
+		{"repeat", BackgroundRepeatRepeat},
+		{"repeat-x", BackgroundRepeatRepeatX},
+		{"no-repeat  repeat", BackgroundRepeatRepeatY},
+		{"no-repeat", BackgroundRepeatNoRepeat},
+	}
+	for _, td := range testdata {
+		got, err := ParseBackgroundRepeat(td.input)
+		if err != nil {
+			t.Error(err)
+		}
+		if got != td.want {
+			t.Errorf("ParseBackgroundRepeat(%q) = %s, want %s", td.input, got, td.want)
+		}
+	}
+	if _, err := ParseBackgroundRepeat("space"); err == nil {
+		t.Error("ParseBackgroundRepeat(space) should fail")
+	}
+}
+
+func TestBackgroundImageSize(t *testing.T) {
+	iw, ih := bag.MustSp("20pt"), bag.MustSp("10pt")
+	wd, ht := bag.MustSp("100pt"), bag.MustSp("100pt")
+	testdata := []struct {
+		size   string
+		wd, ht string
+	}{
+		{"auto", "20pt", "10pt"},
+		{"cover", "200pt", "100pt"},
+		{"contain", "100pt", "50pt"},
+		{"50%", "50pt", "25pt"},
+		{"auto 40pt", "80pt", "40pt"},
+		{"10pt 50%", "10pt", "50pt"},
+	}
+	for _, td := range testdata {
+		bi := &BackgroundImage{}
+		if err := bi.SetSize(td.size); err != nil {
+			t.Fatal(err)
+		}
+		w, h := bi.imageSize(iw, ih, wd, ht)
+		if w != bag.MustSp(td.wd) || h != bag.MustSp(td.ht) {
+			t.Errorf("imageSize(%q) = %s × %s, want %s × %s", td.size, w, h, td.wd, td.ht)
+		}
+	}
+	bi := &BackgroundImage{}
+	if err := bi.SetSize("big"); err == nil {
+		t.Error("SetSize(big) should fail")
+	}
+}
+
+func TestBackgroundImagePosition(t *testing.T) {
+	bi := &BackgroundImage{}
+	if err := bi.SetPosition("right 10pt"); err != nil {
+		t.Fatal(err)
+	}
+	if bi.PositionX != 1 || bi.PositionY != 0 || bi.OffsetY != bag.MustSp("10pt") {
+		t.Errorf("unexpected position %v %v %s", bi.PositionX, bi.PositionY, bi.OffsetY)
+	}
+	if got, want := tileStart(bag.MustSp("25pt"), bag.MustSp("10pt")), bag.MustSp("-5pt"); got != want {
+		t.Errorf("tileStart() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,415 @@
+package frontend
+
+import (
+	"unicode/utf8"
+
+	"github.com/speedata/boxesandglue/backend/font"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/textlayout/harfbuzz"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// This file contains an implementation of the Unicode bidirectional algorithm
+// (UAX #9) without explicit embeddings, overrides, isolates and bracket pairs.
+// The paragraph is typeset in logical order, the embedding levels of the
+// glyphs and spaces are resolved before line breaking and each line gets
+// reordered afterwards.
+
+func bidiClass(r rune) bidi.Class {
+	p, _ := bidi.LookupRune(r)
+	return p.Class()
+}
+
+// hasRTL returns true if str contains right to left characters or arabic
+// numbers.
+func hasRTL(str string) bool {
+	for _, r := range str {
+		switch bidiClass(r) {
+		case bidi.R, bidi.AL, bidi.AN:
+			return true
+		}
+	}
+	return false
+}
+
+// shapeBidi shapes str with the font. Text that contains right to left
+// characters is split into runs of the same direction which get shaped
+// separately. Neutral characters belong to the run before them. The atoms are
+// returned in logical order.
+func shapeBidi(fnt *font.Font, str string, features []harfbuzz.Feature) []font.Atom {
+	if !hasRTL(str) {
+		return fnt.Shape(str, features)
+	}
+	runes := []rune(str)
+	var atoms []font.Atom
+	var dir harfbuzz.Direction
+	start := 0
+	for i, r := range runes {
+		var rdir harfbuzz.Direction
+		switch bidiClass(r) {
+		case bidi.R, bidi.AL:
+			rdir = harfbuzz.RightToLeft
+		case bidi.L, bidi.EN, bidi.AN:
+			rdir = harfbuzz.LeftToRight
+		default:
+			continue
+		}
+		if dir == 0 {
+			dir = rdir
+		} else if rdir != dir {
+			atoms = append(atoms, fnt.ShapeDirection(string(runes[start:i]), features, dir)...)
+			start = i
+			dir = rdir
+		}
+	}
+	return append(atoms, fnt.ShapeDirection(string(runes[start:]), features, dir)...)
+}
+
+// strongDirection returns bidi.L or bidi.R for the resolved type t. Numbers
+// count as right to left (rule N1).
+func strongDirection(t bidi.Class) bidi.Class {
+	if t == bidi.L {
+		return bidi.L
+	}
+	return bidi.R
+}
+
+// resolveLevels returns the embedding level for each character class in the
+// paragraph with the given paragraph embedding level (rules W1-W7, N1, N2, I1
+// and I2).
+func resolveLevels(classes []bidi.Class, paraLevel uint8) []uint8 {
+	l := len(classes)
+	types := make([]bidi.Class, l)
+	for i, c := range classes {
+		switch c {
+		case bidi.L, bidi.R, bidi.AL, bidi.EN, bidi.ES, bidi.ET, bidi.AN, bidi.CS, bidi.NSM, bidi.WS, bidi.ON:
+			types[i] = c
+		case bidi.B, bidi.S:
+			types[i] = bidi.WS
+		default:
+			// Explicit formatting characters are not supported.
+			types[i] = bidi.ON
+		}
+	}
+	sos := bidi.L
+	if paraLevel%2 == 1 {
+		sos = bidi.R
+	}
+	// W1: non spacing marks get the type of the previous character.
+	for i, t := range types {
+		if t == bidi.NSM {
+			if i == 0 {
+				types[i] = sos
+			} else {
+				types[i] = types[i-1]
+			}
+		}
+	}
+	// W2: european numbers after arabic letters become arabic numbers.
+	lastStrong := sos
+	for i, t := range types {
+		switch t {
+		case bidi.L, bidi.R, bidi.AL:
+			lastStrong = t
+		case bidi.EN:
+			if lastStrong == bidi.AL {
+				types[i] = bidi.AN
+			}
+		}
+	}
+	// W3: arabic letters are right to left.
+	for i, t := range types {
+		if t == bidi.AL {
+			types[i] = bidi.R
+		}
+	}
+	// W4: a single separator between two numbers of the same type.
+	for i := 1; i < l-1; i++ {
+		prev, next := types[i-1], types[i+1]
+		switch types[i] {
+		case bidi.ES:
+			if prev == bidi.EN && next == bidi.EN {
+				types[i] = bidi.EN
+			}
+		case bidi.CS:
+			if prev == next && (prev == bidi.EN || prev == bidi.AN) {
+				types[i] = prev
+			}
+		}
+	}
+	// W5: terminators adjacent to european numbers.
+	for i := 0; i < l; {
+		if types[i] != bidi.ET {
+			i++
+			continue
+		}
+		j := i
+		for j < l && types[j] == bidi.ET {
+			j++
+		}
+		if (i > 0 && types[i-1] == bidi.EN) || (j < l && types[j] == bidi.EN) {
+			for k := i; k < j; k++ {
+				types[k] = bidi.EN
+			}
+		}
+		i = j
+	}
+	// W6: remaining separators and terminators are neutral.
+	for i, t := range types {
+		switch t {
+		case bidi.ES, bidi.ET, bidi.CS:
+			types[i] = bidi.ON
+		}
+	}
+	// W7: european numbers after left to right text are left to right.
+	lastStrong = sos
+	for i, t := range types {
+		switch t {
+		case bidi.L, bidi.R:
+			lastStrong = t
+		case bidi.EN:
+			if lastStrong == bidi.L {
+				types[i] = bidi.L
+			}
+		}
+	}
+	// N1 and N2: neutrals take the direction of the surrounding text if both
+	// sides agree, the embedding direction otherwise.
+	for i := 0; i < l; {
+		if types[i] != bidi.WS && types[i] != bidi.ON {
+			i++
+			continue
+		}
+		j := i
+		for j < l && (types[j] == bidi.WS || types[j] == bidi.ON) {
+			j++
+		}
+		leading, trailing := sos, sos
+		if i > 0 {
+			leading = strongDirection(types[i-1])
+		}
+		if j < l {
+			trailing = strongDirection(types[j])
+		}
+		dir := sos
+		if leading == trailing {
+			dir = leading
+		}
+		for k := i; k < j; k++ {
+			types[k] = dir
+		}
+		i = j
+	}
+	// I1 and I2
+	levels := make([]uint8, l)
+	for i, t := range types {
+		lvl := paraLevel
+		if paraLevel%2 == 0 {
+			switch t {
+			case bidi.R:
+				lvl++
+			case bidi.AN, bidi.EN:
+				lvl += 2
+			}
+		} else if t != bidi.R {
+			lvl++
+		}
+		levels[i] = lvl
+	}
+	return levels
+}
+
+// bidiLevels resolves the embedding levels of the glyphs and spaces in the
+// horizontal list starting at hlist. The paragraph level is determined by
+// dir. The returned map is nil if the paragraph is left to right only.
+func bidiLevels(hlist node.Node, dir Direction) (map[node.Node]uint8, uint8) {
+	var nodes []node.Node
+	var classes []bidi.Class
+	hasRTLClass := false
+	for e := hlist; e != nil; e = e.Next() {
+		var cls bidi.Class
+		switch t := e.(type) {
+		case *node.Glyph:
+			// the second and following glyphs of a cluster have no
+			// components
+			cls = bidi.NSM
+			if t.Components != "" {
+				r, _ := utf8.DecodeRuneInString(t.Components)
+				cls = bidiClass(r)
+			}
+		case *node.Glue:
+			cls = bidi.WS
+		case *node.HList, *node.VList, *node.Image, *node.Rule:
+			cls = bidi.ON
+		default:
+			continue
+		}
+		switch cls {
+		case bidi.R, bidi.AL, bidi.AN:
+			hasRTLClass = true
+		}
+		nodes = append(nodes, e)
+		classes = append(classes, cls)
+	}
+	var paraLevel uint8
+	switch dir {
+	case DirectionRTL:
+		paraLevel = 1
+	case DirectionDefault:
+		// P2 and P3: the first strong character determines the direction.
+	classloop:
+		for _, cls := range classes {
+			switch cls {
+			case bidi.L:
+				break classloop
+			case bidi.R, bidi.AL:
+				paraLevel = 1
+				break classloop
+			}
+		}
+	}
+	if !hasRTLClass && paraLevel == 0 {
+		return nil, 0
+	}
+	levels := make(map[node.Node]uint8, len(nodes))
+	for i, lvl := range resolveLevels(classes, paraLevel) {
+		levels[nodes[i]] = lvl
+	}
+	return levels, paraLevel
+}
+
+// resolveAlignment returns the physical alignment for the logical alignments
+// start and end in a paragraph with the given paragraph level. Physical
+// alignments are returned unchanged.
+func resolveAlignment(a HorizontalAlignment, paraLevel uint8) HorizontalAlignment {
+	switch a {
+	case HAlignStart:
+		if paraLevel == 1 {
+			return HAlignRight
+		}
+		return HAlignLeft
+	case HAlignEnd:
+		if paraLevel == 1 {
+			return HAlignLeft
+		}
+		return HAlignRight
+	}
+	return a
+}
+
+// reorderLine reorders the items of the line according to rules L1 and L2.
+// Nodes without a level (kerns, start stop nodes, hyphens inserted by the line
+// breaking) get the level of the previous node, start nodes the level of the
+// next node. Glue and penalties at the beginning and the end of the line get
+// the paragraph level.
+func reorderLine(hl *node.HList, levels map[node.Node]uint8, paraLevel uint8) {
+	var nodes []node.Node
+	for e := hl.List; e != nil; e = e.Next() {
+		nodes = append(nodes, e)
+	}
+	l := len(nodes)
+	if l < 2 {
+		return
+	}
+	lv := make([]uint8, l)
+	known := make([]bool, l)
+	for i, n := range nodes {
+		lv[i], known[i] = levels[n]
+	}
+	// L1
+	for i := l - 1; i >= 0; i-- {
+		if _, ok := nodes[i].(*node.Glue); !ok {
+			if _, ok := nodes[i].(*node.Penalty); !ok {
+				break
+			}
+		}
+		lv[i], known[i] = paraLevel, true
+	}
+	for i := 0; i < l && !known[i]; i++ {
+		if _, ok := nodes[i].(*node.Glue); !ok {
+			break
+		}
+		lv[i], known[i] = paraLevel, true
+	}
+	prev := make([]uint8, l)
+	next := make([]uint8, l)
+	cur := paraLevel
+	for i := 0; i < l; i++ {
+		if known[i] {
+			cur = lv[i]
+		}
+		prev[i] = cur
+	}
+	cur = paraLevel
+	for i := l - 1; i >= 0; i-- {
+		if known[i] {
+			cur = lv[i]
+		}
+		next[i] = cur
+	}
+	var maxLevel uint8
+	minOddLevel := uint8(255)
+	for i, n := range nodes {
+		if !known[i] {
+			if ss, ok := n.(*node.StartStop); ok && ss.StartNode == nil {
+				lv[i] = next[i]
+			} else {
+				lv[i] = prev[i]
+			}
+		}
+		if lv[i] > maxLevel {
+			maxLevel = lv[i]
+		}
+		if lv[i]%2 == 1 && lv[i] < minOddLevel {
+			minOddLevel = lv[i]
+		}
+	}
+	if maxLevel == 0 {
+		return
+	}
+	// L2: reverse all sequences at this level or higher, from the highest
+	// level to the lowest odd level.
+	for lvl := maxLevel; lvl >= minOddLevel; lvl-- {
+		for i := 0; i < l; {
+			if lv[i] < lvl {
+				i++
+				continue
+			}
+			j := i
+			for j < l && lv[j] >= lvl {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				nodes[a], nodes[b] = nodes[b], nodes[a]
+				lv[a], lv[b] = lv[b], lv[a]
+			}
+			i = j
+		}
+	}
+	// Start and stop nodes must stay in order.
+	pos := make(map[node.Node]int, l)
+	for i, n := range nodes {
+		pos[n] = i
+	}
+	for i, n := range nodes {
+		if ss, ok := n.(*node.StartStop); ok && ss.StartNode != nil {
+			if j, ok := pos[ss.StartNode]; ok && j > i {
+				nodes[i], nodes[j] = nodes[j], nodes[i]
+				pos[nodes[i]], pos[nodes[j]] = i, j
+			}
+		}
+	}
+	for i, n := range nodes {
+		if i == 0 {
+			n.SetPrev(nil)
+		} else {
+			n.SetPrev(nodes[i-1])
+		}
+		if i == l-1 {
+			n.SetNext(nil)
+		} else {
+			n.SetNext(nodes[i+1])
+		}
+	}
+	hl.List = nodes[0]
+}
@@ -0,0 +1,114 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/node"
+	"golang.org/x/text/unicode/bidi"
+)
+
+func TestResolveLevels(t *testing.T) {
+	testdata := []struct {
+		classes   []bidi.Class
+		paraLevel uint8
+		levels    []uint8
+	}{
+		// car means CAR.
+		{[]bidi.Class{bidi.L, bidi.L, bidi.L, bidi.WS, bidi.L, bidi.WS, bidi.R, bidi.R, bidi.ON}, 0, []uint8{0, 0, 0, 0, 0, 0, 1, 1, 0}},
+		// CAR MEANS car.
+		{[]bidi.Class{bidi.R, bidi.R, bidi.WS, bidi.R, bidi.WS, bidi.L, bidi.L, bidi.ON}, 1, []uint8{1, 1, 1, 1, 1, 2, 2, 1}},
+		// arabic letter followed by european digits
+		{[]bidi.Class{bidi.AL, bidi.WS, bidi.EN, bidi.EN}, 1, []uint8{1, 1, 2, 2}},
+		// european number with separator and terminator
+		{[]bidi.Class{bidi.R, bidi.WS, bidi.EN, bidi.CS, bidi.EN, bidi.ET}, 0, []uint8{1, 1, 2, 2, 2, 2}},
+		// non spacing mark
+		{[]bidi.Class{bidi.L, bidi.R, bidi.NSM}, 0, []uint8{0, 1, 1}},
+	}
+	for _, tc := range testdata {
+		if got, want := fmt.Sprint(resolveLevels(tc.classes, tc.paraLevel)), fmt.Sprint(tc.levels); got != want {
+			t.Errorf("resolveLevels(%v) = %s, want %s", tc.classes, got, want)
+		}
+	}
+}
+
+func mkBidiLine(str string) *node.HList {
+	var head, cur node.Node
+	for _, r := range str {
+		var n node.Node
+		if r == ' ' {
+			n = node.NewGlue()
+		} else {
+			g := node.NewGlyph()
+			g.Components = string(r)
+			n = g
+		}
+		head = node.InsertAfter(head, cur, n)
+		cur = n
+	}
+	return node.Hpack(head)
+}
+
+func lineString(hl *node.HList) string {
+	var sb strings.Builder
+	for e := hl.List; e != nil; e = e.Next() {
+		switch t := e.(type) {
+		case *node.Glyph:
+			sb.WriteString(t.Components)
+		case *node.Glue:
+			sb.WriteString(" ")
+		}
+	}
+	return sb.String()
+}
+
+func TestReorderLine(t *testing.T) {
+	testdata := []struct {
+		text   string
+		dir    Direction
+		visual string
+	}{
+		{"abc", DirectionDefault, "abc"},
+		{"ab אבג de", DirectionDefault, "ab גבא de"},
+		{"אבג de", DirectionDefault, "de גבא"},
+		{"ab אבג", DirectionRTL, "גבא ab"},
+		{"אב 12", DirectionDefault, "12 בא"},
+	}
+	for _, tc := range testdata {
+		hl := mkBidiLine(tc.text)
+		levels, paraLevel := bidiLevels(hl.List, tc.dir)
+		if levels != nil {
+			reorderLine(hl, levels, paraLevel)
+		}
+		if got := lineString(hl); got != tc.visual {
+			t.Errorf("reorderLine(%q) = %q, want %q", tc.text, got, tc.visual)
+		}
+	}
+}
+
+func TestResolveAlignment(t *testing.T) {
+	testdata := []struct {
+		text  string
+		dir   Direction
+		align HorizontalAlignment
+		want  HorizontalAlignment
+	}{
+		{"abc", DirectionDefault, HAlignStart, HAlignLeft},
+		{"abc", DirectionDefault, HAlignEnd, HAlignRight},
+		{"abc", DirectionRTL, HAlignStart, HAlignRight},
+		{"abc", DirectionRTL, HAlignEnd, HAlignLeft},
+		{"abc", DirectionRTL, HAlignRight, HAlignRight},
+		{"abc", DirectionRTL, HAlignLeft, HAlignLeft},
+		{"אבג de", DirectionDefault, HAlignStart, HAlignRight},
+		{"אבג de", DirectionDefault, HAlignRight, HAlignRight},
+		{"אבג de", DirectionLTR, HAlignStart, HAlignLeft},
+	}
+	for _, tc := range testdata {
+		hl := mkBidiLine(tc.text)
+		_, paraLevel := bidiLevels(hl.List, tc.dir)
+		if got := resolveAlignment(tc.align, paraLevel); got != tc.want {
+			t.Errorf("resolveAlignment(%d) for %q = %d, want %d", tc.align, tc.text, got, tc.want)
+		}
+	}
+}
@@ -3,10 +3,11 @@ package frontend
 import (
 	"fmt"
 	"math"
-	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
+	"github.com/speedata/boxesandglue/backend/bag"
 	"github.com/speedata/boxesandglue/backend/color"
 )
 
@@ -15,13 +16,36 @@ func (d *Document) DefineColor(name string, col *color.Color) {
 	d.usedcolors[name] = col
 }
 
-var rgbmatcher = regexp.MustCompile(`rgba?\(\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)\s*,?\s*([0-9.-]*)?\s*\)`)
-
-// GetColor returns a color. The string can be a predefined color name or an
-// HTML / CSS color definition such as #FAF or rgb(0.5.,0.5,0.5).
+// GetColor returns a color. The string can be a predefined color name or a
+// CSS color definition (see ParseColor). The CSS keywords currentColor,
+// inherit, initial and unset return nil, so the caller keeps the inherited or
+// the default color. If the color cannot be parsed, an error is logged and nil
+// is returned.
 func (d *Document) GetColor(s string) *color.Color {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "currentcolor", "inherit", "initial", "unset":
+		return nil
+	}
+	col, err := d.ParseColor(s)
+	if err != nil {
+		bag.Logger.Error(err.Error())
+		return nil
+	}
+	return col
+}
+
+// ParseColor returns the color defined by s. The string can be a color name
+// (previously defined with DefineColor or a predefined CSS color), a hex
+// color (#rgb, #rgba, #rrggbb, #rrggbbaa) or one of the functions rgb(),
+// rgba(), hsl(), hsla(), hwb(), cmyk(), device-cmyk(), gray() and spot().
+// Function arguments can be separated by commas or spaces, the alpha value
+// can follow a slash. spot(name, color) defines a spot color with the
+// alternative (CMYK) color, the spot color is available by its name
+// afterwards.
+func (d *Document) ParseColor(s string) (*color.Color, error) {
+	s = strings.TrimSpace(s)
 	if col, ok := d.usedcolors[s]; ok {
-		return col
+		return col, nil
 	}
 	if col, ok := csscolors[s]; ok {
 		if col.Space == color.ColorSpotcolor {
@@ -29,52 +53,330 @@ func (d *Document) GetColor(s string) *color.Color {
 			col.SpotcolorID = len(d.usedSpotcolors)
 		}
 		d.usedcolors[s] = col
-		return col
+		return col, nil
+	}
+	if s == "transparent" {
+		return &color.Color{Space: color.ColorNone}, nil
 	}
-	var r, g, b int
-	var alpha float64
-	var err error
-	col := &color.Color{}
 	if strings.HasPrefix(s, "#") {
-		col.Space = color.ColorRGB
-		switch len(s) {
-		case 7:
-			fmt.Sscanf(s, "#%2x%2x%2x", &r, &g, &b)
-		case 4:
-			fmt.Sscanf(s, "#%1x%1x%1x", &r, &g, &b)
-		default:
-			// FIXME: proper error handling
-			// logger.Error("GetColor HTML not implemented", "size", len(s))
+		return parseHexColor(s)
+	}
+	open := strings.IndexByte(s, '(')
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("unknown color %q", s)
+	}
+	fn := strings.ToLower(strings.TrimSpace(s[:open]))
+	args := s[open+1 : len(s)-1]
+	if fn == "spot" {
+		return d.parseSpotColor(s, args)
+	}
+	values, alpha, err := splitColorArguments(args)
+	if err != nil {
+		return nil, fmt.Errorf("color %q: %w", s, err)
+	}
+	var col *color.Color
+	switch fn {
+	case "rgb", "rgba":
+		col, err = rgbColor(values)
+	case "hsl", "hsla":
+		col, err = hslColor(values)
+	case "hwb":
+		col, err = hwbColor(values)
+	case "cmyk", "device-cmyk":
+		col, err = cmykColor(values)
+	case "gray":
+		col, err = grayColor(values)
+	default:
+		return nil, fmt.Errorf("unknown color function %q", fn)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("color %q: %w", s, err)
+	}
+	if alpha != "" {
+		if col.A, err = parseColorValue(alpha, 1); err != nil {
+			return nil, fmt.Errorf("color %q: %w", s, err)
+		}
+		col.HasAlpha = true
+	}
+	return col, nil
+}
+
+func parseHexColor(s string) (*color.Color, error) {
+	digits := s[1:]
+	var size int
+	switch len(digits) {
+	case 3, 4:
+		size = 1
+	case 6, 8:
+		size = 2
+	default:
+		return nil, fmt.Errorf("invalid hex color %q", s)
+	}
+	var v [4]float64
+	for i := 0; i < len(digits)/size; i++ {
+		part := digits[i*size : (i+1)*size]
+		n, err := strconv.ParseUint(part, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex color %q", s)
+		}
+		if size == 1 {
+			n *= 17
+		}
+		v[i] = roundColor(float64(n) / 255)
+	}
+	col := &color.Color{Space: color.ColorRGB, R: v[0], G: v[1], B: v[2]}
+	if len(digits) == 4 || len(digits) == 8 {
+		col.A = v[3]
+		col.HasAlpha = true
+	}
+	return col, nil
+}
+
+// parseSpotColor interprets the arguments of spot(name, color), where color
+// is the alternative color for devices without the colorant.
+func (d *Document) parseSpotColor(s, args string) (*color.Color, error) {
+	name, alternative, found := strings.Cut(args, ",")
+	if !found {
+		return nil, fmt.Errorf("color %q: spot() needs a name and an alternative color", s)
+	}
+	name = strings.Trim(strings.TrimSpace(name), `"'`)
+	if name == "" {
+		return nil, fmt.Errorf("color %q: empty spot color name", s)
+	}
+	if col, ok := d.usedcolors[name]; ok && col.Space == color.ColorSpotcolor {
+		return col, nil
+	}
+	alt, err := d.ParseColor(alternative)
+	if err != nil {
+		return nil, fmt.Errorf("color %q: %w", s, err)
+	}
+	col := &color.Color{Space: color.ColorSpotcolor, Basecolor: name, A: 1}
+	switch alt.Space {
+	case color.ColorCMYK, color.ColorSpotcolor:
+		col.C, col.M, col.Y, col.K = alt.C, alt.M, alt.Y, alt.K
+	case color.ColorRGB:
+		col.C, col.M, col.Y, col.K = rgbToCMYK(alt.R, alt.G, alt.B)
+	case color.ColorGray:
+		col.K = roundColor(1 - alt.G)
+	default:
+		return nil, fmt.Errorf("color %q: invalid alternative color", s)
+	}
+	d.usedSpotcolors[col] = true
+	col.SpotcolorID = len(d.usedSpotcolors)
+	d.usedcolors[name] = col
+	return col, nil
+}
+
+// splitColorArguments splits the arguments of a color function separated by
+// commas or white space. The alpha value is the value after a slash.
+func splitColorArguments(args string) ([]string, string, error) {
+	var alpha string
+	if before, after, found := strings.Cut(args, "/"); found {
+		args = before
+		alpha = strings.TrimSpace(after)
+		if alpha == "" || strings.ContainsAny(alpha, ", \t") {
+			return nil, "", fmt.Errorf("invalid alpha value %q", after)
+		}
+	}
+	values := strings.FieldsFunc(args, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	return values, alpha, nil
+}
+
+// parseColorValue interprets a number or a percentage. Percentages are
+// relative to max.
+func parseColorValue(v string, max float64) (float64, error) {
+	var f float64
+	var err error
+	if strings.HasSuffix(v, "%") {
+		f, err = strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+		f = f * max / 100
+	} else {
+		f, err = strconv.ParseFloat(v, 64)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid color value %q", v)
+	}
+	return math.Max(0, math.Min(max, f)), nil
+}
+
+// parseHue returns the hue in degrees (0 to 360). Angles can have the units
+// deg, rad, grad and turn.
+func parseHue(v string) (float64, error) {
+	factor := 1.0
+	for _, unit := range []struct {
+		suffix string
+		factor float64
+	}{{"deg", 1}, {"grad", 0.9}, {"rad", 180 / math.Pi}, {"turn", 360}} {
+		if strings.HasSuffix(v, unit.suffix) {
+			v = strings.TrimSuffix(v, unit.suffix)
+			factor = unit.factor
+			break
+		}
+	}
+	h, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hue %q", v)
+	}
+	h = math.Mod(h*factor, 360)
+	if h < 0 {
+		h += 360
+	}
+	return h, nil
+}
+
+// colorValues parses the first n values (with the given maximum for
+// percentages) and returns the remaining values.
+func colorValues(values []string, n int, max float64) ([]float64, []string, error) {
+	if len(values) < n {
+		return nil, nil, fmt.Errorf("%d values expected, got %d", n, len(values))
+	}
+	ret := make([]float64, n)
+	for i := 0; i < n; i++ {
+		f, err := parseColorValue(values[i], max)
+		if err != nil {
+			return nil, nil, err
+		}
+		ret[i] = f
+	}
+	return ret, values[n:], nil
+}
+
+// setAlpha sets the alpha value of the legacy comma syntax (rgba(r, g, b, a)).
+// An empty rest means no alpha value.
+func setAlpha(col *color.Color, rest []string) error {
+	switch len(rest) {
+	case 0:
+		return nil
+	case 1:
+		var err error
+		col.A, err = parseColorValue(rest[0], 1)
+		col.HasAlpha = true
+		return err
+	default:
+		return fmt.Errorf("too many values")
+	}
+}
+
+func rgbColor(values []string) (*color.Color, error) {
+	v, rest, err := colorValues(values, 3, 255)
+	if err != nil {
+		return nil, err
+	}
+	col := &color.Color{Space: color.ColorRGB, R: roundColor(v[0] / 255), G: roundColor(v[1] / 255), B: roundColor(v[2] / 255)}
+	return col, setAlpha(col, rest)
+}
+
+func hslColor(values []string) (*color.Color, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("3 values expected, got 0")
+	}
+	h, err := parseHue(values[0])
+	if err != nil {
+		return nil, err
+	}
+	v, rest, err := colorValues(values[1:], 2, 1)
+	if err != nil {
+		return nil, err
+	}
+	r, g, b := hslToRGB(h, v[0], v[1])
+	col := &color.Color{Space: color.ColorRGB, R: roundColor(r), G: roundColor(g), B: roundColor(b)}
+	return col, setAlpha(col, rest)
+}
+
+func hwbColor(values []string) (*color.Color, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("3 values expected, got 0")
+	}
+	h, err := parseHue(values[0])
+	if err != nil {
+		return nil, err
+	}
+	v, rest, err := colorValues(values[1:], 2, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("too many values")
+	}
+	white, black := v[0], v[1]
+	col := &color.Color{Space: color.ColorRGB}
+	if white+black >= 1 {
+		gray := roundColor(white / (white + black))
+		col.R, col.G, col.B = gray, gray, gray
+		return col, nil
+	}
+	r, g, b := hslToRGB(h, 1, 0.5)
+	mix := func(c float64) float64 {
+		return roundColor(c*(1-white-black) + white)
+	}
+	col.R, col.G, col.B = mix(r), mix(g), mix(b)
+	return col, nil
+}
+
+func cmykColor(values []string) (*color.Color, error) {
+	v, rest, err := colorValues(values, 4, 1)
+	if err != nil {
+		return nil, err
+	}
+	col := &color.Color{Space: color.ColorCMYK, C: v[0], M: v[1], Y: v[2], K: v[3]}
+	return col, setAlpha(col, rest)
+}
+
+func grayColor(values []string) (*color.Color, error) {
+	v, rest, err := colorValues(values, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	col := &color.Color{Space: color.ColorGray, R: v[0], G: v[0], B: v[0]}
+	return col, setAlpha(col, rest)
+}
+
+// hslToRGB converts hue (in degrees), saturation and lightness (0 to 1) to
+// RGB values.
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1))
+	}
+	return f(0), f(8), f(4)
+}
+
+// rgbToCMYK is a naive conversion from RGB to CMYK without color management.
+func rgbToCMYK(r, g, b float64) (float64, float64, float64, float64) {
+	k := 1 - math.Max(r, math.Max(g, b))
+	if k == 1 {
+		return 0, 0, 0, 1
+	}
+	return roundColor((1 - r - k) / (1 - k)), roundColor((1 - g - k) / (1 - k)), roundColor((1 - b - k) / (1 - k)), roundColor(k)
+}
+
+func roundColor(v float64) float64 {
+	return math.Round(100.0*v) / 100.0
+}
+
+// shadeColor returns a lighter (f > 0) or a darker (f < 0) variant of the
+// color. f is between -1 (black) and 1 (white). Spot colors are returned
+// unchanged.
+func shadeColor(col color.Color, f float64) color.Color {
+	mix := func(v, target float64) float64 {
+		return math.Round(100.0*(v+(target-v)*math.Abs(f))) / 100.0
+	}
+	switch col.Space {
+	case color.ColorRGB, color.ColorGray:
+		target := 0.0
+		if f > 0 {
+			target = 1
 		}
-		col.R = math.Round(100.0*float64(r)/float64(255)) / 100.0
-		col.G = math.Round(100.0*float64(g)/float64(255)) / 100.0
-		col.B = math.Round(100.0*float64(b)/float64(255)) / 100.0
-		return col
-	} else if strings.HasPrefix(s, "rgb") {
-		col.Space = color.ColorRGB
-		colorvalues := rgbmatcher.FindAllStringSubmatch(s, -1)
-
-		if len(colorvalues) == 1 {
-			if len(colorvalues[0]) > 3 {
-				// TODO: percentage
-				if r, err = strconv.Atoi(colorvalues[0][1]); err != nil {
-					return nil
-				}
-				if g, err = strconv.Atoi(colorvalues[0][2]); err != nil {
-					return nil
-				}
-				if b, err = strconv.Atoi(colorvalues[0][3]); err != nil {
-					return nil
-				}
-				col.R = math.Round(100.0*float64(r)/float64(255)) / 100.0
-				col.G = math.Round(100.0*float64(g)/float64(255)) / 100.0
-				col.B = math.Round(100.0*float64(b)/float64(255)) / 100.0
-				if alpha, err = strconv.ParseFloat(colorvalues[0][4], 64); err == nil {
-					col.A = alpha
-				}
-				return col
-			}
+		col.R, col.G, col.B = mix(col.R, target), mix(col.G, target), mix(col.B, target)
+	case color.ColorCMYK:
+		if f > 0 {
+			col.C, col.M, col.Y, col.K = mix(col.C, 0), mix(col.M, 0), mix(col.Y, 0), mix(col.K, 0)
+		} else {
+			col.K = mix(col.K, 1)
 		}
 	}
-	return nil
+	return col
 }
@@ -38,8 +38,8 @@ func TestParseColors(t *testing.T) {
 		colorvalue string
 		expected   string
 	}{
-		{"rgba(0,255,0)", "rgba(0,255,0,0)"},
-		{"rgb(0,255,0)", "rgba(0,255,0,0)"},
+		{"rgba(0,255,0)", "rgba(0,255,0,1)"},
+		{"rgb(0,255,0)", "rgba(0,255,0,1)"},
 		{"rgb(0,255,0,1.0)", "rgba(0,255,0,1)"},
 		{"rgb(0,255,0,1)", "rgba(0,255,0,1)"},
 	}
@@ -50,3 +50,116 @@ func TestParseColors(t *testing.T) {
 		}
 	}
 }
+
+func TestShadeColor(t *testing.T) {
+	testdata := []struct {
+		col  color.Color
+		f    float64
+		want string
+	}{
+		{color.Color{Space: color.ColorRGB, R: 1, G: 0.5, B: 0}, -0.5, "0.5 0.25 0 rg"},
+		{color.Color{Space: color.ColorRGB, R: 1, G: 0.5, B: 0}, 0.5, "1 0.75 0.5 rg"},
+		{color.Color{Space: color.ColorCMYK, C: 1, K: 0.5}, -0.5, "1 0 0 0.75 k"},
+		{color.Color{Space: color.ColorCMYK, C: 1, K: 0.5}, 0.5, "0.5 0 0 0.25 k"},
+	}
+	for _, tc := range testdata {
+		col := shadeColor(tc.col, tc.f)
+		if got := col.PDFStringNonStroking(); got != tc.want {
+			t.Errorf("shadeColor(%v, %v) = %s, want %s", tc.col, tc.f, got, tc.want)
+		}
+	}
+}
+
+func TestParseColorSyntax(t *testing.T) {
+	f := initDocument()
+	testdata := []struct {
+		colorvalue string
+		pdf        string
+		alpha      float64
+	}{
+		{"#f00", "1 0 0 rg", 0},
+		{"#f008", "1 0 0 rg", 0.53},
+		{"#00ff0080", "0 1 0 rg", 0.5},
+		{"rgb(100% 50% 0%)", "1 0.5 0 rg", 0},
+		{"rgb(255 0 0 / 50%)", "1 0 0 rg", 0.5},
+		{"rgba(255, 0, 0, 0.25)", "1 0 0 rg", 0.25},
+		{"hsl(120 100% 50%)", "0 1 0 rg", 0},
+		{"hsla(0.5turn, 100%, 50%, 0.5)", "0 1 1 rg", 0.5},
+		{"hwb(0 0% 0%)", "1 0 0 rg", 0},
+		{"hwb(0 50% 50%)", "0.5 0.5 0.5 rg", 0},
+		{"cmyk(0 20% 100% 0.3)", "0 0.2 1 0.3 k", 0},
+		{"device-cmyk(1, 0, 0, 0)", "1 0 0 0 k", 0},
+		{"gray(25%)", "0.25 g", 0},
+	}
+	for _, tc := range testdata {
+		col, err := f.ParseColor(tc.colorvalue)
+		if err != nil {
+			t.Errorf("ParseColor(%q) error: %s", tc.colorvalue, err)
+			continue
+		}
+		if got := col.PDFStringNonStroking(); got != tc.pdf {
+			t.Errorf("ParseColor(%q) = %s, want %s", tc.colorvalue, got, tc.pdf)
+		}
+		if col.A != tc.alpha {
+			t.Errorf("ParseColor(%q).A = %v, want %v", tc.colorvalue, col.A, tc.alpha)
+		}
+	}
+
+	// alpha 0 is fully transparent, colors without alpha are opaque
+	for _, tc := range []struct {
+		colorvalue string
+		opacity    float64
+	}{
+		{"rgba(0, 0, 0, 0)", 0},
+		{"rgb(0 0 0 / 0%)", 0},
+		{"#00000000", 0},
+		{"#0000", 0},
+		{"#000000", 1},
+		{"rgb(0, 0, 0)", 1},
+		{"cmyk(0 0 0 1 / 0)", 0},
+	} {
+		col, err := f.ParseColor(tc.colorvalue)
+		if err != nil {
+			t.Errorf("ParseColor(%q) error: %s", tc.colorvalue, err)
+			continue
+		}
+		if got := col.Opacity(); got != tc.opacity {
+			t.Errorf("ParseColor(%q).Opacity() = %v, want %v", tc.colorvalue, got, tc.opacity)
+		}
+	}
+
+	for _, str := range []string{"#12345", "rgb(1,2)", "hsl(foo 10% 10%)", "cmyk(1 2 3)", "nocolor", "rgb(1 2 3 / )"} {
+		if _, err := f.ParseColor(str); err == nil {
+			t.Errorf("ParseColor(%q) expected an error", str)
+		}
+	}
+}
+
+func TestGetColorKeywords(t *testing.T) {
+	f := initDocument()
+	for _, str := range []string{"currentColor", "currentcolor", "inherit", "initial", "unset"} {
+		if col := f.GetColor(str); col != nil {
+			t.Errorf("GetColor(%q) = %v, want nil", str, col)
+		}
+	}
+	if col := f.GetColor("transparent"); col == nil || col.Space != color.ColorNone {
+		t.Errorf("GetColor(%q) = %v, want a color in the color space none", "transparent", col)
+	}
+}
+
+func TestSpotColor(t *testing.T) {
+	f := initDocument()
+	col, err := f.ParseColor(`spot("My Orange", cmyk(0 0.5 1 0))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if col.Space != color.ColorSpotcolor || col.Basecolor != "My Orange" || col.M != 0.5 || col.Y != 1 {
+		t.Errorf("unexpected spot color %#v", col)
+	}
+	if got := f.GetColor("My Orange"); got != col {
+		t.Errorf("GetColor(%q) does not return the spot color", "My Orange")
+	}
+	if got, want := col.PDFStringNonStroking(), "/CS1 cs 1 scn "; got != want {
+		t.Errorf("PDFStringNonStroking() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package frontend
+
+import (
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/color"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+// ColumnFill determines how the material is distributed into the columns.
+type ColumnFill int
+
+const (
+	// ColumnFillBalance makes the columns of the last area (usually on the last
+	// page) equally high.
+	ColumnFillBalance ColumnFill = iota
+	// ColumnFillAuto fills the columns sequentially.
+	ColumnFillAuto
+)
+
+// Columns describes a multi-column layout.
+type Columns struct {
+	// Count is the maximum number of columns. If 0, the number of columns is
+	// determined by Width.
+	Count int
+	// Width is the ideal column width. If 0, the number of columns is Count.
+	Width bag.ScaledPoint
+	// Gap is the distance between two columns.
+	Gap bag.ScaledPoint
+	// RuleWidth is the width of the rule between two columns.
+	RuleWidth bag.ScaledPoint
+	// RuleStyle is the style of the rule between two columns.
+	RuleStyle BorderStyle
+	// RuleColor is the color of the rule between two columns. Defaults to
+	// black.
+	RuleColor *color.Color
+	// Fill sets the column balancing.
+	Fill ColumnFill
+	// PagebreakSettings is used for breaking the material into the columns.
+	PagebreakSettings *node.PagebreakSettings
+}
+
+// ColumnWidth returns the number of columns and the width of each column if
+// the columns are placed in an area with the given width.
+func (cols *Columns) ColumnWidth(width bag.ScaledPoint) (int, bag.ScaledPoint) {
+	n := cols.Count
+	if cols.Width > 0 {
+		fit := int((width + cols.Gap) / (cols.Width + cols.Gap))
+		if n == 0 || fit < n {
+			n = fit
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n, (width - bag.ScaledPoint(n-1)*cols.Gap) / bag.ScaledPoint(n)
+}
+
+// BuildColumns takes the material from vl and puts as much as possible into
+// columns of the given height. The columns are placed side by side in an area
+// with the given width. The material in vl should be formatted to the column
+// width (see ColumnWidth). The first return value is the column area and the
+// second one is the material that does not fit or nil if everything fits. A
+// height of 0 does not limit the height of the columns.
+func (fe *Document) BuildColumns(vl *node.VList, cols *Columns, width, height bag.ScaledPoint) (*node.VList, *node.VList) {
+	n, colWidth := cols.ColumnWidth(width)
+	parts, rest := node.ColumnBreak(vl, n, height, cols.Fill == ColumnFillBalance, cols.PagebreakSettings)
+	areaHeight := height
+	if rest == nil {
+		areaHeight = 0
+		for _, part := range parts {
+			areaHeight = bag.Max(areaHeight, part.Height+part.Depth)
+		}
+	}
+	ruleColor := cols.RuleColor
+	if ruleColor == nil {
+		ruleColor = fe.GetColor("black")
+	}
+
+	var head node.Node
+	for i, part := range parts {
+		if i > 0 {
+			if cols.RuleWidth > 0 && cols.RuleStyle != BorderStyleNone {
+				r := node.NewRule()
+				r.Hide = true
+				r.Pre = borderRect(cols.RuleStyle, *ruleColor, (cols.Gap-cols.RuleWidth)/2, 0, cols.RuleWidth, areaHeight, false, true)
+				r.Attributes = node.H{"origin": "column rule"}
+				head = node.InsertAfter(head, node.Tail(head), r)
+			}
+			g := node.NewGlue()
+			g.Width = cols.Gap
+			g.Attributes = node.H{"origin": "column gap"}
+			head = node.InsertAfter(head, node.Tail(head), g)
+		}
+		// all columns start at the top of the area
+		part.Width = colWidth
+		part.Height = areaHeight
+		part.Depth = 0
+		head = node.InsertAfter(head, node.Tail(head), part)
+	}
+	if head == nil {
+		return node.NewVList(), rest
+	}
+	hl := node.Hpack(head)
+	hl.Width = width
+	hl.Attributes = node.H{"origin": "columns"}
+	area := node.Vpack(hl)
+	area.Attributes = node.H{"origin": "columns"}
+	return area, rest
+}
@@ -0,0 +1,32 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+)
+
+func TestColumnWidth(t *testing.T) {
+	testCases := []struct {
+		count    int
+		width    string
+		gap      string
+		area     string
+		n        int
+		colWidth string
+	}{
+		{3, "0pt", "10pt", "320pt", 3, "100pt"},
+		{0, "100pt", "10pt", "320pt", 3, "100pt"},
+		{0, "90pt", "10pt", "320pt", 3, "100pt"},
+		{2, "90pt", "10pt", "320pt", 2, "155pt"},
+		{4, "150pt", "10pt", "320pt", 2, "155pt"},
+		{0, "400pt", "10pt", "320pt", 1, "320pt"},
+	}
+	for _, tc := range testCases {
+		cols := &Columns{Count: tc.count, Width: bag.MustSp(tc.width), Gap: bag.MustSp(tc.gap)}
+		n, wd := cols.ColumnWidth(bag.MustSp(tc.area))
+		if n != tc.n || wd != bag.MustSp(tc.colWidth) {
+			t.Errorf("ColumnWidth(%s) with count %d, width %s = %d, %s, want %d, %s", tc.area, tc.count, tc.width, n, wd, tc.n, tc.colWidth)
+		}
+	}
+}
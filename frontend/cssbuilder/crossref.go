@@ -0,0 +1,157 @@
+package cssbuilder
+
+import (
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/document"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+// maxLayoutPasses is the maximum number of layout passes of OutputPages.
+const maxLayoutPasses = 5
+
+// collectDestinations records the page number of the named destinations in
+// the node list.
+func collectDestinations(n node.Node, page int, destinations map[string]int) {
+	for e := n; e != nil; e = e.Next() {
+		switch t := e.(type) {
+		case *node.HList:
+			collectDestinations(t.List, page, destinations)
+		case *node.VList:
+			collectDestinations(t.List, page, destinations)
+		case *node.StartStop:
+			if name, ok := t.Value.(string); ok && t.Action == node.ActionDest {
+				destinations[name] = page
+			}
+		}
+	}
+}
+
+// DestinationPage returns the page number of the element with the id (the
+// named destination) after the layout. The page number is known as soon as
+// the page is finished with NewPage or BeforeShipout.
+func (cb *CSSBuilder) DestinationPage(id string) (int, bool) {
+	page, ok := cb.destinations[id]
+	return page, ok
+}
+
+// layoutState contains everything the page builder changes during a layout
+// pass, so the pass can be undone.
+type layoutState struct {
+	pages                 int
+	currentPage           *document.Page
+	currentPageObjects    int
+	currentPageDimensions PageDimensions
+	pendingPages          int
+	destinations          map[string]int
+	namedStrings          map[string]string
+	footnotes             []*node.Insert
+	footnoteCount         int
+	outlines              int
+	outlineStack          []outlineEntry
+	outlineChildren       []int
+	outlineDest           int
+	tocCount              int
+}
+
+func (cb *CSSBuilder) saveLayoutState() *layoutState {
+	doc := cb.frontend.Doc
+	ls := &layoutState{
+		pages:                 len(doc.Pages),
+		currentPage:           doc.CurrentPage,
+		currentPageDimensions: cb.currentPageDimensions,
+		pendingPages:          len(cb.pendingPages),
+		destinations:          make(map[string]int, len(cb.destinations)),
+		namedStrings:          make(map[string]string, len(cb.namedStrings)),
+		footnotes:             append([]*node.Insert{}, cb.footnotes...),
+		footnoteCount:         cb.footnoteCount,
+		outlines:              len(doc.Outlines),
+		outlineStack:          append([]outlineEntry{}, cb.outlineStack...),
+		outlineDest:           cb.outlineDest,
+		tocCount:              cb.tocCount,
+	}
+	if doc.CurrentPage != nil {
+		ls.currentPageObjects = len(doc.CurrentPage.Objects)
+	}
+	for k, v := range cb.namedStrings {
+		ls.namedStrings[k] = v
+	}
+	for k, v := range cb.destinations {
+		ls.destinations[k] = v
+	}
+	for _, entry := range cb.outlineStack {
+		ls.outlineChildren = append(ls.outlineChildren, len(entry.outline.Children))
+	}
+	return ls
+}
+
+// restoreLayoutState removes the pages and the outlines created after the
+// state was saved. The pages must not be shipped out.
+func (cb *CSSBuilder) restoreLayoutState(ls *layoutState) {
+	doc := cb.frontend.Doc
+	doc.Pages = doc.Pages[:ls.pages]
+	doc.CurrentPage = ls.currentPage
+	if doc.CurrentPage != nil {
+		doc.CurrentPage.Objects = doc.CurrentPage.Objects[:ls.currentPageObjects]
+	}
+	cb.currentPageDimensions = ls.currentPageDimensions
+	cb.pendingPages = cb.pendingPages[:ls.pendingPages]
+	cb.namedStrings = ls.namedStrings
+	cb.footnotes = ls.footnotes
+	cb.footnoteCount = ls.footnoteCount
+	doc.Outlines = doc.Outlines[:ls.outlines]
+	cb.outlineStack = ls.outlineStack
+	for i, entry := range cb.outlineStack {
+		entry.outline.Children = entry.outline.Children[:ls.outlineChildren[i]]
+	}
+	cb.outlineDest = ls.outlineDest
+	cb.tocCount = ls.tocCount
+}
+
+// referencesStable returns true if the page numbers used for
+// target-counter(..., page) are the page numbers of the layout.
+func (cb *CSSBuilder) referencesStable() bool {
+	for _, id := range cb.css.PageReferences() {
+		used, ok := cb.css.TargetPages[id]
+		page, found := cb.destinations[id]
+		if ok != found || used != page {
+			return false
+		}
+	}
+	return true
+}
+
+// OutputPages outputs the HTML text like OutputPage. If the text refers to
+// the page numbers of elements with target-counter(attr(href), page), the
+// layout is repeated with the page numbers of the previous pass until the
+// page numbers don't change any more. The pages are held back until
+// BeforeShipout is called.
+func (cb *CSSBuilder) OutputPages(html string) error {
+	cb.holdPages = true
+	ls := cb.saveLayoutState()
+	for pass := 1; ; pass++ {
+		// the page numbers of the previous pass are used for the references,
+		// the destinations are collected again
+		cb.css.TargetPages = cb.destinations
+		cb.destinations = make(map[string]int, len(ls.destinations))
+		for k, v := range ls.destinations {
+			cb.destinations[k] = v
+		}
+		if err := cb.OutputPage(html); err != nil {
+			return err
+		}
+		// the current page is not finished yet
+		if cur := cb.frontend.Doc.CurrentPage; cur != nil {
+			for _, obj := range cur.Objects {
+				collectDestinations(obj.Vlist, len(cb.frontend.Doc.Pages), cb.destinations)
+			}
+		}
+		if cb.referencesStable() {
+			return nil
+		}
+		if pass == maxLayoutPasses {
+			bag.Logger.Warn("page references are not stable", "passes", maxLayoutPasses)
+			return nil
+		}
+		cb.restoreLayoutState(ls)
+	}
+}
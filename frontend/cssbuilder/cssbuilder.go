@@ -11,7 +11,6 @@ import (
 	"github.com/speedata/boxesandglue/backend/node"
 	"github.com/speedata/boxesandglue/csshtml"
 	"github.com/speedata/boxesandglue/frontend"
-	"github.com/speedata/boxesandglue/frontend/pdfdraw"
 	"github.com/speedata/boxesandglue/htmlstyle"
 	"golang.org/x/net/html"
 )
@@ -25,15 +24,41 @@ type CSSBuilder struct {
 	frontend              *frontend.Document
 	css                   *csshtml.CSS
 	stylesStack           htmlstyle.StylesStack
+	generateOutline       bool
+	outlineStack          []outlineEntry
+	outlineDest           int
+	// pendingPages are the pages waiting for the total number of pages.
+	pendingPages []*pageState
+	// namedStrings are the values of the string-set names at the end of the
+	// last finished page.
+	namedStrings map[string]string
+	// footnotes are the footnote inserts on the current page.
+	footnotes []*node.Insert
+	// footnoteCount is the number of the last footnote in the document.
+	footnoteCount int
+	// destinations are the page numbers of the named destinations.
+	destinations map[string]int
+	// holdPages is true if NewPage must not ship out the pages because the
+	// layout might be repeated.
+	holdPages bool
+	// tocPlaceholder and tocEntries are the selectors of the table of
+	// contents, see SetTableOfContents.
+	tocPlaceholder string
+	tocEntries     string
+	// tocCount is the number of the last generated id of a table of contents
+	// entry.
+	tocCount int
 }
 
 // New creates an instance of the CSSBuilder.
 func New(fd *frontend.Document, c *csshtml.CSS) *CSSBuilder {
 	cb := CSSBuilder{
-		css:         c,
-		frontend:    fd,
-		stylesStack: make(htmlstyle.StylesStack, 0),
-		pagebox:     []node.Node{},
+		css:          c,
+		frontend:     fd,
+		stylesStack:  make(htmlstyle.StylesStack, 0),
+		pagebox:      []node.Node{},
+		namedStrings: make(map[string]string),
+		destinations: make(map[string]int),
 	}
 	cb.css.FrontendDocument = fd
 
@@ -147,6 +172,7 @@ func (cb *CSSBuilder) InitPage() error {
 			BorderBottomLeftRadius:  styles.BorderBottomLeftRadius,
 			BorderBottomRightRadius: styles.BorderBottomRightRadius,
 		}
+		hv.SetOpacity(styles.Opacity())
 		vl = cb.frontend.HTMLBorder(vl, hv)
 		cb.stylesStack.PopStyles()
 
@@ -167,13 +193,14 @@ func (cb *CSSBuilder) InitPage() error {
 			masterpage:    defaultPage,
 		}
 		cb.frontend.Doc.NewPage()
-		if styles.BackgroundColor != nil {
-			r := node.NewRule()
-			x := pdfdraw.NewStandalone().ColorNonstroking(*styles.BackgroundColor).Rect(0, 0, wd, -ht).Fill()
-			r.Pre = x.String()
-			rvl := node.Vpack(r)
-			rvl.Attributes = node.H{"origin": "page background color"}
-			cb.frontend.Doc.CurrentPage.OutputAt(0, ht, rvl)
+		if styles.BackgroundColor != nil || styles.BackgroundGradient != nil || styles.BackgroundImage() != nil {
+			bg := cb.frontend.BackgroundVList(frontend.HTMLValues{
+				BackgroundColor:    styles.BackgroundColor,
+				BackgroundGradient: styles.BackgroundGradient,
+				BackgroundImage:    styles.BackgroundImage(),
+			}, wd, ht)
+			bg.Attributes = node.H{"origin": "page background"}
+			cb.frontend.Doc.CurrentPage.OutputAt(0, ht, bg)
 		}
 		cb.frontend.Doc.CurrentPage.OutputAt(ml, ht-mt, vl)
 		return nil
@@ -216,22 +243,35 @@ func (cb *CSSBuilder) ParseCSSString(css string) error {
 	return nil
 }
 
-// NewPage puts the current page into the PDF document and starts with a new page.
+// NewPage puts the current page into the PDF document and starts with a new
+// page. If the page margin boxes need the total number of pages, the page is
+// kept until BeforeShipout is called for the last page.
 func (cb *CSSBuilder) NewPage() error {
 	if err := cb.InitPage(); err != nil {
 		return err
 	}
-	if err := cb.BeforeShipout(); err != nil {
+	if err := cb.placeFootnotes(); err != nil {
 		return err
 	}
-	cb.frontend.Doc.CurrentPage.Shipout()
-	cb.frontend.Doc.NewPage()
-	return nil
+	ps := cb.newPageState()
+	if cb.holdPages || cb.needsPageCount() {
+		cb.pendingPages = append(cb.pendingPages, ps)
+	} else {
+		if err := cb.renderMarginBoxes(ps, 0); err != nil {
+			return err
+		}
+		ps.page.Shipout()
+	}
+	// InitPage creates the next page with the matching page master
+	// (:left/:right) and its decorations.
+	cb.frontend.Doc.CurrentPage = nil
+	return cb.InitPage()
 }
 
 // ParseHTMLFromNode interprets the HTML structure and applies all previously read CSS data.
 func (cb *CSSBuilder) ParseHTMLFromNode(input *html.Node) (*frontend.Text, error) {
 	doc := goquery.NewDocumentFromNode(input)
+	cb.insertTableOfContents(doc)
 	gq, err := cb.css.ApplyCSS(doc)
 	if err != nil {
 		return nil, err
@@ -251,6 +291,7 @@ func (cb *CSSBuilder) HTMLToText(html string) (*frontend.Text, error) {
 	if err != nil {
 		return nil, err
 	}
+	cb.insertTableOfContents(doc)
 	gq, err := cb.css.ApplyCSS(doc)
 	if err != nil {
 		return nil, err
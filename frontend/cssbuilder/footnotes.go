@@ -0,0 +1,130 @@
+package cssbuilder
+
+import (
+	"strings"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/frontend"
+)
+
+// numberFootnotes gives the footnotes in the text consecutive numbers in
+// document order. With per page numbering the page builder changes the
+// numbers when it places the footnotes.
+func (cb *CSSBuilder) numberFootnotes(te *frontend.Text) {
+	for _, itm := range te.Items {
+		switch t := itm.(type) {
+		case *frontend.Footnote:
+			cb.footnoteCount++
+			t.Number = cb.footnoteCount
+		case *frontend.Text:
+			cb.numberFootnotes(t)
+		}
+	}
+}
+
+// footnotesPerPage returns true if a page rule resets the footnote counter
+// (@page { counter-reset: footnote }).
+func (cb *CSSBuilder) footnotesPerPage() bool {
+	for _, pg := range cb.css.Pages {
+		for _, attr := range pg.Attributes {
+			if attr.Key == "!counter-reset" && strings.Contains(attr.Val, "footnote") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// footnoteHeight returns the height of the footnote area of the current page
+// with the additional inserts. The height is 0 if there are no footnotes.
+func (cb *CSSBuilder) footnoteHeight(inserts []*node.Insert) (bag.ScaledPoint, error) {
+	if len(cb.footnotes)+len(inserts) == 0 {
+		return 0, nil
+	}
+	if cb.footnotesPerPage() {
+		for i, ins := range inserts {
+			if err := cb.frontend.RenumberFootnote(ins, len(cb.footnotes)+i+1); err != nil {
+				return 0, err
+			}
+		}
+	}
+	all := append(append([]*node.Insert{}, cb.footnotes...), inserts...)
+	vl, err := cb.frontend.BuildFootnotes(all, cb.currentPageDimensions.ContentWidth, nil)
+	if err != nil {
+		return 0, err
+	}
+	return vl.Height + vl.Depth, nil
+}
+
+// splitHeight returns the height for the first part of vl when vl is split
+// so that the part and its footnotes fit into the available space.
+func (cb *CSSBuilder) splitHeight(vl *node.VList, available bag.ScaledPoint) (bag.ScaledPoint, error) {
+	fh, err := cb.footnoteHeight(nil)
+	if err != nil {
+		return 0, err
+	}
+	// split is the height for VSplit, ht is the height of the material up to
+	// the current line and lastLine the height up to the last line which
+	// fits with its footnotes.
+	split := available - fh
+	var ht, lastLine bag.ScaledPoint
+	var inserts []*node.Insert
+	var walk func(head node.Node) (bool, error)
+	walk = func(head node.Node) (bool, error) {
+		for n := head; n != nil; n = n.Next() {
+			switch t := n.(type) {
+			case *node.VList:
+				if done, err := walk(t.List); done || err != nil {
+					return done, err
+				}
+			case *node.Glue:
+				ht += t.Width
+			case *node.Kern:
+				ht += t.Kern
+			case *node.HList:
+				lineFh := fh
+				if ins := node.CollectInserts(t.List, frontend.InsertFootnote); len(ins) > 0 {
+					inserts = append(inserts, ins...)
+					if lineFh, err = cb.footnoteHeight(inserts); err != nil {
+						return true, err
+					}
+				}
+				if ht+t.Height+t.Depth+lineFh > available {
+					split = available - fh
+					if ht+t.Height+t.Depth <= split {
+						// the line fits but its footnotes don't
+						split = lastLine
+					}
+					return true, nil
+				}
+				ht += t.Height + t.Depth
+				lastLine = ht
+				fh = lineFh
+				split = available - fh
+			}
+		}
+		return false, nil
+	}
+	if _, err = walk(vl.List); err != nil {
+		return 0, err
+	}
+	return split, nil
+}
+
+// placeFootnotes outputs the footnotes of the current page above the bottom
+// margin and starts a new list of footnotes.
+func (cb *CSSBuilder) placeFootnotes() error {
+	if len(cb.footnotes) == 0 {
+		return nil
+	}
+	pd := cb.currentPageDimensions
+	vl, err := cb.frontend.BuildFootnotes(cb.footnotes, pd.ContentWidth, nil)
+	if err != nil {
+		return err
+	}
+	bottom := pd.Height - pd.MarginTop - pd.ContentHeight
+	cb.frontend.Doc.CurrentPage.OutputAt(pd.MarginLeft, bottom+vl.Height+vl.Depth, vl)
+	cb.footnotes = cb.footnotes[:0]
+	return nil
+}
@@ -20,7 +20,11 @@ func (cb *CSSBuilder) CreateVlist(te *frontend.Text, wd bag.ScaledPoint) (*node.
 		case *node.StartStop:
 			// ignore for now - should be used for frames
 		case *node.VList:
-			if xattr, ok := t.Attributes["x"].(bag.ScaledPoint); ok {
+			xattr, ok := t.Attributes["x"].(bag.ScaledPoint)
+			if cols, ok := t.Attributes["columns"].(*frontend.Columns); ok {
+				t, _ = cb.frontend.BuildColumns(t, cols, t.Attributes["hsize"].(bag.ScaledPoint), 0)
+			}
+			if ok {
 				t.ShiftX = xattr
 			}
 			list = node.InsertAfter(list, node.Tail(list), t)
@@ -57,11 +61,37 @@ func (cb *CSSBuilder) buildVlistInternal(te *frontend.Text, width bag.ScaledPoin
 		}
 	}
 
+	cols, _ := te.Settings[frontend.SettingColumns].(*frontend.Columns)
+
 	var prevMB, height bag.ScaledPoint
 	if bx, ok := te.Settings[frontend.SettingBox]; ok && bx.(bool) {
 		// a box, containing one or more item (a div for example)
+		var columnMaterial node.Node
+		flushColumns := func() {
+			if columnMaterial == nil {
+				return
+			}
+			vl := cb.columnsVList(columnMaterial, cols, hsize, x+hv.BorderLeftWidth+hv.PaddingLeft)
+			height += vl.Attributes["height"].(bag.ScaledPoint)
+			ret.pagebox = append(ret.pagebox, vl)
+			columnMaterial = nil
+			prevMB = 0
+		}
 		for _, itm := range te.Items {
 			if txt, ok := itm.(*frontend.Text); ok {
+				if cols != nil {
+					if span, ok := txt.Settings[frontend.SettingColumnSpan].(bool); !ok || !span {
+						_, colWidth := cols.ColumnWidth(hsize)
+						info, err := cb.buildVlistInternal(txt, colWidth, 0, 0)
+						if err != nil {
+							return nil, err
+						}
+						columnMaterial = appendColumnMaterial(columnMaterial, info)
+						continue
+					}
+					// column-span: all
+					flushColumns()
+				}
 				info, err := cb.buildVlistInternal(txt, hsize, x+hv.BorderLeftWidth+hv.PaddingLeft, shiftDown)
 				if err != nil {
 					return nil, err
@@ -104,6 +134,7 @@ func (cb *CSSBuilder) buildVlistInternal(te *frontend.Text, width bag.ScaledPoin
 				prevMB = info.marginBottom
 			}
 		}
+		flushColumns()
 		ret.x = x
 		ret.hsize = hsize
 		ret.height = height
@@ -114,6 +145,25 @@ func (cb *CSSBuilder) buildVlistInternal(te *frontend.Text, width bag.ScaledPoin
 	// not a box
 	//
 	// something like a p tag that contains some stuff to be typeset.
+	if level, ok := te.Settings[frontend.SettingOutlineLevel]; ok && cb.generateOutline {
+		cb.addOutline(te, level.(int))
+	}
+	if cols != nil {
+		_, colWidth := cols.ColumnWidth(hsize)
+		vl, err := cb.createVList(te, colWidth, hv)
+		if err != nil {
+			return nil, err
+		}
+		// the paragraph can be broken into the columns
+		vl.Attributes = nil
+		vl = cb.columnsVList(vl, cols, hsize, x+hv.PaddingLeft+hv.BorderLeftWidth)
+		ret.height = vl.Attributes["height"].(bag.ScaledPoint)
+		ret.vl = vl
+		ret.hv = hv
+		ret.hsize = hsize
+		ret.x = x
+		return ret, nil
+	}
 	vl, err := cb.createVList(te, hsize, hv)
 	if err != nil {
 		return nil, err
@@ -141,6 +191,12 @@ func (cb *CSSBuilder) buildVlistInternal(te *frontend.Text, width bag.ScaledPoin
 		"x":      x + hv.PaddingLeft + hv.BorderLeftWidth,
 		"hsize":  hsize,
 	}
+	if len(te.Items) == 1 {
+		if tbl, ok := te.Items[0].(*frontend.Table); ok {
+			// tables can be split at page breaks
+			vl.Attributes["table"] = tbl
+		}
+	}
 	ret.height = vl.Height + vl.Depth
 	ret.vl = vl
 	ret.hv = hv
@@ -161,3 +217,66 @@ func (cb *CSSBuilder) createVList(te *frontend.Text, wd bag.ScaledPoint, hv fron
 	}
 	return vl, nil
 }
+
+// appendColumnMaterial appends the contents of a box inside a multi-column
+// layout to list. Margins, borders and paddings become vertical space, borders
+// and backgrounds are not drawn inside columns.
+func appendColumnMaterial(list node.Node, inf *info) node.Node {
+	addSpace := func(wd bag.ScaledPoint) {
+		if wd == 0 {
+			return
+		}
+		g := node.NewGlue()
+		g.Width = wd
+		g.Attributes = node.H{"origin": "column space"}
+		list = node.InsertAfter(list, node.Tail(list), g)
+	}
+	addVList := func(vl *node.VList) {
+		if xattr, ok := vl.Attributes["x"].(bag.ScaledPoint); ok && xattr != 0 {
+			vl.ShiftX = xattr
+		}
+		if _, ok := vl.Attributes["columns"]; !ok {
+			// paragraphs without attributes can be broken between lines
+			vl.Attributes = nil
+		}
+		list = node.InsertAfter(list, node.Tail(list), vl)
+	}
+	addSpace(inf.marginTop + inf.hv.BorderTopWidth + inf.hv.PaddingTop)
+	if inf.vl != nil {
+		addVList(inf.vl)
+	} else {
+		for _, n := range inf.pagebox {
+			switch t := n.(type) {
+			case *node.StartStop:
+				sd, _ := t.Attributes["shiftDown"].(bag.ScaledPoint)
+				if hv, ok := t.Attributes["hv"].(frontend.HTMLValues); ok {
+					if t.StartNode == nil {
+						sd += hv.BorderTopWidth + hv.PaddingTop
+					} else {
+						sd += hv.BorderBottomWidth + hv.PaddingBottom
+					}
+				}
+				addSpace(sd)
+			case *node.VList:
+				addVList(t)
+			}
+		}
+	}
+	addSpace(inf.marginBottom + inf.hv.BorderBottomWidth + inf.hv.PaddingBottom)
+	return list
+}
+
+// columnsVList packs the material of a multi-column layout into a vlist for the
+// page box. The height attribute is the height of the balanced columns, the
+// columns are built when the vlist is placed on the page.
+func (cb *CSSBuilder) columnsVList(material node.Node, cols *frontend.Columns, hsize, x bag.ScaledPoint) *node.VList {
+	area, _ := cb.frontend.BuildColumns(node.Vpack(node.CopyList(material)), cols, hsize, 0)
+	vl := node.Vpack(material)
+	vl.Attributes = node.H{
+		"height":  area.Height + area.Depth,
+		"x":       x,
+		"hsize":   hsize,
+		"columns": cols,
+	}
+	return vl
+}
@@ -0,0 +1,70 @@
+package cssbuilder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/speedata/boxesandglue/backend/document"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/frontend"
+)
+
+// outlineDestPrefix is the prefix of the named destinations of the outline
+// entries. Named destinations don't clash with numbered destinations of other
+// callers.
+const outlineDestPrefix = "bag-outline-"
+
+type outlineEntry struct {
+	level   int
+	outline *document.Outline
+}
+
+// SetGenerateOutline enables or disables the generation of PDF outlines
+// (bookmarks) from the headings h1 to h6.
+func (cb *CSSBuilder) SetGenerateOutline(generate bool) {
+	cb.generateOutline = generate
+}
+
+// textContents returns the plain text of te with normalized whitespace.
+func textContents(te *frontend.Text) string {
+	var sb strings.Builder
+	var collect func(*frontend.Text)
+	collect = func(t *frontend.Text) {
+		for _, itm := range t.Items {
+			switch v := itm.(type) {
+			case string:
+				sb.WriteString(v)
+			case *frontend.Text:
+				collect(v)
+			}
+		}
+	}
+	collect(te)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// addOutline inserts a destination node at the beginning of the heading te
+// and adds an outline entry below the last entry with a lower level.
+func (cb *CSSBuilder) addOutline(te *frontend.Text, level int) {
+	cb.outlineDest++
+	dest := node.NewStartStop()
+	dest.Action = node.ActionDest
+	dest.Value = fmt.Sprintf("%s%d", outlineDestPrefix, cb.outlineDest)
+	te.Items = append([]any{dest}, te.Items...)
+
+	ol := &document.Outline{
+		Title: textContents(te),
+		Dest:  dest,
+		Open:  true,
+	}
+	for len(cb.outlineStack) > 0 && cb.outlineStack[len(cb.outlineStack)-1].level >= level {
+		cb.outlineStack = cb.outlineStack[:len(cb.outlineStack)-1]
+	}
+	if len(cb.outlineStack) == 0 {
+		cb.frontend.Doc.Outlines = append(cb.frontend.Doc.Outlines, ol)
+	} else {
+		parent := cb.outlineStack[len(cb.outlineStack)-1].outline
+		parent.Children = append(parent.Children, ol)
+	}
+	cb.outlineStack = append(cb.outlineStack, outlineEntry{level: level, outline: ol})
+}
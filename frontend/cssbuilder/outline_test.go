@@ -0,0 +1,60 @@
+package cssbuilder
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/document"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/csshtml"
+	"github.com/speedata/boxesandglue/frontend"
+)
+
+// outlineTree returns the titles of the outlines with the children in
+// parentheses.
+func outlineTree(outlines []*document.Outline) string {
+	var titles []string
+	for _, ol := range outlines {
+		title := ol.Title
+		if len(ol.Children) > 0 {
+			title += " (" + outlineTree(ol.Children) + ")"
+		}
+		titles = append(titles, title)
+	}
+	return strings.Join(titles, ", ")
+}
+
+func TestAddOutline(t *testing.T) {
+	fe, err := frontend.New(filepath.Join(t.TempDir(), "out.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := New(fe, csshtml.NewCSSParser())
+	headings := []struct {
+		title string
+		level int
+	}{
+		{"One", 1},
+		{"One.a", 3},
+		{"One.b", 2},
+		{"One.b.i", 3},
+		{"Two", 1},
+	}
+	for _, h := range headings {
+		te := frontend.NewText()
+		te.Items = append(te.Items, "  "+h.title+" ")
+		cb.addOutline(te, h.level)
+		dest, ok := te.Items[0].(*node.StartStop)
+		if !ok || dest.Action != node.ActionDest {
+			t.Fatalf("heading %s does not start with a destination", h.title)
+		}
+		if name, ok := dest.Value.(string); !ok || !strings.HasPrefix(name, outlineDestPrefix) {
+			t.Errorf("destination of %s = %v, want a name with the prefix %s", h.title, dest.Value, outlineDestPrefix)
+		}
+	}
+	want := "One (One.a, One.b (One.b.i)), Two"
+	if got := outlineTree(fe.Doc.Outlines); got != want {
+		t.Errorf("outlines = %s, want %s", got, want)
+	}
+}
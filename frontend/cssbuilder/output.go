@@ -3,6 +3,7 @@ package cssbuilder
 import (
 	"github.com/speedata/boxesandglue/backend/bag"
 	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/csshtml"
 	"github.com/speedata/boxesandglue/frontend"
 	"github.com/speedata/boxesandglue/htmlstyle"
 )
@@ -13,6 +14,7 @@ func (cb *CSSBuilder) OutputPage(html string) error {
 	if err != nil {
 		return err
 	}
+	cb.insertTableOfContents(doc)
 	gq, err := cb.css.ApplyCSS(doc)
 	if err != nil {
 		return err
@@ -37,6 +39,7 @@ func (cb *CSSBuilder) outputOnPage(te *frontend.Text) error {
 	if err != nil {
 		return err
 	}
+	cb.numberFootnotes(te)
 	info, err := cb.buildVlistInternal(te, dim.ContentWidth, dim.MarginLeft, 0)
 	if err != nil {
 		return err
@@ -50,30 +53,36 @@ func (cb *CSSBuilder) outputOnPage(te *frontend.Text) error {
 	return nil
 }
 
-// turn content: `"page " counter(page) " of " counter(pages)` into a meaningful
-// string.
-func (cb *CSSBuilder) parseContent(in string) string {
-	var result []rune
-	inString := false
-	for _, r := range in {
-		switch r {
-		case '"':
-			inString = !inString
-		default:
-			if inString {
-				result = append(result, r)
-			}
+// BeforeShipout should be called when placing a CSS page in the PDF. It adds
+// page margin boxes to the current page. If the margin boxes use
+// counter(pages) or if OutputPages was used, the pages which have been held
+// back by NewPage are finished and shipped out first, so BeforeShipout must be
+// called for the last page of the document.
+func (cb *CSSBuilder) BeforeShipout() error {
+	if err := cb.InitPage(); err != nil {
+		return err
+	}
+	total := len(cb.frontend.Doc.Pages)
+	for _, ps := range cb.pendingPages {
+		if err := cb.renderMarginBoxes(ps, total); err != nil {
+			return err
 		}
+		ps.page.Shipout()
+	}
+	cb.pendingPages = cb.pendingPages[:0]
+	cb.holdPages = false
+	if err := cb.placeFootnotes(); err != nil {
+		return err
 	}
-	return string(result)
+	return cb.renderMarginBoxes(cb.newPageState(), total)
 }
 
-// BeforeShipout should be called when placing a CSS page in the PDF. It adds
-// page margin boxes to the current page.
-func (cb *CSSBuilder) BeforeShipout() error {
+// renderMarginBoxes adds the page margin boxes to the page. total is the
+// number of pages or 0 if not known yet.
+func (cb *CSSBuilder) renderMarginBoxes(ps *pageState, total int) error {
 	var err error
 	df := cb.frontend
-	dimensions := cb.currentPageDimensions
+	dimensions := ps.dimensions
 	mp := dimensions.masterpage
 	if mp != nil {
 		pageMarginBoxes := make(map[string]*pageMarginBox)
@@ -95,12 +104,12 @@ func (cb *CSSBuilder) BeforeShipout() error {
 			switch areaName {
 			case "top-left-corner":
 				pmb.x = 0
-				pmb.y = df.Doc.DefaultPageHeight
+				pmb.y = dimensions.Height
 				pmb.wd = dimensions.MarginLeft
 				pmb.ht = dimensions.MarginTop
 			case "top-right-corner":
 				pmb.x = dimensions.Width - dimensions.MarginRight
-				pmb.y = df.Doc.DefaultPageHeight
+				pmb.y = dimensions.Height
 				pmb.wd = dimensions.MarginRight
 				pmb.ht = dimensions.MarginTop
 			case "bottom-left-corner":
@@ -115,7 +124,7 @@ func (cb *CSSBuilder) BeforeShipout() error {
 				pmb.ht = dimensions.MarginBottom
 			case "top-left", "top-center", "top-right":
 				pmb.x = dimensions.MarginLeft
-				pmb.y = df.Doc.DefaultPageHeight
+				pmb.y = dimensions.Height
 				pmb.wd = dimensions.Width - dimensions.MarginLeft - dimensions.MarginRight
 				pmb.ht = dimensions.MarginTop
 				switch areaName {
@@ -157,7 +166,7 @@ func (cb *CSSBuilder) BeforeShipout() error {
 				vl := node.NewVList()
 				var err error
 				if c, ok := area["content"]; ok {
-					c = cb.parseContent(c)
+					c = csshtml.ContentText(csshtml.ResolveContent(c, pageResolver{ps: ps, total: total}))
 					if c != "" {
 						txt := frontend.NewText()
 						htmlstyle.ApplySettings(txt.Settings, styles)
@@ -199,9 +208,12 @@ func (cb *CSSBuilder) BeforeShipout() error {
 						BorderBottomLeftRadius:  styles.BorderBottomLeftRadius,
 						BorderBottomRightRadius: styles.BorderBottomRightRadius,
 						BackgroundColor:         styles.BackgroundColor,
+						BackgroundGradient:      styles.BackgroundGradient,
+						BackgroundImage:         styles.BackgroundImage(),
 					}
+					hv.SetOpacity(styles.Opacity())
 					vl = df.HTMLBorder(vl, hv)
-					df.Doc.CurrentPage.OutputAt(pmb.x, pmb.y, vl)
+					ps.page.OutputAt(pmb.x, pmb.y, vl)
 					cb.stylesStack.PopStyles()
 
 				}
@@ -211,6 +223,63 @@ func (cb *CSSBuilder) BeforeShipout() error {
 	return nil
 }
 
+// openBox is a box (such as a div) whose start node has been placed on a page
+// but whose stop node has not been reached yet. The border of an open box is
+// drawn in slices, one for each page the box spans.
+type openBox struct {
+	hv    frontend.HTMLValues
+	x     bag.ScaledPoint
+	hsize bag.ScaledPoint
+	// contentBottom is the y position of the end of the box contents on the
+	// current page if no further page break occurs.
+	contentBottom bag.ScaledPoint
+}
+
+// drawBorder outputs the part of the box border that fits between top and
+// limit on the current page. The top border is omitted on continuation slices
+// and the bottom border is omitted if the box does not end on this page.
+func (cb *CSSBuilder) drawBorder(bx *openBox, top, limit bag.ScaledPoint, continued bool) {
+	hv := bx.hv
+	if continued {
+		hv.BorderTopWidth = 0
+		hv.PaddingTop = 0
+		hv.BorderTopLeftRadius = 0
+		hv.BorderTopRightRadius = 0
+	}
+	bottom := bx.contentBottom - hv.PaddingBottom - hv.BorderBottomWidth
+	if bottom < limit {
+		hv.BorderBottomWidth = 0
+		hv.PaddingBottom = 0
+		hv.BorderBottomLeftRadius = 0
+		hv.BorderBottomRightRadius = 0
+		bottom = limit
+	}
+	vl := node.NewVList()
+	vl.Width = bx.hsize
+	vl.Height = top - bottom - hv.PaddingTop - hv.BorderTopWidth - hv.PaddingBottom - hv.BorderBottomWidth
+	vl = cb.frontend.HTMLBorder(vl, hv)
+	cb.frontend.Doc.CurrentPage.OutputAt(bx.x, top, vl)
+}
+
+// firstLineFits returns true if the first line of the paragraph vl fits into
+// the available height, so the paragraph can be split at a line boundary.
+func firstLineFits(vl *node.VList, available bag.ScaledPoint) bool {
+	for e := vl.List; e != nil; e = e.Next() {
+		switch t := e.(type) {
+		case *node.HList:
+			if origin, ok := t.Attributes["origin"]; !ok || origin != "line" {
+				return false
+			}
+			return t.Height+t.Depth <= available
+		case *node.Glue, *node.Penalty, *node.Kern, *node.StartStop:
+			// skip
+		default:
+			return false
+		}
+	}
+	return false
+}
+
 // buildPages takes the internal pagebox slice and outputs each item with page
 // breaks in between.
 func (cb *CSSBuilder) buildPages() error {
@@ -220,12 +289,58 @@ func (cb *CSSBuilder) buildPages() error {
 		The start node (a StartStop node that has an empty Start field) denotes the
 		start of a box (such as a div or a p).
 		The VList node is actually something to typeset.
+
+		A new page is started when the next item does not fit into the remaining
+		space of the current page. All boxes that are open at that point get a
+		border without a bottom edge on the old page and a border without a top
+		edge on the new page.
 	*/
 	pd, err := cb.PageSize()
 	if err != nil {
 		return err
 	}
-	y := pd.Height - pd.MarginTop
+	top := pd.Height - pd.MarginTop
+	limit := top - pd.ContentHeight
+	y := top
+	var openBoxes []*openBox
+	// fnHeight is the height of the footnote area on the current page
+	fnHeight, err := cb.footnoteHeight(nil)
+	if err != nil {
+		return err
+	}
+	addFootnotes := func(vl *node.VList) error {
+		inserts := node.CollectInserts(vl.List, frontend.InsertFootnote)
+		if len(inserts) == 0 {
+			return nil
+		}
+		if fnHeight, err = cb.footnoteHeight(inserts); err != nil {
+			return err
+		}
+		cb.footnotes = append(cb.footnotes, inserts...)
+		return nil
+	}
+
+	newPage := func() error {
+		if err := cb.NewPage(); err != nil {
+			return err
+		}
+		if pd, err = cb.PageSize(); err != nil {
+			return err
+		}
+		newTop := pd.Height - pd.MarginTop
+		for _, bx := range openBoxes {
+			bx.contentBottom = newTop - (y - bx.contentBottom)
+		}
+		top = newTop
+		limit = top - pd.ContentHeight
+		y = top
+		fnHeight = 0
+		for _, bx := range openBoxes {
+			cb.drawBorder(bx, top, limit, true)
+		}
+		return nil
+	}
+
 	var height, shiftDown bag.ScaledPoint
 	for _, n := range cb.pagebox {
 		switch t := n.(type) {
@@ -233,36 +348,149 @@ func (cb *CSSBuilder) buildPages() error {
 			// start node
 			tAttribs := t.Attributes
 			if _, ok := tAttribs["pagebreak"]; ok {
-				if err := cb.NewPage(); err != nil {
+				if err := newPage(); err != nil {
 					return err
 				}
 			}
 			var hv frontend.HTMLValues
 			var ok bool
 			shiftDown = tAttribs["shiftDown"].(bag.ScaledPoint)
-			y -= shiftDown
 
 			if hv, ok = tAttribs["hv"].(frontend.HTMLValues); ok {
 				if t.StartNode == nil {
 					// top start node -> draw border
-					x := t.Attributes["x"].(bag.ScaledPoint)
-					vl := node.NewVList()
-					vl.Width = tAttribs["hsize"].(bag.ScaledPoint)
-					vl.Height = tAttribs["height"].(bag.ScaledPoint)
-					vl = cb.frontend.HTMLBorder(vl, hv)
-					cb.frontend.Doc.CurrentPage.OutputAt(x, y, vl)
+					if y < top && y-shiftDown-hv.PaddingTop-hv.BorderTopWidth < limit+fnHeight {
+						if err := newPage(); err != nil {
+							return err
+						}
+						// the margin is truncated at an unforced page break
+						shiftDown = 0
+					}
+					y -= shiftDown
+					bx := &openBox{
+						hv:    hv,
+						x:     t.Attributes["x"].(bag.ScaledPoint),
+						hsize: tAttribs["hsize"].(bag.ScaledPoint),
+					}
+					bx.contentBottom = y - hv.PaddingTop - hv.BorderTopWidth - tAttribs["height"].(bag.ScaledPoint)
+					cb.drawBorder(bx, y, limit, false)
+					openBoxes = append(openBoxes, bx)
 					y -= hv.PaddingTop + hv.BorderTopWidth
 				} else {
 					// bottom start node -> just move cursor
+					y -= shiftDown
 					y -= hv.PaddingBottom + hv.BorderBottomWidth
+					if len(openBoxes) > 0 {
+						openBoxes = openBoxes[:len(openBoxes)-1]
+					}
 				}
+			} else {
+				y -= shiftDown
 			}
 
 		case *node.VList:
 			tAttribs := t.Attributes
 			height = tAttribs["height"].(bag.ScaledPoint)
 			x := tAttribs["x"].(bag.ScaledPoint)
+			// distribute multi-column material into column areas, one per page
+			if cols, ok := tAttribs["columns"].(*frontend.Columns); ok {
+				hsize := tAttribs["hsize"].(bag.ScaledPoint)
+				if y <= limit+fnHeight {
+					if err := newPage(); err != nil {
+						return err
+					}
+				}
+				area, rest := cb.frontend.BuildColumns(t, cols, hsize, y-limit-fnHeight)
+				for rest != nil {
+					cb.frontend.Doc.CurrentPage.OutputAt(x, y, area)
+					if err := addFootnotes(area); err != nil {
+						return err
+					}
+					y -= area.Height + area.Depth
+					if err := newPage(); err != nil {
+						return err
+					}
+					area, rest = cb.frontend.BuildColumns(rest, cols, hsize, y-limit-fnHeight)
+				}
+				t = area
+				height = area.Height + area.Depth
+			}
+			// split tables that do not fit on the page between rows
+			if tbl, ok := tAttribs["table"].(*frontend.Table); ok && y-height < limit+fnHeight {
+				tbl.FirstPageHeight = y - limit - fnHeight
+				tbl.PageHeight = pd.ContentHeight
+				parts, err := cb.frontend.BuildTable(tbl)
+				if err != nil {
+					return err
+				}
+				for _, part := range parts[:len(parts)-1] {
+					cb.frontend.Doc.CurrentPage.OutputAt(x, y, part)
+					if err := addFootnotes(part); err != nil {
+						return err
+					}
+					if err := newPage(); err != nil {
+						return err
+					}
+				}
+				t = parts[len(parts)-1]
+				height = t.Height + t.Depth
+			}
+			// split paragraphs that do not fit on the page between lines, the
+			// footnotes of the lines on this page must fit as well
+			for {
+				available, err := cb.splitHeight(t, y-limit)
+				if err != nil {
+					return err
+				}
+				if height <= available {
+					break
+				}
+				if !firstLineFits(t, available) {
+					if y == top {
+						break
+					}
+					if err := newPage(); err != nil {
+						return err
+					}
+					continue
+				}
+				first, rest := node.VSplit(t, available, nil)
+				if rest == nil {
+					t = first
+					height = first.Height + first.Depth
+					break
+				}
+				cb.frontend.Doc.CurrentPage.OutputAt(x, y, first)
+				if err := addFootnotes(first); err != nil {
+					return err
+				}
+				y -= first.Height + first.Depth
+				// first is packed to the available height, the open boxes
+				// continue with the material of rest on the next page
+				slack := first.Height + first.Depth - (height - rest.Height - rest.Depth)
+				for _, bx := range openBoxes {
+					bx.contentBottom -= slack
+				}
+				if err := newPage(); err != nil {
+					return err
+				}
+				t = rest
+				height = rest.Height + rest.Depth
+			}
+			inserts := node.CollectInserts(t.List, frontend.InsertFootnote)
+			fh, err := cb.footnoteHeight(inserts)
+			if err != nil {
+				return err
+			}
+			if y < top && y-height < limit+fh {
+				if err := newPage(); err != nil {
+					return err
+				}
+			}
 			cb.frontend.Doc.CurrentPage.OutputAt(x, y, t)
+			if err := addFootnotes(t); err != nil {
+				return err
+			}
 			y -= height
 		}
 	}
@@ -273,6 +501,7 @@ func (cb *CSSBuilder) buildPages() error {
 // width. OutputAt inserts page breaks if necessary.
 func (cb *CSSBuilder) OutputAt(text *frontend.Text, x, y, width bag.ScaledPoint) error {
 	bag.Logger.Debug("CSSBuilder#OutputAt")
+	cb.numberFootnotes(text)
 	inf, err := cb.buildVlistInternal(text, width, x, 0)
 	if err != nil {
 		return err
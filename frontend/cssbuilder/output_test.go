@@ -0,0 +1,103 @@
+package cssbuilder
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/document"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/csshtml"
+	"github.com/speedata/boxesandglue/frontend"
+)
+
+// borderSegment returns the border drawn on the page and whether it has a top
+// and a bottom edge.
+func borderSegment(t *testing.T, pg *document.Page) (bag.ScaledPoint, bool, bool) {
+	t.Helper()
+	for _, obj := range pg.Objects {
+		if origin, ok := obj.Vlist.Attributes["origin"]; !ok || origin != "vpack padding" {
+			continue
+		}
+		var top, bottom bool
+		for e := obj.Vlist.List; e != nil; e = e.Next() {
+			if g, ok := e.(*node.Glue); ok {
+				top = top || g.Attributes["origin"] == "html border top glue"
+				bottom = bottom || g.Attributes["origin"] == "html border bottom glue"
+			}
+		}
+		return obj.Vlist.Height + obj.Vlist.Depth, top, bottom
+	}
+	t.Fatal("no border on the page")
+	return 0, false, false
+}
+
+func TestBuildPagesBorderSplit(t *testing.T) {
+	fe, err := frontend.New(filepath.Join(t.TempDir(), "out.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := New(fe, csshtml.NewCSSParser())
+	cb.holdPages = true
+
+	// a paragraph of three lines with 100mm each does not fit on a A4 page
+	// with 277mm content height
+	lineHeight := bag.MustSp("100mm")
+	var head, tail node.Node
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			g := node.NewGlue()
+			head = node.InsertAfter(head, tail, g)
+			tail = g
+		}
+		r := node.NewRule()
+		r.Height = lineHeight
+		hl := node.Hpack(r)
+		hl.Attributes = node.H{"origin": "line"}
+		head = node.InsertAfter(head, tail, hl)
+		tail = hl
+	}
+	para := node.Vpack(head)
+	para.Attributes = node.H{"height": 3 * lineHeight, "x": onecm}
+
+	borderWidth := bag.MustSp("2pt")
+	hv := frontend.HTMLValues{
+		BorderTopWidth:    borderWidth,
+		BorderBottomWidth: borderWidth,
+		BorderTopStyle:    frontend.BorderStyleSolid,
+		BorderBottomStyle: frontend.BorderStyleSolid,
+	}
+	start := node.NewStartStop()
+	start.Attributes = node.H{
+		"shiftDown": bag.ScaledPoint(0),
+		"hv":        hv,
+		"height":    3 * lineHeight,
+		"hsize":     bag.MustSp("190mm"),
+		"x":         onecm,
+	}
+	stop := node.NewStartStop()
+	stop.StartNode = start
+	stop.Attributes = node.H{
+		"shiftDown": bag.ScaledPoint(0),
+		"height":    3 * lineHeight,
+		"hv":        hv,
+	}
+	cb.pagebox = []node.Node{start, para, stop}
+	if err = cb.buildPages(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cb.pendingPages) != 1 {
+		t.Fatalf("got %d finished pages, want 1", len(cb.pendingPages))
+	}
+
+	// the first page has the top edge and reaches down to the bottom margin
+	ht, top, bottom := borderSegment(t, cb.pendingPages[0].page)
+	if want := cb.currentPageDimensions.ContentHeight; ht != want || !top || bottom {
+		t.Errorf("border on page 1 = %s (top %t, bottom %t), want %s (top true, bottom false)", ht, top, bottom, want)
+	}
+	// the second page has the last line and the bottom edge
+	ht, top, bottom = borderSegment(t, fe.Doc.CurrentPage)
+	if want := lineHeight + borderWidth; ht != want || top || !bottom {
+		t.Errorf("border on page 2 = %s (top %t, bottom %t), want %s (top false, bottom true)", ht, top, bottom, want)
+	}
+}
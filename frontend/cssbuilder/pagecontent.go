@@ -0,0 +1,131 @@
+package cssbuilder
+
+import (
+	"strings"
+
+	"github.com/speedata/boxesandglue/backend/document"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/csshtml"
+)
+
+// pageState contains everything needed to render the page margin boxes of a
+// page after the page contents have been placed.
+type pageState struct {
+	page       *document.Page
+	dimensions PageDimensions
+	number     int
+	// assignments are the string-set values in the order of the page
+	// contents.
+	assignments []csshtml.StringSet
+	// entryStrings are the named strings at the beginning of the page.
+	entryStrings map[string]string
+}
+
+// pageResolver resolves counter(page), counter(pages) and string() in the
+// page margin boxes. total is 0 if the number of pages is not known yet.
+type pageResolver struct {
+	ps    *pageState
+	total int
+}
+
+func (pr pageResolver) Counter(name string) ([]int, bool) {
+	switch name {
+	case "page":
+		return []int{pr.ps.number}, true
+	case "pages":
+		if pr.total == 0 {
+			return nil, false
+		}
+		return []int{pr.total}, true
+	}
+	return []int{0}, true
+}
+
+func (pr pageResolver) Function(name string, args []string) (string, bool) {
+	if name != "string" || len(args) == 0 {
+		return "", false
+	}
+	strName := strings.TrimSpace(args[0])
+	pos := "first"
+	if len(args) > 1 {
+		pos = strings.TrimSpace(args[1])
+	}
+	var values []string
+	for _, a := range pr.ps.assignments {
+		if a.Name == strName {
+			values = append(values, csshtml.ContentText(csshtml.ResolveContent(a.Value, pr)))
+		}
+	}
+	entry := pr.ps.entryStrings[strName]
+	if len(values) == 0 {
+		return entry, true
+	}
+	switch pos {
+	case "start":
+		if entry != "" {
+			return entry, true
+		}
+		return values[0], true
+	case "last":
+		return values[len(values)-1], true
+	case "first-except":
+		return "", true
+	}
+	return values[0], true
+}
+
+// collectStringSets returns the string-set assignments in the node list in
+// document order.
+func collectStringSets(n node.Node) []csshtml.StringSet {
+	var ret []csshtml.StringSet
+	for e := n; e != nil; e = e.Next() {
+		switch t := e.(type) {
+		case *node.HList:
+			ret = append(ret, collectStringSets(t.List)...)
+		case *node.VList:
+			ret = append(ret, collectStringSets(t.List)...)
+		case *node.StartStop:
+			if v, ok := node.GetAttribute(t, "string-set"); ok {
+				ret = append(ret, v.([]csshtml.StringSet)...)
+			}
+		}
+	}
+	return ret
+}
+
+// newPageState collects the string-set assignments of the current page and
+// remembers the last value of each named string for the following pages.
+func (cb *CSSBuilder) newPageState() *pageState {
+	doc := cb.frontend.Doc
+	ps := &pageState{
+		page:         doc.CurrentPage,
+		dimensions:   cb.currentPageDimensions,
+		number:       len(doc.Pages),
+		entryStrings: make(map[string]string, len(cb.namedStrings)),
+	}
+	for k, v := range cb.namedStrings {
+		ps.entryStrings[k] = v
+	}
+	for _, obj := range doc.CurrentPage.Objects {
+		ps.assignments = append(ps.assignments, collectStringSets(obj.Vlist)...)
+		collectDestinations(obj.Vlist, ps.number, cb.destinations)
+	}
+	pr := pageResolver{ps: ps}
+	for _, a := range ps.assignments {
+		cb.namedStrings[a.Name] = csshtml.ContentText(csshtml.ResolveContent(a.Value, pr))
+	}
+	return ps
+}
+
+// needsPageCount returns true if a page margin box uses counter(pages). Such
+// pages can only be finished at the end of the document.
+func (cb *CSSBuilder) needsPageCount() bool {
+	for _, pg := range cb.css.Pages {
+		for _, area := range pg.PageArea {
+			if strings.Contains(strings.ReplaceAll(area["content"], " ", ""), "(pages") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package cssbuilder
+
+import (
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/csshtml"
+)
+
+func TestPageResolver(t *testing.T) {
+	ps := &pageState{
+		number: 4,
+		assignments: []csshtml.StringSet{
+			{Name: "chapter", Value: `"Two"`},
+			{Name: "chapter", Value: `"Three (p. " counter( page ) ")"`},
+		},
+		entryStrings: map[string]string{"chapter": "One", "title": "Book"},
+	}
+	testdata := []struct {
+		content string
+		total   int
+		want    string
+	}{
+		{`"Page " counter( page ) " of " counter( pages )`, 0, `"Page 4 of " counter( pages )`},
+		{`"Page " counter( page ) " of " counter( pages )`, 9, `"Page 4 of 9"`},
+		{`string( chapter )`, 0, `"Two"`},
+		{`string( chapter , start )`, 0, `"One"`},
+		{`string( chapter , last )`, 0, `"Three (p. 4)"`},
+		{`string( chapter , first-except )`, 0, `""`},
+		{`string( title , first-except )`, 0, `"Book"`},
+		{`string( unknown )`, 0, `""`},
+	}
+	for _, td := range testdata {
+		if got := csshtml.ResolveContent(td.content, pageResolver{ps: ps, total: td.total}); got != td.want {
+			t.Errorf("ResolveContent(%s) = %s, want %s", td.content, got, td.want)
+		}
+	}
+}
+
+func TestCollectDestinations(t *testing.T) {
+	dest := node.NewStartStop()
+	dest.Action = node.ActionDest
+	dest.Value = "intro"
+	outline := node.NewStartStop()
+	outline.Action = node.ActionDest
+	outline.Value = 1
+	hl := node.Hpack(node.InsertAfter(outline, outline, dest))
+	vl := node.NewVList()
+	vl.List = hl
+
+	destinations := map[string]int{"intro": 1}
+	collectDestinations(vl, 7, destinations)
+	if len(destinations) != 1 || destinations["intro"] != 7 {
+		t.Errorf("destinations = %v, want map[intro:7]", destinations)
+	}
+}
@@ -0,0 +1,76 @@
+package cssbuilder
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultTOCEntries selects the elements which appear in the table of contents
+// if no other selector is given.
+const defaultTOCEntries = "h1, h2, h3, h4, h5, h6"
+
+// SetTableOfContents enables the generation of a table of contents. The
+// contents of the elements matching the placeholder selector are replaced by
+// an entry for each element matching the entries selector (h1 to h6 if
+// entries is empty). Each entry is a div with the classes toc-entry and
+// toc-level-n (the heading level, 1 for other elements) containing a link to
+// the element. The default style sheet adds dot leaders and the page number
+// with target-counter(), so the text must be typeset with OutputPages to get
+// the page numbers right. An empty placeholder disables the table of contents.
+func (cb *CSSBuilder) SetTableOfContents(placeholder, entries string) {
+	if entries == "" {
+		entries = defaultTOCEntries
+	}
+	cb.tocPlaceholder = placeholder
+	cb.tocEntries = entries
+}
+
+// tocLevel returns the heading level of the element name or 1 if the element
+// is not a heading.
+func tocLevel(name string) int {
+	if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+		return int(name[1] - '0')
+	}
+	return 1
+}
+
+// insertTableOfContents fills the table of contents placeholder in doc.
+// Entries without an id get a generated one, so the entries can link to them.
+func (cb *CSSBuilder) insertTableOfContents(doc *goquery.Document) {
+	if cb.tocPlaceholder == "" {
+		return
+	}
+	placeholder := doc.Find(cb.tocPlaceholder)
+	if placeholder.Length() == 0 {
+		return
+	}
+	ids := make(map[string]bool)
+	doc.Find("[id]").Each(func(i int, sel *goquery.Selection) {
+		id, _ := sel.Attr("id")
+		ids[id] = true
+	})
+	var sb strings.Builder
+	doc.Find(cb.tocEntries).Each(func(i int, sel *goquery.Selection) {
+		// the table of contents does not list itself
+		if sel.Closest(cb.tocPlaceholder).Length() > 0 {
+			return
+		}
+		id, _ := sel.Attr("id")
+		if id == "" {
+			// the generated ids must not clash with the ids in the document
+			for id == "" || ids[id] {
+				cb.tocCount++
+				id = fmt.Sprintf("toc-%d", cb.tocCount)
+			}
+			ids[id] = true
+			sel.SetAttr("id", id)
+		}
+		title := strings.Join(strings.Fields(sel.Text()), " ")
+		fmt.Fprintf(&sb, `<div class="toc-entry toc-level-%d"><a href="#%s">%s</a></div>`,
+			tocLevel(goquery.NodeName(sel)), html.EscapeString(id), html.EscapeString(title))
+	})
+	placeholder.SetHtml(sb.String())
+}
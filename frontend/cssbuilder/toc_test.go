@@ -0,0 +1,37 @@
+package cssbuilder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestInsertTableOfContents(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>
+	<nav id="toc"><h2>Contents</h2></nav>
+	<h1>One &amp; two</h1><p id="toc-2">text</p><h2>Two</h2><h3 id="sub">Sub  section</h3></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := &CSSBuilder{}
+	cb.SetTableOfContents("#toc", "")
+	cb.insertTableOfContents(doc)
+	if id, _ := doc.Find("h1").Attr("id"); id != "toc-1" {
+		t.Errorf("generated id = %q, want toc-1", id)
+	}
+	// toc-2 is already used by the paragraph
+	if id, _ := doc.Find("body > h2").Attr("id"); id != "toc-3" {
+		t.Errorf("generated id = %q, want toc-3", id)
+	}
+	got, err := doc.Find("#toc").Html()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<div class="toc-entry toc-level-1"><a href="#toc-1">One &amp; two</a></div>` +
+		`<div class="toc-entry toc-level-2"><a href="#toc-3">Two</a></div>` +
+		`<div class="toc-entry toc-level-3"><a href="#sub">Sub section</a></div>`
+	if got != want {
+		t.Errorf("table of contents = %s, want %s", got, want)
+	}
+}
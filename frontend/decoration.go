@@ -2,84 +2,155 @@ package frontend
 
 import (
 	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/color"
 	"github.com/speedata/boxesandglue/backend/node"
 	"github.com/speedata/boxesandglue/frontend/pdfdraw"
 )
 
-type styles struct {
-	isUnderline bool
-	ulpos       bag.ScaledPoint
-	linewidth   bag.ScaledPoint
+// textDecoration is stored in the start node of a decorated text run.
+type textDecoration struct {
+	Line            TextDecorationLine
+	Style           TextDecorationStyle
+	Color           *color.Color
+	Thickness       bag.ScaledPoint
+	UnderlineOffset bag.ScaledPoint
+	Fontsize        bag.ScaledPoint
 }
 
-func doUnderline(head, start, stop node.Node, st *styles) node.Node {
-	pdfUL := pdfdraw.NewStandalone().LineWidth(st.linewidth).Moveto(0, st.ulpos).Lineto(node.Dimensions(start, stop, node.Horizontal), st.ulpos).Stroke().String()
+// positions returns the vertical positions of the decoration lines relative
+// to the base line.
+func (td *textDecoration) positions() []bag.ScaledPoint {
+	var ret []bag.ScaledPoint
+	if td.Line&TextDecorationUnderline != 0 {
+		ret = append(ret, -td.Fontsize/6-td.UnderlineOffset)
+	}
+	if td.Line&TextDecorationOverline != 0 {
+		ret = append(ret, td.Fontsize*3/4)
+	}
+	if td.Line&TextDecorationLineThrough != 0 {
+		ret = append(ret, td.Fontsize/4)
+	}
+	return ret
+}
+
+// pdfString returns the PDF instructions to draw the decoration lines with the
+// width wd.
+func (td *textDecoration) pdfString(wd bag.ScaledPoint) string {
+	lw := td.Thickness
+	p := pdfdraw.NewStandalone()
+	if td.Color != nil {
+		p.ColorStroking(*td.Color)
+	}
+	switch td.Style {
+	case TextDecorationStyleDotted:
+		p.LineCap(1).DashPattern([]bag.ScaledPoint{0, 2 * lw}, 0)
+	case TextDecorationStyleDashed:
+		p.DashPattern([]bag.ScaledPoint{3 * lw, 2 * lw}, 0)
+	case TextDecorationStyleDouble:
+		lw = lw * 2 / 3
+	}
+	p.LineWidth(lw)
+	for _, y := range td.positions() {
+		switch td.Style {
+		case TextDecorationStyleDouble:
+			p.Moveto(0, y+lw).Lineto(wd, y+lw)
+			p.Moveto(0, y-lw).Lineto(wd, y-lw)
+		case TextDecorationStyleWavy:
+			wavyLine(p, wd, y, lw*3/2)
+		default:
+			p.Moveto(0, y).Lineto(wd, y)
+		}
+	}
+	return p.Stroke().String()
+}
+
+// wavyLine adds a wave from (0,y) to (wd,y) to the path.
+func wavyLine(p *pdfdraw.Object, wd, y, amplitude bag.ScaledPoint) {
+	halfwave := 2 * amplitude
+	if halfwave <= 0 {
+		p.Moveto(0, y).Lineto(wd, y)
+		return
+	}
+	// control points at 4/3 of the amplitude give a peak at the amplitude
+	h := amplitude * 4 / 3
+	p.Moveto(0, y)
+	for x := bag.ScaledPoint(0); x < wd; x += halfwave {
+		x1 := x + halfwave
+		if x1 > wd {
+			x1 = wd
+		}
+		p.Curveto(x, y+h, x1, y+h, x1, y)
+		h = -h
+	}
+}
+
+// drawDecoration inserts a hidden rule before start that draws the text
+// decoration from start to stop.
+func drawDecoration(head, start, stop node.Node, td *textDecoration) node.Node {
 	r := node.NewRule()
 	r.Hide = true
-	r.Pre = pdfUL
-	head = node.InsertBefore(head, start, r)
-	return head
+	r.Pre = td.pdfString(node.Dimensions(start, stop, node.Horizontal))
+	return node.InsertBefore(head, start, r)
 }
 
-func postLinebreakHL(n node.Node, st *styles) node.Node {
-	underlineFromStart := st.isUnderline
-	var underlineStart, underlineStop node.Node
-	var head, tail node.Node
-	head = n
+// decorateList draws all text decorations in the list starting at n. The
+// decorations in open are continued from a previous line and start at the
+// beginning of this list. The returned slice contains the decorations that
+// continue after the end of the list.
+func decorateList(n node.Node, open []*node.StartStop) (node.Node, []*node.StartStop) {
+	head := n
+	// started maps the start node of a decoration to the first node in this
+	// list where the decoration is drawn.
+	started := make(map[*node.StartStop]node.Node)
+	for _, ss := range open {
+		started[ss] = nil
+	}
+	var tail node.Node
 	for e := n; e != nil; e = e.Next() {
 		tail = e
-		if hl, ok := e.(*node.HList); ok {
-			hl.List = postLinebreakHL(hl.List, st)
-		} else if vl, ok := e.(*node.VList); ok {
-			vl.List = postLinebreakHL(vl.List, st)
-		} else if ss, ok := e.(*node.StartStop); ok {
-			if val, ok := node.GetAttribute(ss, "underline"); ok {
-				if val.(bool) {
-					st.isUnderline = true
-					underlineStart = ss
-					if ulpos, ok := node.GetAttribute(ss, "underlinepos"); ok {
-						if ulpos != nil {
-							st.ulpos = ulpos.(bag.ScaledPoint)
-						}
-					}
-					if lw, ok := node.GetAttribute(ss, "underlinelw"); ok {
-						if lw != nil {
-							st.linewidth = lw.(bag.ScaledPoint)
-						}
+		switch t := e.(type) {
+		case *node.HList:
+			t.List, _ = decorateList(t.List, nil)
+		case *node.VList:
+			t.List, _ = decorateList(t.List, nil)
+		case *node.StartStop:
+			if _, ok := node.GetAttribute(t, "textdecoration"); ok {
+				open = append(open, t)
+				started[t] = t
+			} else if t.StartNode != nil {
+				for i, ss := range open {
+					if ss != t.StartNode {
+						continue
 					}
-				} else {
-					if underlineFromStart {
-						head = doUnderline(head, head, e, st)
+					td, _ := node.GetAttribute(ss, "textdecoration")
+					if from := started[ss]; from != nil {
+						head = drawDecoration(head, from, t, td.(*textDecoration))
+					} else {
+						head = drawDecoration(head, head, t, td.(*textDecoration))
 					}
-					st.isUnderline = false
-					underlineStop = ss
+					open = append(open[:i], open[i+1:]...)
+					delete(started, ss)
+					break
 				}
 			}
 		}
-		if underlineStart != nil && underlineStop != nil {
-			head = doUnderline(head, underlineStart, underlineStop, st)
-			underlineStart = nil
-			underlineStop = nil
-		}
 	}
-	if st.isUnderline {
-		if underlineStart == nil && underlineStop == nil {
-			// whole line
-			head = doUnderline(head, head, tail, st)
-		}
-		if underlineStart != nil {
-			// up to the end
-			head = doUnderline(head, underlineStart, tail, st)
+	for _, ss := range open {
+		td, _ := node.GetAttribute(ss, "textdecoration")
+		if from := started[ss]; from != nil {
+			head = drawDecoration(head, from, tail, td.(*textDecoration))
+		} else if tail != nil {
+			head = drawDecoration(head, head, tail, td.(*textDecoration))
 		}
 	}
-	return head
+	return head, open
 }
 
 func postLinebreak(vl *node.VList) *node.VList {
-	var e node.Node
-	for e = vl.List; e != nil; e = e.Next() {
+	var open []*node.StartStop
+	for e := vl.List; e != nil; e = e.Next() {
 		if hl, ok := e.(*node.HList); ok {
-			postLinebreakHL(hl, &styles{})
+			hl.List, open = decorateList(hl.List, open)
 		}
 	}
 	return vl
@@ -0,0 +1,50 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+func TestDecorationAcrossLines(t *testing.T) {
+	td := &textDecoration{
+		Line:      TextDecorationUnderline | TextDecorationLineThrough,
+		Thickness: bag.MustSp("1pt"),
+		Fontsize:  bag.MustSp("12pt"),
+	}
+	if got, want := len(td.positions()), 2; got != want {
+		t.Fatalf("len(positions) = %d, want %d", got, want)
+	}
+
+	start := node.NewStartStop()
+	node.SetAttribute(start, "textdecoration", td)
+	stop := node.NewStartStop()
+	stop.StartNode = start
+
+	// first line: start and 20pt of material
+	r1 := node.NewRule()
+	r1.Width = bag.MustSp("20pt")
+	line1 := node.InsertAfter(start, start, r1)
+	// second line: 10pt of material and the stop node
+	r2 := node.NewRule()
+	r2.Width = bag.MustSp("10pt")
+	line2 := node.InsertAfter(r2, r2, stop)
+
+	vl := node.NewVList()
+	hl1 := node.HpackTo(line1, bag.MustSp("20pt"))
+	hl2 := node.HpackTo(line2, bag.MustSp("10pt"))
+	vl.List = node.InsertAfter(hl1, hl1, hl2)
+	postLinebreak(vl)
+
+	for i, hl := range []*node.HList{hl1, hl2} {
+		r, ok := hl.List.(*node.Rule)
+		if !ok || !r.Hide || r.Pre == "" {
+			t.Errorf("line %d: expected a hidden rule with the decoration at the beginning", i+1)
+		}
+	}
+	want := "q 1 w 0 -2 m 30 -2 l 0 3 m 30 3 l S Q"
+	if got := td.pdfString(bag.MustSp("30pt")); got != want {
+		t.Errorf("pdfString() = %q, want %q", got, want)
+	}
+}
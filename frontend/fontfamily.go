@@ -65,6 +65,32 @@ func (fe *Document) LoadFace(fs *FontSource) (*pdf.Face, error) {
 	return f, nil
 }
 
+// svgFontFace returns the face for text in SVG graphics. The generic family
+// sans-serif is mapped to the font family sans.
+func (fe *Document) svgFontFace(family string, weight int, italic bool) *pdf.Face {
+	if family == "sans-serif" {
+		family = "sans"
+	}
+	ff := fe.FindFontFamily(family)
+	if ff == nil {
+		return nil
+	}
+	style := FontStyleNormal
+	if italic {
+		style = FontStyleItalic
+	}
+	fs, err := ff.GetFontSource(FontWeight(weight), style)
+	if err != nil {
+		return nil
+	}
+	face, err := fe.LoadFace(fs)
+	if err != nil {
+		bag.Logger.Error("cannot load font for SVG text", "font", fs.Name, "error", err)
+		return nil
+	}
+	return face
+}
+
 // AddDataToFontsource adds the font data to the font source.
 func (fe *Document) AddDataToFontsource(fs *FontSource, fontname string) error {
 	savedFS, ok := fe.fontlocal[fontname]
@@ -0,0 +1,225 @@
+package frontend
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+// InsertFootnote is the class of the insert nodes which carry footnotes.
+const InsertFootnote = "footnote"
+
+// A Footnote is an item of a Text. The footnote call is typeset in the text,
+// followed by an insert node (class InsertFootnote) which carries the footnote
+// body through the line breaking. The page builder collects the inserts of a
+// page and places them with BuildFootnotes at the bottom of the page.
+type Footnote struct {
+	// Number is the number of the footnote. The page builder can change it
+	// with RenumberFootnote, for example to number the footnotes per page.
+	Number int
+	// Call contains the settings (size, vertical offset, ...) of the footnote
+	// call in the text. The items are ignored.
+	Call *Text
+	// Marker contains the settings of the footnote marker in front of the
+	// body. The items are ignored.
+	Marker *Text
+	// Body is the text of the footnote.
+	Body *Text
+	// Label returns the texts of the footnote call and of the marker for the
+	// footnote number. If Label is nil, the call is the number and the marker
+	// is the number followed by a period.
+	Label func(number int) (call string, marker string)
+}
+
+// NewFootnote returns an initialized footnote with the body.
+func NewFootnote(body *Text) *Footnote {
+	return &Footnote{
+		Call:   NewText(),
+		Marker: NewText(),
+		Body:   body,
+	}
+}
+
+func (fn *Footnote) labels(number int) (string, string) {
+	if fn.Label != nil {
+		return fn.Label(number)
+	}
+	n := strconv.Itoa(number)
+	return n, n + ". "
+}
+
+// footnoteInsert is the value of a footnote insert node.
+type footnoteInsert struct {
+	footnote *Footnote
+	number   int
+	// call is the hlist with the footnote call in the text.
+	call *node.HList
+	// settings are the settings of the text around the call.
+	settings TypesettingSettings
+}
+
+// buildFootnoteCall returns the footnote call for the number.
+func (fe *Document) buildFootnoteCall(fi *footnoteInsert) (*node.HList, error) {
+	call, _ := fi.footnote.labels(fi.number)
+	te := NewText()
+	for k, v := range fi.settings {
+		te.Settings[k] = v
+	}
+	for k, v := range fi.footnote.Call.Settings {
+		te.Settings[k] = v
+	}
+	te.Items = append(te.Items, call)
+	head, _, err := fe.Mknodes(te)
+	if err != nil {
+		return nil, err
+	}
+	hl := node.Hpack(head)
+	hl.Attributes = node.H{"origin": "footnote call"}
+	return hl, nil
+}
+
+// footnoteNodes returns the footnote call and the insert node for the
+// footnote.
+func (fe *Document) footnoteNodes(fn *Footnote, settings TypesettingSettings) (*node.HList, *node.Insert, error) {
+	fi := &footnoteInsert{
+		footnote: fn,
+		number:   fn.Number,
+		settings: settings,
+	}
+	var err error
+	if fi.call, err = fe.buildFootnoteCall(fi); err != nil {
+		return nil, nil, err
+	}
+	ins := node.NewInsert()
+	ins.Class = InsertFootnote
+	ins.Value = fi
+	return fi.call, ins, nil
+}
+
+func footnoteValue(ins *node.Insert) (*footnoteInsert, error) {
+	fi, ok := ins.Value.(*footnoteInsert)
+	if !ok {
+		return nil, fmt.Errorf("insert %d is not a footnote", ins.ID)
+	}
+	return fi, nil
+}
+
+// FootnoteNumber returns the number of the footnote in the insert node.
+func FootnoteNumber(ins *node.Insert) int {
+	if fi, err := footnoteValue(ins); err == nil {
+		return fi.number
+	}
+	return 0
+}
+
+// RenumberFootnote changes the number of the footnote in the insert node. The
+// footnote call in the text is rebuilt, the line containing the call is not
+// broken again.
+func (fe *Document) RenumberFootnote(ins *node.Insert, number int) error {
+	fi, err := footnoteValue(ins)
+	if err != nil {
+		return err
+	}
+	if fi.number == number {
+		return nil
+	}
+	fi.number = number
+	hl, err := fe.buildFootnoteCall(fi)
+	if err != nil {
+		return err
+	}
+	fi.call.List = hl.List
+	fi.call.Width = hl.Width
+	ins.List = nil
+	return nil
+}
+
+// FootnoteSeparator describes the space and the rule between the main text and
+// the footnotes.
+type FootnoteSeparator struct {
+	// Skip is the space above the rule.
+	Skip bag.ScaledPoint
+	// RuleWidth and RuleThickness are the dimensions of the rule.
+	RuleWidth     bag.ScaledPoint
+	RuleThickness bag.ScaledPoint
+	// Gap is the space between the rule and the first footnote and between
+	// the footnotes.
+	Gap bag.ScaledPoint
+}
+
+// DefaultFootnoteSeparator returns the separator for an area with the given
+// width: a rule of a third of the width.
+func DefaultFootnoteSeparator(width bag.ScaledPoint) *FootnoteSeparator {
+	return &FootnoteSeparator{
+		Skip:          bag.MustSp("12pt"),
+		RuleWidth:     width / 3,
+		RuleThickness: bag.MustSp("0.4pt"),
+		Gap:           bag.MustSp("3pt"),
+	}
+}
+
+// footnoteBody formats the marker and the body of the footnote.
+func (fe *Document) footnoteBody(fi *footnoteInsert, width bag.ScaledPoint) (*node.VList, error) {
+	fn := fi.footnote
+	_, marker := fn.labels(fi.number)
+	mt := NewText()
+	for k, v := range fn.Marker.Settings {
+		mt.Settings[k] = v
+	}
+	mt.Items = append(mt.Items, marker)
+	te := NewText()
+	for k, v := range fn.Body.Settings {
+		te.Settings[k] = v
+	}
+	te.Items = append([]any{mt}, fn.Body.Items...)
+	vl, _, err := fe.FormatParagraph(te, width)
+	return vl, err
+}
+
+// BuildFootnotes returns the footnote area with the footnotes in the insert
+// nodes, formatted to the width. The area starts with the separator, if sep
+// is nil, the default separator is used.
+func (fe *Document) BuildFootnotes(inserts []*node.Insert, width bag.ScaledPoint, sep *FootnoteSeparator) (*node.VList, error) {
+	if sep == nil {
+		sep = DefaultFootnoteSeparator(width)
+	}
+	var head, tail node.Node
+	appendNode := func(n node.Node) {
+		head = node.InsertAfter(head, tail, n)
+		tail = n
+	}
+	skip := node.NewGlue()
+	skip.Width = sep.Skip
+	appendNode(skip)
+	rule := node.NewRule()
+	rule.Width = sep.RuleWidth
+	rule.Height = sep.RuleThickness
+	rule.Attributes = node.H{"origin": "footnote separator"}
+	appendNode(rule)
+	for _, ins := range inserts {
+		if ins.List == nil {
+			fi, err := footnoteValue(ins)
+			if err != nil {
+				return nil, err
+			}
+			if ins.List, err = fe.footnoteBody(fi, width); err != nil {
+				return nil, err
+			}
+		}
+		gap := node.NewGlue()
+		gap.Width = sep.Gap
+		appendNode(gap)
+		// the insert keeps the formatted body, so the area gets a new vlist
+		// with the same contents
+		body := node.NewVList()
+		body.List = ins.List.List
+		body.Width, body.Height, body.Depth = ins.List.Width, ins.List.Height, ins.List.Depth
+		appendNode(body)
+	}
+	vl := node.Vpack(head)
+	vl.Width = width
+	vl.Attributes = node.H{"origin": "footnotes"}
+	return vl, nil
+}
@@ -0,0 +1,60 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+func TestFootnoteLabels(t *testing.T) {
+	fn := NewFootnote(NewText())
+	if call, marker := fn.labels(3); call != "3" || marker != "3. " {
+		t.Errorf("labels(3) = %q, %q, want \"3\", \"3. \"", call, marker)
+	}
+	fn.Label = func(n int) (string, string) { return "*", "* " }
+	if call, marker := fn.labels(3); call != "*" || marker != "* " {
+		t.Errorf("labels(3) = %q, %q, want \"*\", \"* \"", call, marker)
+	}
+}
+
+func TestBuildFootnotes(t *testing.T) {
+	fe := &Document{}
+	var inserts []*node.Insert
+	for i := 1; i <= 2; i++ {
+		ins := node.NewInsert()
+		ins.Class = InsertFootnote
+		ins.Value = &footnoteInsert{footnote: NewFootnote(NewText()), number: i}
+		// a formatted body of 10pt
+		r := node.NewRule()
+		r.Height = bag.MustSp("10pt")
+		ins.List = node.Vpack(r)
+		inserts = append(inserts, ins)
+	}
+	if got := FootnoteNumber(inserts[1]); got != 2 {
+		t.Errorf("FootnoteNumber() = %d, want 2", got)
+	}
+	sep := &FootnoteSeparator{
+		Skip:          bag.MustSp("12pt"),
+		RuleWidth:     bag.MustSp("50pt"),
+		RuleThickness: bag.MustSp("1pt"),
+		Gap:           bag.MustSp("3pt"),
+	}
+	vl, err := fe.BuildFootnotes(inserts, bag.MustSp("150pt"), sep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := vl.Height+vl.Depth, bag.MustSp("39pt"); got != want {
+		t.Errorf("footnote area height = %s, want %s", got, want)
+	}
+	if got, want := vl.Width, bag.MustSp("150pt"); got != want {
+		t.Errorf("footnote area width = %s, want %s", got, want)
+	}
+	// the area can be built again for a different page
+	if vl, err = fe.BuildFootnotes(inserts[1:], bag.MustSp("150pt"), sep); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := vl.Height+vl.Depth, bag.MustSp("26pt"); got != want {
+		t.Errorf("footnote area height = %s, want %s", got, want)
+	}
+}
@@ -48,6 +48,7 @@ func New(filename string) (*Document, error) {
 	if err = fe.RegisterCallback(CallbackPostLinebreak, PostLinebreakCallbackFunc(postLinebreak)); err != nil {
 		return nil, err
 	}
+	fe.Doc.RegisterCallback(document.CallbackSVGFont, fe.svgFontFace)
 	fe.Doc.Filename = filename
 	return fe, nil
 }
@@ -87,6 +88,19 @@ const (
 	HangingPunctuationAllowEnd = 1
 )
 
+// Direction is the base direction of a paragraph.
+type Direction int
+
+const (
+	// DirectionDefault takes the direction of the first strong character in
+	// the paragraph (left to right if there is none).
+	DirectionDefault Direction = iota
+	// DirectionLTR sets the paragraph direction to left to right.
+	DirectionLTR
+	// DirectionRTL sets the paragraph direction to right to left.
+	DirectionRTL
+)
+
 // HorizontalAlignment is the horizontal alignment.
 type HorizontalAlignment int
 
@@ -104,6 +118,12 @@ const (
 	HAlignCenter
 	// HAlignJustified makes text left and right aligned.
 	HAlignJustified
+	// HAlignStart aligns the text at the start of the line, which is the
+	// left side in left to right paragraphs and the right side in right to
+	// left paragraphs.
+	HAlignStart
+	// HAlignEnd aligns the text at the end of the line.
+	HAlignEnd
 )
 const (
 	// VAlignDefault is an undefined vertical alignment.
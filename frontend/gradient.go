@@ -0,0 +1,386 @@
+package frontend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/color"
+	"github.com/speedata/boxesandglue/frontend/pdfdraw"
+)
+
+// GradientSize is the size of a radial gradient (the position of the last
+// color stop).
+type GradientSize int
+
+const (
+	// GradientSizeFarthestCorner ends the gradient at the farthest corner of
+	// the box.
+	GradientSizeFarthestCorner GradientSize = iota
+	// GradientSizeClosestCorner ends the gradient at the closest corner.
+	GradientSizeClosestCorner
+	// GradientSizeFarthestSide ends the gradient at the farthest side.
+	GradientSizeFarthestSide
+	// GradientSizeClosestSide ends the gradient at the closest side.
+	GradientSizeClosestSide
+)
+
+func (gs GradientSize) String() string {
+	switch gs {
+	case GradientSizeFarthestCorner:
+		return "farthest-corner"
+	case GradientSizeClosestCorner:
+		return "closest-corner"
+	case GradientSizeFarthestSide:
+		return "farthest-side"
+	case GradientSizeClosestSide:
+		return "closest-side"
+	}
+	return "???"
+}
+
+// Gradient is a linear or a radial CSS gradient used as a background image.
+type Gradient struct {
+	Colors *color.Gradient
+	// Radial is true for a radial gradient and false for a linear gradient.
+	Radial bool
+	// Angle is the direction of a linear gradient in degrees (0 is to top, 90
+	// is to right).
+	Angle float64
+	// Corner is set for linear gradients to a corner ("to top right") such
+	// as "top right". The angle then depends on the dimensions of the box.
+	Corner string
+	// Circle is true for a circle and false for an ellipse (radial only).
+	Circle bool
+	// Size determines the radii of a radial gradient.
+	Size GradientSize
+	// PositionX and PositionY are the center of a radial gradient relative
+	// to the width and the height of the box (0 to 1). The offsets are added.
+	PositionX float64
+	PositionY float64
+	OffsetX   bag.ScaledPoint
+	OffsetY   bag.ScaledPoint
+}
+
+// ParseGradient interprets a CSS linear-gradient() or radial-gradient()
+// value.
+func (d *Document) ParseGradient(s string) (*Gradient, error) {
+	s = strings.TrimSpace(s)
+	open := strings.IndexByte(s, '(')
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("invalid gradient %q", s)
+	}
+	args := splitTopLevel(s[open+1:len(s)-1], ',')
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid gradient %q", s)
+	}
+	g := &Gradient{Angle: 180, PositionX: 0.5, PositionY: 0.5}
+	var err error
+	switch strings.TrimSpace(s[:open]) {
+	case "linear-gradient":
+		if g.parseLinearDirection(args[0]) {
+			args = args[1:]
+		}
+	case "radial-gradient":
+		g.Radial = true
+		var ok bool
+		if ok, err = g.parseRadialShape(args[0]); err != nil {
+			return nil, fmt.Errorf("gradient %q: %w", s, err)
+		}
+		if ok {
+			args = args[1:]
+		}
+	default:
+		return nil, fmt.Errorf("unknown gradient %q", s)
+	}
+	if g.Colors, err = d.parseColorStops(args); err != nil {
+		return nil, fmt.Errorf("gradient %q: %w", s, err)
+	}
+	return g, nil
+}
+
+// parseLinearDirection interprets the first argument of linear-gradient()
+// and returns false if the argument is not a direction.
+func (g *Gradient) parseLinearDirection(arg string) bool {
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(arg, "to ") {
+		var vertical, horizontal string
+		for _, side := range strings.Fields(arg[3:]) {
+			switch side {
+			case "top", "bottom":
+				vertical = side
+			case "left", "right":
+				horizontal = side
+			}
+		}
+		switch {
+		case vertical != "" && horizontal != "":
+			g.Corner = vertical + " " + horizontal
+		case vertical == "top":
+			g.Angle = 0
+		case vertical == "bottom":
+			g.Angle = 180
+		case horizontal == "left":
+			g.Angle = 270
+		case horizontal == "right":
+			g.Angle = 90
+		}
+		return true
+	}
+	if a, err := parseAngle(arg); err == nil {
+		g.Angle = a
+		return true
+	}
+	return false
+}
+
+// parseRadialShape interprets the first argument of radial-gradient() and
+// returns false if the argument does not describe the shape.
+func (g *Gradient) parseRadialShape(arg string) (bool, error) {
+	fields := strings.Fields(arg)
+	isShape := false
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "circle":
+			g.Circle = true
+		case "ellipse":
+			g.Circle = false
+		case "farthest-corner":
+			g.Size = GradientSizeFarthestCorner
+		case "closest-corner":
+			g.Size = GradientSizeClosestCorner
+		case "farthest-side":
+			g.Size = GradientSizeFarthestSide
+		case "closest-side":
+			g.Size = GradientSizeClosestSide
+		case "at":
+			pos, err := parseBackgroundPosition(fields[i+1:])
+			if err != nil {
+				return false, err
+			}
+			g.PositionX, g.PositionY = pos.x, pos.y
+			g.OffsetX, g.OffsetY = pos.offsetX, pos.offsetY
+			return true, nil
+		default:
+			if isShape {
+				return false, fmt.Errorf("unknown radial gradient shape %q", fields[i])
+			}
+			return false, nil
+		}
+		isShape = true
+	}
+	return isShape, nil
+}
+
+// backgroundPosition is a position in an area. x and y are relative to the
+// free space (0 is left/top, 1 is right/bottom), the offsets are added.
+type backgroundPosition struct {
+	x, y             float64
+	offsetX, offsetY bag.ScaledPoint
+}
+
+// parseBackgroundPosition interprets a position such as "left top", "25% 75%"
+// or "1cm 2cm".
+func parseBackgroundPosition(fields []string) (backgroundPosition, error) {
+	pos := backgroundPosition{x: 0.5, y: 0.5}
+	if len(fields) > 2 {
+		return pos, fmt.Errorf("unsupported position %q", strings.Join(fields, " "))
+	}
+	for i, f := range fields {
+		// the first value is the horizontal position unless it is a vertical
+		// keyword
+		horizontal := i == 0
+		switch f {
+		case "left":
+			pos.x = 0
+		case "right":
+			pos.x = 1
+		case "top":
+			pos.y = 0
+		case "bottom":
+			pos.y = 1
+		case "center":
+			// default
+		default:
+			var rel float64
+			var offset bag.ScaledPoint
+			if strings.HasSuffix(f, "%") {
+				p, err := strconv.ParseFloat(strings.TrimSuffix(f, "%"), 64)
+				if err != nil {
+					return pos, fmt.Errorf("invalid position %q", f)
+				}
+				rel = p / 100
+			} else {
+				sp, err := bag.Sp(f)
+				if err != nil {
+					return pos, fmt.Errorf("invalid position %q", f)
+				}
+				offset = sp
+			}
+			if horizontal {
+				pos.x, pos.offsetX = rel, offset
+			} else {
+				pos.y, pos.offsetY = rel, offset
+			}
+		}
+	}
+	return pos, nil
+}
+
+// parseColorStops interprets the color stops of a gradient (color and
+// optional percentage). Missing positions are distributed evenly.
+func (d *Document) parseColorStops(args []string) (*color.Gradient, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("at least two colors expected")
+	}
+	g := &color.Gradient{}
+	offsets := make([]float64, len(args))
+	for i, arg := range args {
+		arg = strings.TrimSpace(arg)
+		offsets[i] = math.NaN()
+		if pos := strings.LastIndexByte(arg, ' '); pos > 0 && strings.HasSuffix(arg, "%") && !strings.HasSuffix(arg, ")") {
+			p, err := strconv.ParseFloat(strings.TrimSuffix(arg[pos+1:], "%"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid color stop %q", arg)
+			}
+			offsets[i] = p / 100
+			arg = strings.TrimSpace(arg[:pos])
+		}
+		col, err := d.ParseColor(arg)
+		if err != nil {
+			return nil, err
+		}
+		g.Stops = append(g.Stops, color.GradientStop{Color: *col})
+	}
+	if math.IsNaN(offsets[0]) {
+		offsets[0] = 0
+	}
+	if last := len(offsets) - 1; math.IsNaN(offsets[last]) {
+		offsets[last] = 1
+	}
+	for i := 1; i < len(offsets); i++ {
+		if math.IsNaN(offsets[i]) {
+			// find the next stop with a position
+			j := i + 1
+			for math.IsNaN(offsets[j]) {
+				j++
+			}
+			step := (offsets[j] - offsets[i-1]) / float64(j-i+1)
+			for k := i; k < j; k++ {
+				offsets[k] = offsets[k-1] + step
+			}
+		}
+		// positions must not decrease
+		offsets[i] = math.Max(offsets[i], offsets[i-1])
+	}
+	for i := range g.Stops {
+		g.Stops[i].Offset = offsets[i]
+	}
+	if _, err := g.Space(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// parseAngle interprets a CSS angle (deg, grad, rad, turn) and returns the
+// angle in degrees.
+func parseAngle(s string) (float64, error) {
+	for _, unit := range []struct {
+		suffix string
+		factor float64
+	}{{"deg", 1}, {"grad", 0.9}, {"rad", 180 / math.Pi}, {"turn", 360}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			a, err := strconv.ParseFloat(strings.TrimSuffix(s, unit.suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid angle %q", s)
+			}
+			return a * unit.factor, nil
+		}
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	return 0, fmt.Errorf("invalid angle %q", s)
+}
+
+// splitTopLevel splits s at each sep that is not enclosed in parentheses.
+func splitTopLevel(s string, sep byte) []string {
+	var ret []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				ret = append(ret, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		ret = append(ret, s[start:])
+	}
+	return ret
+}
+
+// Draw fills the rectangle with the top left corner at (x, y) with the
+// gradient. The shading is registered with shadings.
+func (g *Gradient) Draw(shadings pdfdraw.Shadings, p *pdfdraw.Object, x, y, wd, ht bag.ScaledPoint) {
+	w, h := wd.ToPT(), ht.ToPT()
+	if !g.Radial {
+		angle := g.Angle
+		if g.Corner != "" {
+			// the gradient line is perpendicular to the diagonal through the
+			// other two corners
+			a := math.Atan2(h, w) * 180 / math.Pi
+			switch g.Corner {
+			case "top right":
+				angle = a
+			case "bottom right":
+				angle = 180 - a
+			case "bottom left":
+				angle = 180 + a
+			case "top left":
+				angle = 360 - a
+			}
+		}
+		p.LinearGradient(shadings, g.Colors, x, y-ht, wd, ht, angle)
+		return
+	}
+	cx, cy := w*g.PositionX+g.OffsetX.ToPT(), h*g.PositionY+g.OffsetY.ToPT()
+	left, right, top, bottom := cx, w-cx, cy, h-cy
+	var rx, ry float64
+	switch g.Size {
+	case GradientSizeClosestSide:
+		rx, ry = math.Min(left, right), math.Min(top, bottom)
+	case GradientSizeFarthestSide:
+		rx, ry = math.Max(left, right), math.Max(top, bottom)
+	case GradientSizeClosestCorner:
+		rx, ry = math.Min(left, right), math.Min(top, bottom)
+	case GradientSizeFarthestCorner:
+		rx, ry = math.Max(left, right), math.Max(top, bottom)
+	}
+	if g.Circle {
+		switch g.Size {
+		case GradientSizeClosestSide:
+			rx = math.Min(rx, ry)
+		case GradientSizeFarthestSide:
+			rx = math.Max(rx, ry)
+		default:
+			rx = math.Hypot(rx, ry)
+		}
+		ry = rx
+	} else if g.Size == GradientSizeClosestCorner || g.Size == GradientSizeFarthestCorner {
+		// an ellipse with the same aspect ratio through the corner
+		rx, ry = rx*math.Sqrt2, ry*math.Sqrt2
+	}
+	p.RadialGradient(shadings, g.Colors, x, y-ht, wd, ht,
+		x+bag.ScaledPointFromFloat(cx), y-bag.ScaledPointFromFloat(cy),
+		bag.ScaledPointFromFloat(rx), bag.ScaledPointFromFloat(ry))
+}
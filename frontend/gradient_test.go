@@ -0,0 +1,107 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/frontend/pdfdraw"
+)
+
+func TestParseGradient(t *testing.T) {
+	f := initDocument()
+	g, err := f.ParseGradient("linear-gradient(to right, red, #00f 80%, white)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Radial || g.Angle != 90 {
+		t.Errorf("Angle = %v, want 90", g.Angle)
+	}
+	offsets := []float64{0, 0.8, 1}
+	if len(g.Colors.Stops) != len(offsets) {
+		t.Fatalf("len(Stops) = %d, want %d", len(g.Colors.Stops), len(offsets))
+	}
+	for i, o := range offsets {
+		if got := g.Colors.Stops[i].Offset; got != o {
+			t.Errorf("Stops[%d].Offset = %v, want %v", i, got, o)
+		}
+	}
+	col := g.Colors.ColorAt(0.4)
+	if got, want := col.PDFStringNonStroking(), "0.5 0 0.5 rg"; got != want {
+		t.Errorf("ColorAt(0.4) = %s, want %s", got, want)
+	}
+
+	g, err = f.ParseGradient("radial-gradient(circle closest-side at left 25%, cmyk(1 0 0 0), black)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.Radial || !g.Circle || g.Size != GradientSizeClosestSide || g.PositionX != 0 || g.PositionY != 0.25 {
+		t.Errorf("unexpected radial gradient %#v", g)
+	}
+	// black is converted to CMYK
+	col = g.Colors.ColorAt(1)
+	if got, want := col.PDFStringNonStroking(), "0 0 0 1 k"; got != want {
+		t.Errorf("ColorAt(1) = %s, want %s", got, want)
+	}
+
+	for _, str := range []string{"linear-gradient(red)", "conic-gradient(red, blue)", "linear-gradient(red, cmyk(0 0 0 1), blue)"} {
+		if _, err := f.ParseGradient(str); err == nil {
+			t.Errorf("ParseGradient(%q) expected an error", str)
+		}
+	}
+}
+
+// testShadings collects the shading dictionaries.
+type testShadings []string
+
+func (ts *testShadings) Shading(dict string) string {
+	*ts = append(*ts, dict)
+	return fmt.Sprintf("/Sh%d", len(*ts))
+}
+
+func TestDrawGradient(t *testing.T) {
+	f := initDocument()
+	g, err := f.ParseGradient("linear-gradient(red, blue 40%, white)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shadings testShadings
+	p := pdfdraw.New()
+	g.Draw(&shadings, p, 0, 0, bag.MustSp("100pt"), bag.MustSp("20pt"))
+	str := p.String()
+	// top to bottom
+	if !strings.Contains(str, "0 -1 1 0 50 -10 cm /Sh1 sh") {
+		t.Errorf("unexpected transformation in %q", str)
+	}
+	if !strings.HasPrefix(str, "q ") || !strings.HasSuffix(str, " Q") {
+		t.Errorf("gradient should be encapsulated in q ... Q")
+	}
+	if len(shadings) != 1 {
+		t.Fatalf("got %d shadings, want 1", len(shadings))
+	}
+	for _, want := range []string{
+		"/ShadingType 2 /ColorSpace /DeviceRGB /Coords [-10 0 10 0] /Extend [true true]",
+		"/FunctionType 3 /Domain [0 1]",
+		"/C0 [1 0 0] /C1 [0 0 1]",
+		"/C0 [0 0 1] /C1 [1 1 1]",
+		"/Bounds [0.4]",
+	} {
+		if !strings.Contains(shadings[0], want) {
+			t.Errorf("shading %q does not contain %q", shadings[0], want)
+		}
+	}
+
+	g, err = f.ParseGradient("radial-gradient(circle closest-side, cmyk(1 0 0 0), black)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = pdfdraw.New()
+	g.Draw(&shadings, p, 0, 0, bag.MustSp("100pt"), bag.MustSp("20pt"))
+	if str := p.String(); !strings.Contains(str, "1 0 0 1 50 -10 cm /Sh2 sh") {
+		t.Errorf("unexpected radial gradient %q", str)
+	}
+	if want := "/ShadingType 3 /ColorSpace /DeviceCMYK /Coords [0 0 0 0 0 10]"; len(shadings) != 2 || !strings.Contains(shadings[1], want) {
+		t.Errorf("radial shading %v does not contain %q", shadings, want)
+	}
+}
@@ -15,14 +15,80 @@ const (
 	BorderStyleNone BorderStyle = iota
 	// BorderStyleSolid is a solid line
 	BorderStyleSolid
+	// BorderStyleDotted is a series of round dots
+	BorderStyleDotted
+	// BorderStyleDashed is a series of short line segments
+	BorderStyleDashed
+	// BorderStyleDouble are two parallel lines
+	BorderStyleDouble
+	// BorderStyleGroove looks as if it were carved into the page
+	BorderStyleGroove
+	// BorderStyleRidge looks as if it were coming out of the page
+	BorderStyleRidge
+	// BorderStyleInset makes the box look embedded
+	BorderStyleInset
+	// BorderStyleOutset makes the box look embossed
+	BorderStyleOutset
 )
 
+func (bs BorderStyle) String() string {
+	switch bs {
+	case BorderStyleNone:
+		return "none"
+	case BorderStyleSolid:
+		return "solid"
+	case BorderStyleDotted:
+		return "dotted"
+	case BorderStyleDashed:
+		return "dashed"
+	case BorderStyleDouble:
+		return "double"
+	case BorderStyleGroove:
+		return "groove"
+	case BorderStyleRidge:
+		return "ridge"
+	case BorderStyleInset:
+		return "inset"
+	case BorderStyleOutset:
+		return "outset"
+	default:
+		return "???"
+	}
+}
+
+// ParseBorderStyle returns the border style for the CSS value such as "solid"
+// or "dashed". Unknown values and "hidden" return BorderStyleNone.
+func ParseBorderStyle(s string) BorderStyle {
+	switch s {
+	case "solid":
+		return BorderStyleSolid
+	case "dotted":
+		return BorderStyleDotted
+	case "dashed":
+		return BorderStyleDashed
+	case "double":
+		return BorderStyleDouble
+	case "groove":
+		return BorderStyleGroove
+	case "ridge":
+		return BorderStyleRidge
+	case "inset":
+		return BorderStyleInset
+	case "outset":
+		return BorderStyleOutset
+	default:
+		return BorderStyleNone
+	}
+}
+
 // HTMLProperties contains css values
 type HTMLProperties map[string]string
 
 // HTMLValues contains margin, padding and border values for a rectangular area.
 type HTMLValues struct {
 	BackgroundColor         *color.Color
+	BackgroundGradient      *Gradient
+	BackgroundImage         *BackgroundImage
 	BorderTopWidth          bag.ScaledPoint
 	BorderRightWidth        bag.ScaledPoint
 	BorderBottomWidth       bag.ScaledPoint
@@ -56,11 +122,32 @@ func (hv HTMLValues) hasBorder() bool {
 		hv.BorderRightWidth > 0 && hv.BorderRightStyle != BorderStyleNone
 }
 
+// SetOpacity multiplies the alpha values of the background and the border
+// colors with opacity. Borders without a color are black.
+func (hv *HTMLValues) SetOpacity(opacity float64) {
+	if opacity >= 1 {
+		return
+	}
+	hv.BackgroundColor = hv.BackgroundColor.WithOpacity(opacity)
+	for _, col := range []**color.Color{&hv.BorderTopColor, &hv.BorderRightColor, &hv.BorderBottomColor, &hv.BorderLeftColor} {
+		if *col == nil {
+			*col = &color.Color{Space: color.ColorGray}
+		}
+		*col = (*col).WithOpacity(opacity)
+	}
+}
+
 func SettingsToValues(s TypesettingSettings) HTMLValues {
 	hv := HTMLValues{}
 	if c, ok := s[SettingBackgroundColor]; ok {
 		hv.BackgroundColor = c.(*color.Color)
 	}
+	if g, ok := s[SettingBackgroundGradient]; ok {
+		hv.BackgroundGradient = g.(*Gradient)
+	}
+	if bi, ok := s[SettingBackgroundImage]; ok {
+		hv.BackgroundImage = bi.(*BackgroundImage)
+	}
 	if bw, ok := s[SettingBorderTopWidth]; ok {
 		hv.BorderTopWidth = bw.(bag.ScaledPoint)
 	}
@@ -140,6 +227,9 @@ func SettingsToValues(s TypesettingSettings) HTMLValues {
 	if sty, ok := s[SettingBorderBottomStyle]; ok {
 		hv.BorderBottomStyle = sty.(BorderStyle)
 	}
+	if op, ok := s[SettingOpacity]; ok {
+		hv.SetOpacity(op.(float64))
+	}
 	return hv
 }
 
@@ -150,6 +240,12 @@ func (d *Document) CSSPropertiesToValues(p HTMLProperties) HTMLValues {
 		switch k {
 		case "background-color":
 			hv.BackgroundColor = d.GetColor(v)
+		case "background-image":
+			if g, err := d.ParseGradient(v); err == nil {
+				hv.BackgroundGradient = g
+			} else {
+				bag.Logger.Error(err.Error())
+			}
 		case "border-top-width":
 			hv.BorderTopWidth = bag.MustSp(v)
 		case "border-right-width":
@@ -175,13 +271,7 @@ func (d *Document) CSSPropertiesToValues(p HTMLProperties) HTMLValues {
 		case "border-left-color":
 			hv.BorderLeftColor = d.GetColor(v)
 		case "border-top-style", "border-right-style", "border-bottom-style", "border-left-style":
-			var sty BorderStyle
-			switch v {
-			case "none":
-				sty = BorderStyleNone
-			default:
-				sty = BorderStyleSolid
-			}
+			sty := ParseBorderStyle(v)
 			switch k {
 			case "border-top-style":
 				hv.BorderTopStyle = sty
@@ -294,6 +384,103 @@ func getBorderPaths(x0, y0, x1, y1, x2, y2, x3, y3 bag.ScaledPoint, hv HTMLValue
 	return
 }
 
+// borderBand returns the inner and the outer path of a ring within the border.
+// from and to are the fractions of the border widths measured from the outer
+// edge, so borderBand(..., 0, 1) is the whole border.
+func borderBand(x0, y0, x3, y3 bag.ScaledPoint, hv HTMLValues, from, to float64) (inner, outer *pdfdraw.Object) {
+	l := bag.MultiplyFloat(hv.BorderLeftWidth, from)
+	r := bag.MultiplyFloat(hv.BorderRightWidth, from)
+	t := bag.MultiplyFloat(hv.BorderTopWidth, from)
+	b := bag.MultiplyFloat(hv.BorderBottomWidth, from)
+	band := hv
+	band.BorderLeftWidth = bag.MultiplyFloat(hv.BorderLeftWidth, to-from)
+	band.BorderRightWidth = bag.MultiplyFloat(hv.BorderRightWidth, to-from)
+	band.BorderTopWidth = bag.MultiplyFloat(hv.BorderTopWidth, to-from)
+	band.BorderBottomWidth = bag.MultiplyFloat(hv.BorderBottomWidth, to-from)
+	band.BorderTopLeftRadius = bag.Max(0, hv.BorderTopLeftRadius-l)
+	band.BorderTopRightRadius = bag.Max(0, hv.BorderTopRightRadius-r)
+	band.BorderBottomLeftRadius = bag.Max(0, hv.BorderBottomLeftRadius-l)
+	band.BorderBottomRightRadius = bag.Max(0, hv.BorderBottomRightRadius-r)
+	x0, y0, x3, y3 = x0+l, y0-t, x3-r, y3+b
+	return getBorderPaths(x0, y0, x0, y0, x3, y3, x3, y3, band)
+}
+
+// borderShades returns the colors for the outer and the inner half of a border
+// side. Top and left borders of 3D styles are the dark ones for groove and
+// inset.
+func borderShades(sty BorderStyle, col color.Color, topLeft bool) (outer, inner color.Color) {
+	dark, light := shadeColor(col, -0.5), shadeColor(col, 0.5)
+	if !topLeft {
+		dark, light = light, dark
+	}
+	switch sty {
+	case BorderStyleGroove:
+		return dark, light
+	case BorderStyleRidge:
+		return light, dark
+	case BorderStyleInset:
+		return dark, dark
+	case BorderStyleOutset:
+		return light, light
+	}
+	return col, col
+}
+
+// dashPattern sets the dash pattern for dotted and dashed lines with the line
+// width wd.
+func dashPattern(p *pdfdraw.Object, sty BorderStyle, wd bag.ScaledPoint) {
+	switch sty {
+	case BorderStyleDotted:
+		p.LineCap(1).DashPattern([]bag.ScaledPoint{0, 2 * wd}, 0)
+	case BorderStyleDashed:
+		p.DashPattern([]bag.ScaledPoint{3 * wd, 3 * wd}, 0)
+	}
+}
+
+// borderRect returns the PDF instructions to draw a straight border of the
+// style sty into the rectangle at x, y with the width wd and the height ht. If
+// horizontal is true, the border is a top or bottom border, otherwise a left or
+// right border. Table cells use this for their border rules.
+func borderRect(sty BorderStyle, col color.Color, x, y, wd, ht bag.ScaledPoint, horizontal, topLeft bool) string {
+	p := pdfdraw.NewStandalone()
+	// split returns the rectangle between the fractions from and to of the
+	// border width, measured from the outer edge.
+	split := func(from, to float64) (bag.ScaledPoint, bag.ScaledPoint, bag.ScaledPoint, bag.ScaledPoint) {
+		if horizontal {
+			if topLeft {
+				return x, y + ht - bag.MultiplyFloat(ht, to), wd, bag.MultiplyFloat(ht, to-from)
+			}
+			return x, y + bag.MultiplyFloat(ht, from), wd, bag.MultiplyFloat(ht, to-from)
+		}
+		if topLeft {
+			return x + bag.MultiplyFloat(wd, from), y, bag.MultiplyFloat(wd, to-from), ht
+		}
+		return x + wd - bag.MultiplyFloat(wd, to), y, bag.MultiplyFloat(wd, to-from), ht
+	}
+	switch sty {
+	case BorderStyleDotted, BorderStyleDashed:
+		p.Rect(x, y, wd, ht).Clip().Endpath()
+		p.ColorStroking(col)
+		if horizontal {
+			dashPattern(p, sty, ht)
+			p.LineWidth(ht).Moveto(x, y+ht/2).Lineto(x+wd, y+ht/2).Stroke()
+		} else {
+			dashPattern(p, sty, wd)
+			p.LineWidth(wd).Moveto(x+wd/2, y+ht).Lineto(x+wd/2, y).Stroke()
+		}
+	case BorderStyleDouble:
+		p.ColorNonstroking(col)
+		p.Rect(split(0, 1.0/3)).Rect(split(2.0/3, 1)).Fill()
+	case BorderStyleGroove, BorderStyleRidge, BorderStyleInset, BorderStyleOutset:
+		outer, inner := borderShades(sty, col, topLeft)
+		p.ColorNonstroking(outer).Rect(split(0, 0.5)).Fill()
+		p.ColorNonstroking(inner).Rect(split(0.5, 1)).Fill()
+	default:
+		p.ColorNonstroking(col).Rect(x, y, wd, ht).Fill()
+	}
+	return p.String()
+}
+
 // HTMLBorder returns two string with a HTML border. The first string is part of
 // a prefix for a possible background string and the second string renders the
 // border.
@@ -360,17 +547,17 @@ func (d *Document) HTMLBorder(vl *node.VList, hv HTMLValues) *node.VList {
 	ybg3 := ybg0 - height - hv.PaddingTop - hv.BorderTopWidth - hv.PaddingBottom - hv.BorderBottomWidth
 	ybg2 := ybg3 + maxTrapezoidThickness
 
-	if hv.BackgroundColor != nil && hv.BackgroundColor.Space != color.ColorNone {
-		// this is the rule node for the background
-		rbg := node.NewRule()
-		rbg.Hide = true
-		var innerBG *pdfdraw.Object
-		innerBG, _ = getBorderPaths(xbg0, ybg0, xbg1, ybg1, xbg2, ybg2, xbg3, ybg3, hv)
-		innerBG.Clip().Endpath()
-		innerBG.ColorNonstroking(*hv.BackgroundColor).Rect(xbg0, ybg3, xbg3-xbg0, ybg0-ybg3).Fill()
-		rbg.Pre = "q " + innerBG.String() + " Q"
-		rbg.Attributes = node.H{"origin": "html background color"}
-		vl.List = node.InsertBefore(vl.List, vl.List, rbg)
+	clip := func() *pdfdraw.Object {
+		p, _ := getBorderPaths(xbg0, ybg0, xbg1, ybg1, xbg2, ybg2, xbg3, ybg3, hv)
+		return p
+	}
+	if bg := d.backgroundNodes(hv, clip, xbg0, ybg0, xbg3-xbg0, ybg0-ybg3); bg != nil {
+		tail := node.Tail(bg)
+		tail.SetNext(vl.List)
+		if vl.List != nil {
+			vl.List.SetPrev(tail)
+		}
+		vl.List = bg
 	}
 
 	lgWd := hv.PaddingLeft + hv.BorderLeftWidth
@@ -412,10 +599,61 @@ func (d *Document) HTMLBorder(vl *node.VList, hv HTMLValues) *node.VList {
 		// inner.Stroke().Endpath()
 
 		// Draw the four trapezoids
-		inner.ColorNonstroking(*hv.BorderTopColor).Moveto(x0, y0).Lineto(x1, y1).Lineto(x2, y1).Lineto(x3, y0).Close().Fill()
-		inner.ColorNonstroking(*hv.BorderLeftColor).Moveto(x0, y3).Lineto(x1, y2).Lineto(x1, y1).Lineto(x0, y0).Close().Fill()
-		inner.ColorNonstroking(*hv.BorderBottomColor).Moveto(x0, y3).Lineto(x3, y3).Lineto(x2, y2).Lineto(x1, y2).Close().Fill()
-		inner.ColorNonstroking(*hv.BorderRightColor).Moveto(x2, y2).Lineto(x3, y3).Lineto(x3, y0).Lineto(x2, y1).Close().Fill()
+		sides := []struct {
+			sty     BorderStyle
+			col     color.Color
+			wd      bag.ScaledPoint
+			topLeft bool
+			points  [4][2]bag.ScaledPoint
+		}{
+			{hv.BorderTopStyle, *hv.BorderTopColor, hv.BorderTopWidth, true, [4][2]bag.ScaledPoint{{x0, y0}, {x1, y1}, {x2, y1}, {x3, y0}}},
+			{hv.BorderLeftStyle, *hv.BorderLeftColor, hv.BorderLeftWidth, true, [4][2]bag.ScaledPoint{{x0, y3}, {x1, y2}, {x1, y1}, {x0, y0}}},
+			{hv.BorderBottomStyle, *hv.BorderBottomColor, hv.BorderBottomWidth, false, [4][2]bag.ScaledPoint{{x0, y3}, {x3, y3}, {x2, y2}, {x1, y2}}},
+			{hv.BorderRightStyle, *hv.BorderRightColor, hv.BorderRightWidth, false, [4][2]bag.ScaledPoint{{x2, y2}, {x3, y3}, {x3, y0}, {x2, y1}}},
+		}
+		for _, side := range sides {
+			if side.wd == 0 || side.sty == BorderStyleNone {
+				continue
+			}
+			trapezoid := func() {
+				pt := side.points
+				inner.Moveto(pt[0][0], pt[0][1]).Lineto(pt[1][0], pt[1][1]).Lineto(pt[2][0], pt[2][1]).Lineto(pt[3][0], pt[3][1]).Close()
+			}
+			if side.sty == BorderStyleSolid {
+				inner.ColorNonstroking(side.col)
+				trapezoid()
+				inner.Fill()
+				continue
+			}
+			// all other styles are drawn within the trapezoid
+			inner.Save()
+			trapezoid()
+			inner.Clip().Endpath()
+			// fill clips to the band between from and to and fills the
+			// trapezoid with the color col.
+			fill := func(col color.Color, from, to float64) {
+				bandInner, bandOuter := borderBand(x0, y0, x3, y3, hv, from, to)
+				inner.Save().Literal(bandOuter.String()).Literal(bandInner.String()).Clip().Endpath()
+				inner.ColorNonstroking(col).Rect(x0, y3, x3-x0, y0-y3).Fill().Restore()
+			}
+			switch side.sty {
+			case BorderStyleDotted, BorderStyleDashed:
+				// stroke along the middle of the border, so that the round
+				// corners are dotted/dashed as well
+				_, center := borderBand(x0, y0, x3, y3, hv, 0.5, 0.5)
+				inner.ColorStroking(side.col).LineWidth(side.wd)
+				dashPattern(inner, side.sty, side.wd)
+				inner.Literal(center.Close().String()).Stroke()
+			case BorderStyleDouble:
+				fill(side.col, 0, 1.0/3)
+				fill(side.col, 2.0/3, 1)
+			default:
+				outerCol, innerCol := borderShades(side.sty, side.col, side.topLeft)
+				fill(outerCol, 0, 0.5)
+				fill(innerCol, 0.5, 1)
+			}
+			inner.Restore()
+		}
 
 		r.Pre = "q " + outer.String() + " " + inner.String() + " Q"
 		head = node.InsertAfter(head, tail, r)
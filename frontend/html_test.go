@@ -0,0 +1,30 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/color"
+)
+
+func TestBorderRect(t *testing.T) {
+	black := color.Color{Space: color.ColorGray}
+	testdata := []struct {
+		sty        BorderStyle
+		horizontal bool
+		want       string
+	}{
+		{BorderStyleSolid, true, "q 0 g 0 -3 30 3 re f Q"},
+		{BorderStyleDouble, true, "q 0 g 0 -1 30 1 re 0 -3 30 1 re f Q"},
+		{BorderStyleDashed, true, "q 0 -3 30 3 re W* n 0 G [9 9] 0 d 3 w 0 -1.5 m 30 -1.5 l S Q"},
+	}
+	for _, tc := range testdata {
+		got := borderRect(tc.sty, black, 0, -3*bag.Factor, 30*bag.Factor, 3*bag.Factor, tc.horizontal, true)
+		if got != tc.want {
+			t.Errorf("borderRect(%s) = %q, want %q", tc.sty, got, tc.want)
+		}
+	}
+	if got := ParseBorderStyle("groove"); got != BorderStyleGroove {
+		t.Errorf("ParseBorderStyle(groove) = %s, want groove", got)
+	}
+}
@@ -0,0 +1,243 @@
+package frontend
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/image"
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/frontend/pdfdraw"
+)
+
+// ObjectFit determines how an image is fitted into its box.
+type ObjectFit int
+
+const (
+	// ObjectFitFill stretches the image to the box.
+	ObjectFitFill ObjectFit = iota
+	// ObjectFitContain scales the image to fit into the box.
+	ObjectFitContain
+	// ObjectFitCover scales the image to cover the box, the rest is clipped.
+	ObjectFitCover
+	// ObjectFitNone keeps the natural size of the image.
+	ObjectFitNone
+	// ObjectFitScaleDown is ObjectFitNone or ObjectFitContain, whichever
+	// results in a smaller image.
+	ObjectFitScaleDown
+)
+
+func (of ObjectFit) String() string {
+	switch of {
+	case ObjectFitFill:
+		return "fill"
+	case ObjectFitContain:
+		return "contain"
+	case ObjectFitCover:
+		return "cover"
+	case ObjectFitNone:
+		return "none"
+	case ObjectFitScaleDown:
+		return "scale-down"
+	}
+	return "???"
+}
+
+// ParseObjectFit interprets the CSS object-fit value.
+func ParseObjectFit(s string) (ObjectFit, error) {
+	switch s {
+	case "fill":
+		return ObjectFitFill, nil
+	case "contain":
+		return ObjectFitContain, nil
+	case "cover":
+		return ObjectFitCover, nil
+	case "none":
+		return ObjectFitNone, nil
+	case "scale-down":
+		return ObjectFitScaleDown, nil
+	}
+	return ObjectFitFill, fmt.Errorf("unsupported object-fit %q", s)
+}
+
+// Image is an image in a Text. The size of the box is calculated when the
+// paragraph is formatted, so percentages can refer to the width of the
+// paragraph.
+type Image struct {
+	Img *image.Image
+	// Width and Height are "auto" (or empty), a length or a percentage of
+	// the paragraph width. If one of them is auto, the aspect ratio of the
+	// image is kept. The height of the containing block is not known, so
+	// percentages of the height are treated as auto and a warning is logged.
+	Width  string
+	Height string
+	// MinWidth, MaxWidth, MinHeight and MaxHeight limit the size of the box.
+	// Empty strings, "none" and "auto" mean no limit. Percentages of the
+	// height are ignored like in Height.
+	MinWidth  string
+	MaxWidth  string
+	MinHeight string
+	MaxHeight string
+	// ObjectFit determines the size of the image in the box.
+	ObjectFit ObjectFit
+	// PositionX and PositionY align the image in the box, 0 is left (top) and
+	// 1 is right (bottom). The offsets are added.
+	PositionX float64
+	PositionY float64
+	OffsetX   bag.ScaledPoint
+	OffsetY   bag.ScaledPoint
+}
+
+// NewImage returns an Image with the natural size of img which is centered in
+// its box.
+func NewImage(img *image.Image) *Image {
+	return &Image{
+		Img:       img,
+		PositionX: 0.5,
+		PositionY: 0.5,
+	}
+}
+
+// SetPosition sets the position from a CSS object-position value.
+func (img *Image) SetPosition(s string) error {
+	pos, err := parseBackgroundPosition(strings.Fields(s))
+	if err != nil {
+		return err
+	}
+	img.PositionX, img.PositionY = pos.x, pos.y
+	img.OffsetX, img.OffsetY = pos.offsetX, pos.offsetY
+	return nil
+}
+
+// imageLength resolves a size of the image box. Percentages are relative to
+// ref, they are ignored if ref is 0. The boolean is false for auto.
+func imageLength(s string, ref bag.ScaledPoint) (bag.ScaledPoint, bool) {
+	if s == "none" || (ref == 0 && strings.HasSuffix(s, "%")) {
+		return 0, false
+	}
+	return sizeValue(s, ref)
+}
+
+// imageHeight resolves a height of the image box. Percentages are not
+// supported and treated as auto.
+func imageHeight(property, s string) (bag.ScaledPoint, bool) {
+	if strings.HasSuffix(s, "%") {
+		bag.Logger.Warn("percentage heights of images are not supported, using auto", "property", property, "value", s)
+		return 0, false
+	}
+	return imageLength(s, 0)
+}
+
+// boxSize returns the size of the image box in a paragraph with the width
+// hsize.
+func (img *Image) boxSize(hsize bag.ScaledPoint) (bag.ScaledPoint, bag.ScaledPoint) {
+	iw, ih := img.Img.Width, img.Img.Height
+	if iw <= 0 || ih <= 0 {
+		return 0, 0
+	}
+	ratio := iw.ToPT() / ih.ToPT()
+	wd, hasWidth := imageLength(img.Width, hsize)
+	ht, hasHeight := imageHeight("height", img.Height)
+	switch {
+	case hasWidth && hasHeight:
+		// both given
+	case hasWidth:
+		ht = bag.MultiplyFloat(wd, 1/ratio)
+	case hasHeight:
+		wd = bag.MultiplyFloat(ht, ratio)
+	default:
+		wd, ht = iw, ih
+	}
+	// the limits keep the aspect ratio if a side is not given
+	if limit, ok := imageLength(img.MaxWidth, hsize); ok && wd > limit {
+		wd = limit
+		if !hasHeight {
+			ht = bag.MultiplyFloat(wd, 1/ratio)
+		}
+	}
+	if limit, ok := imageLength(img.MinWidth, hsize); ok && wd < limit {
+		wd = limit
+		if !hasHeight {
+			ht = bag.MultiplyFloat(wd, 1/ratio)
+		}
+	}
+	if limit, ok := imageHeight("max-height", img.MaxHeight); ok && ht > limit {
+		ht = limit
+		if !hasWidth {
+			wd = bag.MultiplyFloat(ht, ratio)
+		}
+	}
+	if limit, ok := imageHeight("min-height", img.MinHeight); ok && ht < limit {
+		ht = limit
+		if !hasWidth {
+			wd = bag.MultiplyFloat(ht, ratio)
+		}
+	}
+	return wd, ht
+}
+
+// imageSize returns the size of the image in a box with the dimensions wd ×
+// ht.
+func (img *Image) imageSize(wd, ht bag.ScaledPoint) (bag.ScaledPoint, bag.ScaledPoint) {
+	iw, ih := img.Img.Width, img.Img.Height
+	if img.ObjectFit == ObjectFitFill || iw <= 0 || ih <= 0 {
+		return wd, ht
+	}
+	contain := math.Min(wd.ToPT()/iw.ToPT(), ht.ToPT()/ih.ToPT())
+	var scale float64
+	switch img.ObjectFit {
+	case ObjectFitContain:
+		scale = contain
+	case ObjectFitCover:
+		scale = math.Max(wd.ToPT()/iw.ToPT(), ht.ToPT()/ih.ToPT())
+	case ObjectFitNone:
+		scale = 1
+	case ObjectFitScaleDown:
+		scale = math.Min(contain, 1)
+	}
+	return bag.MultiplyFloat(iw, scale), bag.MultiplyFloat(ih, scale)
+}
+
+// buildImage returns a horizontal list with the image for a paragraph with
+// the width hsize.
+func (img *Image) buildImage(hsize bag.ScaledPoint) *node.HList {
+	wd, ht := img.boxSize(hsize)
+	iw, ih := img.imageSize(wd, ht)
+	posX := bag.MultiplyFloat(wd-iw, img.PositionX) + img.OffsetX
+	posY := bag.MultiplyFloat(ht-ih, img.PositionY) + img.OffsetY
+
+	imgNode := node.NewImage()
+	imgNode.Img = img.Img
+	imgNode.Width = iw
+	imgNode.Height = ih
+	if iw == wd && ih == ht && posX == 0 && posY == 0 {
+		return node.Hpack(imgNode)
+	}
+
+	// The image is placed in a vertical list with the size of the box and
+	// clipped to the box. Images in a vertical list are placed at the top of
+	// the list, so the glue moves the image down.
+	imgVL := node.NewVList()
+	imgVL.List = imgNode
+	imgVL.Width = iw
+	imgVL.Height = ih
+	g := node.NewGlue()
+	g.Width = posY
+	g.Attributes = node.H{"origin": "image position"}
+	wrapper := node.NewVList()
+	wrapper.List = node.InsertAfter(g, g, imgVL)
+	wrapper.ShiftX = posX
+	wrapper.Attributes = node.H{"origin": "image"}
+
+	clip := backgroundRule("q "+pdfdraw.New().Rect(0, -ht, wd, ht).Clip().Endpath().String(), "image clip")
+	var head node.Node = clip
+	head = node.InsertAfter(head, clip, wrapper)
+	node.InsertAfter(head, wrapper, backgroundRule("Q", "image clip end"))
+	box := node.NewVList()
+	box.List = head
+	box.Width = wd
+	box.Height = ht
+	box.Attributes = node.H{"origin": "image box"}
+	return node.Hpack(box)
+}
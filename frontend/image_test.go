@@ -0,0 +1,106 @@
+package frontend
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/image"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+func TestImageBoxSize(t *testing.T) {
+	hsize := bag.MustSp("100pt")
+	testdata := []struct {
+		width, height, maxWidth string
+		wd, ht                  string
+	}{
+		{"", "", "", "40pt", "20pt"},
+		{"auto", "auto", "", "40pt", "20pt"},
+		{"20pt", "", "", "20pt", "10pt"},
+		{"", "30pt", "", "60pt", "30pt"},
+		{"50%", "", "", "50pt", "25pt"},
+		{"10pt", "10pt", "", "10pt", "10pt"},
+		{"", "", "50%", "40pt", "20pt"},
+		{"200pt", "", "100%", "100pt", "50pt"},
+		{"", "50%", "", "40pt", "20pt"},
+	}
+	for _, td := range testdata {
+		img := NewImage(&image.Image{Width: bag.MustSp("40pt"), Height: bag.MustSp("20pt")})
+		img.Width, img.Height, img.MaxWidth = td.width, td.height, td.maxWidth
+		wd, ht := img.boxSize(hsize)
+		if wd != bag.MustSp(td.wd) || ht != bag.MustSp(td.ht) {
+			t.Errorf("boxSize(%q, %q, max %q) = %s × %s, want %s × %s", td.width, td.height, td.maxWidth, wd, ht, td.wd, td.ht)
+		}
+	}
+}
+
+func TestImagePercentHeight(t *testing.T) {
+	var buf bytes.Buffer
+	logger := bag.Logger
+	bag.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer bag.SetLogger(logger)
+
+	img := NewImage(&image.Image{Width: bag.MustSp("40pt"), Height: bag.MustSp("20pt")})
+	img.MaxHeight = "10%"
+	wd, ht := img.boxSize(bag.MustSp("100pt"))
+	if wd != bag.MustSp("40pt") || ht != bag.MustSp("20pt") {
+		t.Errorf("boxSize(max-height 10%%) = %s × %s, want 40pt × 20pt", wd, ht)
+	}
+	if out := buf.String(); !strings.Contains(out, "percentage heights of images are not supported") || !strings.Contains(out, "property=max-height") {
+		t.Errorf("no warning for the percentage height: %q", out)
+	}
+}
+
+func TestObjectFit(t *testing.T) {
+	wd, ht := bag.MustSp("80pt"), bag.MustSp("80pt")
+	testdata := []struct {
+		fit    string
+		wd, ht string
+	}{
+		{"fill", "80pt", "80pt"},
+		{"contain", "80pt", "40pt"},
+		{"cover", "160pt", "80pt"},
+		{"none", "40pt", "20pt"},
+		{"scale-down", "40pt", "20pt"},
+	}
+	for _, td := range testdata {
+		img := NewImage(&image.Image{Width: bag.MustSp("40pt"), Height: bag.MustSp("20pt")})
+		var err error
+		if img.ObjectFit, err = ParseObjectFit(td.fit); err != nil {
+			t.Fatal(err)
+		}
+		if img.ObjectFit.String() != td.fit {
+			t.Errorf("ObjectFit.String() = %s, want %s", img.ObjectFit, td.fit)
+		}
+		w, h := img.imageSize(wd, ht)
+		if w != bag.MustSp(td.wd) || h != bag.MustSp(td.ht) {
+			t.Errorf("imageSize(%s) = %s × %s, want %s × %s", td.fit, w, h, td.wd, td.ht)
+		}
+	}
+	if _, err := ParseObjectFit("stretch"); err == nil {
+		t.Error("ParseObjectFit(stretch) should fail")
+	}
+}
+
+func TestBuildImage(t *testing.T) {
+	img := NewImage(&image.Image{Width: bag.MustSp("40pt"), Height: bag.MustSp("20pt")})
+	hl := img.buildImage(bag.MustSp("100pt"))
+	if _, ok := hl.List.(*node.Image); !ok {
+		t.Errorf("buildImage() list = %T, want *node.Image", hl.List)
+	}
+	img.Width, img.Height = "20pt", "20pt"
+	img.ObjectFit = ObjectFitCover
+	if err := img.SetPosition("left"); err != nil {
+		t.Fatal(err)
+	}
+	hl = img.buildImage(bag.MustSp("100pt"))
+	if hl.Width != bag.MustSp("20pt") || hl.Height != bag.MustSp("20pt") {
+		t.Errorf("buildImage() size = %s × %s, want 20pt × 20pt", hl.Width, hl.Height)
+	}
+	if vl, ok := hl.List.(*node.VList); !ok || vl.Attributes["origin"] != "image box" {
+		t.Errorf("buildImage() list = %T, want image box", hl.List)
+	}
+}
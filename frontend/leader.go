@@ -0,0 +1,36 @@
+package frontend
+
+import (
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+// A Leader is an item of a Text which fills the rest of the line with copies
+// of its text, for example the dots between an entry of a table of contents
+// and the page number.
+type Leader struct {
+	// Text is the text which is repeated, such as "." or ". ".
+	Text string
+}
+
+// leaderNodes returns a penalty which prevents a line break before the leader
+// and the glue which fills the line with the leader.
+func (fe *Document) leaderNodes(l *Leader, settings TypesettingSettings) (node.Node, node.Node, error) {
+	nl, err := fe.BuildNodelistFromString(settings, l.Text)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := node.NewPenalty()
+	p.Penalty = 10000
+	g := node.NewGlue()
+	// the leader takes (almost) all of the space which the line end glue
+	// would take otherwise
+	g.Stretch = 1000 * bag.Factor
+	g.StretchOrder = node.StretchFilll
+	g.Attributes = node.H{"origin": "leader"}
+	if nl != nil {
+		g.Leader = node.Hpack(nl)
+	}
+	node.InsertAfter(p, p, g)
+	return p, g, nil
+}
@@ -0,0 +1,56 @@
+package frontend
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+func TestLeader(t *testing.T) {
+	fe, err := New(filepath.Join(t.TempDir(), "out.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fe.LoadIncludedFonts(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := GetLanguage("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	te := NewText()
+	te.Settings[SettingFontFamily] = fe.FindFontFamily("serif")
+	te.Settings[SettingSize] = bag.MustSp("10pt")
+	te.Items = append(te.Items, "Introduction", &Leader{Text: "."}, "3")
+	hsize := bag.MustSp("200pt")
+	vl, _, err := fe.FormatParagraph(te, hsize, Language(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, ok := vl.List.(*node.HList)
+	if !ok {
+		t.Fatal("the paragraph does not start with a line")
+	}
+	var leader *node.Glue
+	for e := line.List; e != nil; e = e.Next() {
+		if g, ok := e.(*node.Glue); ok && g.Attributes["origin"] == "leader" {
+			leader = g
+			if p, ok := g.Prev().(*node.Penalty); !ok || p.Penalty != 10000 {
+				t.Error("the line can be broken before the leader")
+			}
+		}
+	}
+	if leader == nil {
+		t.Fatal("no leader glue in the line")
+	}
+	if leader.Leader == nil || leader.Leader.Width <= 0 {
+		t.Error("the leader glue has no leader box")
+	}
+	// the leader takes the rest of the line, so the page number is at the
+	// right edge
+	if line.Width != hsize || leader.Width < hsize/2 {
+		t.Errorf("line width = %s, leader width = %s, want a line of %s filled by the leader", line.Width, leader.Width, hsize)
+	}
+}
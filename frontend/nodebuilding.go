@@ -98,20 +98,71 @@ const (
 	FontStyleOblique
 )
 
-// TextDecorationLine sets the underline type
+// TextDecorationLine sets the underline type. The values can be combined with
+// a bitwise or, for example TextDecorationUnderline | TextDecorationOverline.
 type TextDecorationLine int
 
 const (
 	// TextDecorationLineNone means no underline
-	TextDecorationLineNone TextDecorationLine = iota
+	TextDecorationLineNone TextDecorationLine = 0
 	// TextDecorationUnderline is a simple underlining
-	TextDecorationUnderline
+	TextDecorationUnderline TextDecorationLine = 1 << (iota - 1)
 	// TextDecorationOverline has a line above
 	TextDecorationOverline
 	// TextDecorationLineThrough is a strike out
 	TextDecorationLineThrough
 )
 
+func (tdl TextDecorationLine) String() string {
+	if tdl == TextDecorationLineNone {
+		return "none"
+	}
+	var lines []string
+	if tdl&TextDecorationUnderline != 0 {
+		lines = append(lines, "underline")
+	}
+	if tdl&TextDecorationOverline != 0 {
+		lines = append(lines, "overline")
+	}
+	if tdl&TextDecorationLineThrough != 0 {
+		lines = append(lines, "line-through")
+	}
+	return strings.Join(lines, " ")
+}
+
+// TextDecorationStyle is the style of the text decoration lines.
+type TextDecorationStyle int
+
+func (tds TextDecorationStyle) String() string {
+	switch tds {
+	case TextDecorationStyleSolid:
+		return "solid"
+	case TextDecorationStyleDouble:
+		return "double"
+	case TextDecorationStyleDotted:
+		return "dotted"
+	case TextDecorationStyleDashed:
+		return "dashed"
+	case TextDecorationStyleWavy:
+		return "wavy"
+	default:
+		return "???"
+	}
+}
+
+const (
+	// TextDecorationStyleSolid draws a single line.
+	TextDecorationStyleSolid TextDecorationStyle = iota
+	// TextDecorationStyleDouble draws two parallel lines.
+	TextDecorationStyleDouble
+	// TextDecorationStyleDotted draws a dotted line.
+	TextDecorationStyleDotted
+	// TextDecorationStyleDashed draws a dashed line.
+	TextDecorationStyleDashed
+	// TextDecorationStyleWavy draws a wavy line.
+	TextDecorationStyleWavy
+)
+
 const (
 	// SettingDummy is a no op.
 	SettingDummy SettingType = iota
@@ -119,6 +170,12 @@ const (
 	SettingBox
 	// SettingBackgroundColor sets the background color.
 	SettingBackgroundColor
+	// SettingBackgroundGradient sets a linear or radial gradient (*Gradient) as
+	// the background.
+	SettingBackgroundGradient
+	// SettingBackgroundImage sets an image (*BackgroundImage) as the
+	// background.
+	SettingBackgroundImage
 	// SettingBorderBottomWidth sets the bottom border width.
 	SettingBorderBottomWidth
 	// SettingBorderLeftWidth sets the left border width.
@@ -153,8 +210,14 @@ const (
 	SettingBorderBottomRightRadius
 	// SettingColor sets a predefined color.
 	SettingColor
+	// SettingColumns arranges the contents of a box in columns (*Columns).
+	SettingColumns
+	// SettingColumnSpan makes a box inside a multi-column box span all columns.
+	SettingColumnSpan
 	// SettingDebug can contain debugging information
 	SettingDebug
+	// SettingDirection sets the base direction (Direction) of the paragraph.
+	SettingDirection
 	// SettingFontExpansion is the amount of expansion / shrinkage allowed. Value is a float between 0 (no expansion) and 1 (100% of the glyph width).
 	SettingFontExpansion
 	// SettingFontFamily selects a font family.
@@ -183,8 +246,13 @@ const (
 	SettingMarginRight
 	// SettingMarginTop sets the top margin.
 	SettingMarginTop
+	// SettingOpacity sets the opacity (0 is transparent, 1 is opaque) of a box
+	// and its text.
+	SettingOpacity
 	// SettingOpenTypeFeature allows the user to (de)select OpenType features such as ligatures.
 	SettingOpenTypeFeature
+	// SettingOutlineLevel is the outline level (1-6) of a heading.
+	SettingOutlineLevel
 	// SettingPaddingBottom is the bottom padding.
 	SettingPaddingBottom
 	// SettingPaddingLeft is the left hand padding.
@@ -205,8 +273,16 @@ const (
 	SettingTabSizeSpaces
 	// SettingTabSize is the tab width.
 	SettingTabSize
-	// SettingTextDecorationLine sets underline
+	// SettingTextDecorationColor sets the color of the text decoration lines. Defaults to the text color.
+	SettingTextDecorationColor
+	// SettingTextDecorationLine sets underline, overline and line-through (TextDecorationLine).
 	SettingTextDecorationLine
+	// SettingTextDecorationStyle sets the style (TextDecorationStyle) of the text decoration lines.
+	SettingTextDecorationStyle
+	// SettingTextDecorationThickness sets the width of the text decoration lines.
+	SettingTextDecorationThickness
+	// SettingTextUnderlineOffset moves the underline further down.
+	SettingTextUnderlineOffset
 	// SettingWidth sets alternative widths for the text.
 	SettingWidth
 	// SettingVAlign sets the vertical alignment. A height should be set.
@@ -222,6 +298,10 @@ func (st SettingType) String() string {
 		settingName = "SettingBox"
 	case SettingBackgroundColor:
 		settingName = "SettingBackgroundColor"
+	case SettingBackgroundGradient:
+		settingName = "SettingBackgroundGradient"
+	case SettingBackgroundImage:
+		settingName = "SettingBackgroundImage"
 	case SettingBorderBottomWidth:
 		settingName = "SettingBorderBottomWidth"
 	case SettingBorderTopWidth:
@@ -256,8 +336,14 @@ func (st SettingType) String() string {
 		settingName = "SettingBorderBottomRightRadius"
 	case SettingColor:
 		settingName = "SettingColor"
+	case SettingColumns:
+		settingName = "SettingColumns"
+	case SettingColumnSpan:
+		settingName = "SettingColumnSpan"
 	case SettingDebug:
 		settingName = "SettingDebug"
+	case SettingDirection:
+		settingName = "SettingDirection"
 	case SettingFontExpansion:
 		settingName = "SettingFontExpansion"
 	case SettingFontFamily:
@@ -286,8 +372,12 @@ func (st SettingType) String() string {
 		settingName = "SettingMarginRight"
 	case SettingMarginTop:
 		settingName = "SettingMarginTop"
+	case SettingOpacity:
+		settingName = "SettingOpacity"
 	case SettingOpenTypeFeature:
 		settingName = "SettingOpenTypeFeature"
+	case SettingOutlineLevel:
+		settingName = "SettingOutlineLevel"
 	case SettingPaddingBottom:
 		settingName = "SettingPaddingBottom"
 	case SettingPaddingRight:
@@ -308,8 +398,16 @@ func (st SettingType) String() string {
 		settingName = "SettingTabSize"
 	case SettingTabSizeSpaces:
 		settingName = "SettingTabSizeSpaces"
+	case SettingTextDecorationColor:
+		settingName = "SettingTextDecorationColor"
 	case SettingTextDecorationLine:
 		settingName = "SettingTextDecorationLine"
+	case SettingTextDecorationStyle:
+		settingName = "SettingTextDecorationStyle"
+	case SettingTextDecorationThickness:
+		settingName = "SettingTextDecorationThickness"
+	case SettingTextUnderlineOffset:
+		settingName = "SettingTextUnderlineOffset"
 	case SettingVAlign:
 		settingName = "SettingVAlign"
 	case SettingYOffset:
@@ -403,6 +501,10 @@ func debugText(ts *Text, enc *xml.Encoder) {
 			if t == 0 {
 				showSetting = false
 			}
+		case TextDecorationStyle:
+			if t == 0 {
+				showSetting = false
+			}
 		case HangingPunctuation:
 			if t == 0 {
 				showSetting = false
@@ -446,6 +548,28 @@ func debugText(ts *Text, enc *xml.Encoder) {
 			enc.EncodeToken(xml.CharData(t))
 		case *node.VList:
 			enc.EncodeToken(xml.CharData(node.DebugToString(t)))
+		case *Footnote:
+			fn := xml.StartElement{Name: xml.Name{Local: "footnote"}}
+			fn.Attr = []xml.Attr{{Name: xml.Name{Local: "number"}, Value: fmt.Sprint(t.Number)}}
+			enc.EncodeToken(fn)
+			debugText(t.Body, enc)
+			enc.EncodeToken(fn.End())
+		case *Leader:
+			ldr := xml.StartElement{Name: xml.Name{Local: "leader"}}
+			ldr.Attr = []xml.Attr{{Name: xml.Name{Local: "text"}, Value: t.Text}}
+			enc.EncodeToken(ldr)
+			enc.EncodeToken(ldr.End())
+		case *Image:
+			img := xml.StartElement{Name: xml.Name{Local: "image"}}
+			img.Attr = []xml.Attr{
+				{Name: xml.Name{Local: "width"}, Value: t.Width},
+				{Name: xml.Name{Local: "height"}, Value: t.Height},
+			}
+			if t.Img != nil && t.Img.ImageFile != nil {
+				img.Attr = append(img.Attr, xml.Attr{Name: xml.Name{Local: "filename"}, Value: t.Img.ImageFile.Filename})
+			}
+			enc.EncodeToken(img)
+			enc.EncodeToken(img.End())
 		default:
 			panic(fmt.Sprintf("unknown type %T", t))
 		}
@@ -576,7 +700,7 @@ func (fe *Document) FormatParagraph(te *Text, hsize bag.ScaledPoint, opts ...Typ
 	}
 	var hlist, tail node.Node
 	var err error
-	hlist, tail, err = fe.Mknodes(te)
+	hlist, tail, err = fe.mknodes(te, hsize)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -589,6 +713,13 @@ func (fe *Document) FormatParagraph(te *Text, hsize bag.ScaledPoint, opts ...Typ
 		return node.Vpack(hlist), nil, nil
 	}
 
+	var direction Direction
+	if dir, ok := te.Settings[SettingDirection]; ok {
+		direction = dir.(Direction)
+	}
+	levels, paraLevel := bidiLevels(hlist, direction)
+	p.Alignment = resolveAlignment(p.Alignment, paraLevel)
+
 	Hyphenate(hlist, p.Language)
 	node.AppendLineEndAfter(hlist, tail)
 
@@ -633,6 +764,13 @@ func (fe *Document) FormatParagraph(te *Text, hsize bag.ScaledPoint, opts ...Typ
 		ls.LineStartGlue = lg
 	}
 	vlist, info := node.Linebreak(hlist, ls)
+	if levels != nil {
+		for e := vlist.List; e != nil; e = e.Next() {
+			if hl, ok := e.(*node.HList); ok {
+				reorderLine(hl, levels, paraLevel)
+			}
+		}
+	}
 	for _, cb := range fe.postLinebreakCallback {
 		vlist = cb(vlist)
 	}
@@ -709,7 +847,8 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 	var col *color.Color
 	var hyperlink document.Hyperlink
 	var hasHyperlink bool
-	var hasUnderline bool
+	var decoration textDecoration
+	opacity := 1.0
 	fontfeatures := make([]harfbuzz.Feature, 0, len(fe.DefaultFeatures))
 	for _, f := range fe.DefaultFeatures {
 		fontfeatures = append(fontfeatures, f)
@@ -743,9 +882,24 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 			hyperlink = v.(document.Hyperlink)
 			hasHyperlink = true
 		case SettingTextDecorationLine:
-			if underlineType, ok := v.(TextDecorationLine); ok && underlineType == TextDecorationUnderline {
-				hasUnderline = true
+			if tdl, ok := v.(TextDecorationLine); ok {
+				decoration.Line = tdl
+			}
+		case SettingTextDecorationStyle:
+			if tds, ok := v.(TextDecorationStyle); ok {
+				decoration.Style = tds
 			}
+		case SettingTextDecorationColor:
+			switch t := v.(type) {
+			case string:
+				decoration.Color = fe.GetColor(t)
+			case *color.Color:
+				decoration.Color = t
+			}
+		case SettingTextDecorationThickness:
+			decoration.Thickness = v.(bag.ScaledPoint)
+		case SettingTextUnderlineOffset:
+			decoration.UnderlineOffset = v.(bag.ScaledPoint)
 		case SettingFontExpansion:
 			// ignore
 		case SettingStyle:
@@ -764,10 +918,14 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 			// ignore
 		case SettingBorderBottomLeftRadius, SettingBorderBottomRightRadius, SettingBorderTopLeftRadius, SettingBorderTopRightRadius:
 			// ignore
-		case SettingBackgroundColor, SettingPrepend, SettingDebug, SettingHeight, SettingVAlign, SettingHangingPunctuation:
+		case SettingBackgroundColor, SettingBackgroundGradient, SettingBackgroundImage, SettingPrepend, SettingDebug, SettingHeight, SettingVAlign, SettingHangingPunctuation:
+			// ignore
+		case SettingWidth, SettingBox, SettingOutlineLevel, SettingDirection:
 			// ignore
-		case SettingWidth, SettingBox:
+		case SettingColumns, SettingColumnSpan:
 			// ignore
+		case SettingOpacity:
+			opacity = v.(float64)
 		case SettingPreserveWhitespace:
 			preserveWhitespace = v.(bool)
 		case SettingYOffset:
@@ -776,6 +934,13 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 			return nil, fmt.Errorf("Unknown setting %v", k)
 		}
 	}
+	if opacity < 1 {
+		if col == nil {
+			col = fe.GetColor("black")
+		}
+		col = col.WithOpacity(opacity)
+		decoration.Color = decoration.Color.WithOpacity(opacity)
+	}
 
 	var fnt *font.Font
 	var face *pdf.Face
@@ -825,24 +990,32 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 		}
 		head = hyperlinkStart
 	}
-	var underlineStart *node.StartStop
-	if hasUnderline {
-		underlineStart = node.NewStartStop()
-		node.SetAttribute(underlineStart, "underline", true)
-		node.SetAttribute(underlineStart, "underlinepos", -fontsize/6)
-		node.SetAttribute(underlineStart, "underlinelw", fontsize/20)
-		node.SetAttribute(underlineStart, "SettingTextDecorationLine", TextDecorationUnderline)
+	var decorationStart *node.StartStop
+	if decoration.Line != TextDecorationLineNone {
+		decoration.Fontsize = fontsize
+		if decoration.Color == nil {
+			decoration.Color = col
+		}
+		if decoration.Thickness == 0 {
+			decoration.Thickness = fontsize / 20
+		}
+		decorationStart = node.NewStartStop()
+		decorationStart.Action = node.ActionUserSetting
+		node.SetAttribute(decorationStart, "textdecoration", &decoration)
 		if head != nil {
-			head = node.InsertAfter(head, head, underlineStart)
+			head = node.InsertAfter(head, head, decorationStart)
 		} else {
-			head = underlineStart
+			head = decorationStart
 		}
-		underlineStart.Action = node.ActionUserSetting
 	}
+	var colStart *node.StartStop
 	if col != nil {
-		colStart := node.NewStartStop()
+		colStart = node.NewStartStop()
 		colStart.Position = node.PDFOutputPage
 		colStart.ShipoutCallback = func(n node.Node) string {
+			if op := col.Opacity(); op < 1 {
+				return color.PDFStringAlpha(op, op) + " " + col.PDFStringNonStroking() + " "
+			}
 			return col.PDFStringNonStroking() + " "
 		}
 		if head != nil {
@@ -852,7 +1025,7 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 	}
 	cur = head
 	var lastglue node.Node
-	atoms := fnt.Shape(str, fontfeatures)
+	atoms := shapeBidi(fnt, str, fontfeatures)
 	for _, r := range atoms {
 		if r.IsSpace {
 			if preserveWhitespace {
@@ -926,7 +1099,8 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 			n.Width = r.Advance
 			n.Height = r.Height
 			n.Depth = r.Depth
-			n.YOffset = yoffset
+			n.XOffset = r.XOffset
+			n.YOffset = yoffset + r.YOffset
 			head = node.InsertAfter(head, cur, n)
 			cur = n
 			lastglue = nil
@@ -941,19 +1115,23 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 	}
 	if col != nil {
 		stop := node.NewStartStop()
+		stop.StartNode = colStart
 		stop.Position = node.PDFOutputPage
 		stop.ShipoutCallback = func(n node.Node) string {
+			if col.Opacity() < 1 {
+				return color.PDFStringAlpha(1, 1) + " 0 0 0 RG 0 0 0 rg "
+			}
 			return "0 0 0 RG 0 0 0 rg "
 		}
 		node.InsertAfter(head, cur, stop)
 		cur = stop
 	}
-	if hasUnderline {
-		underlineStop := node.NewStartStop()
-		underlineStop.StartNode = underlineStart
-		node.SetAttribute(underlineStop, "underline", false)
-		head = node.InsertAfter(head, cur, underlineStop)
-		cur = underlineStop
+	if decorationStart != nil {
+		decorationStop := node.NewStartStop()
+		decorationStop.Action = node.ActionUserSetting
+		decorationStop.StartNode = decorationStart
+		head = node.InsertAfter(head, cur, decorationStop)
+		cur = decorationStop
 	}
 	if hasHyperlink {
 		hyperlinkStop = node.NewStartStop()
@@ -967,8 +1145,13 @@ func (fe *Document) BuildNodelistFromString(ts TypesettingSettings, str string)
 
 // Mknodes creates a list of nodes which which can be formatted to a given
 // width. The returned head and the tail are the beginning and the end of the
-// node list.
+// node list. Percentages in the size of images are ignored.
 func (fe *Document) Mknodes(ts *Text) (head node.Node, tail node.Node, err error) {
+	return fe.mknodes(ts, 0)
+}
+
+// mknodes creates the node list for a paragraph with the width hsize.
+func (fe *Document) mknodes(ts *Text, hsize bag.ScaledPoint) (head node.Node, tail node.Node, err error) {
 	bag.Logger.Log(nil, -8, "Document#Mknodes")
 	if len(ts.Items) == 0 {
 		return nil, nil, nil
@@ -1039,7 +1222,7 @@ func (fe *Document) Mknodes(ts *Text) (head node.Node, tail node.Node, err error
 			}
 			// we don't want to inherit hyperlinks
 			delete(t.Settings, SettingHyperlink)
-			nl, end, err = fe.Mknodes(t)
+			nl, end, err = fe.mknodes(t, hsize)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -1050,6 +1233,25 @@ func (fe *Document) Mknodes(ts *Text) (head node.Node, tail node.Node, err error
 		case node.Node:
 			head = node.InsertAfter(head, tail, t)
 			tail = t
+		case *Image:
+			hl := t.buildImage(hsize)
+			head = node.InsertAfter(head, tail, hl)
+			tail = hl
+		case *Leader:
+			p, g, err := fe.leaderNodes(t, newSettings)
+			if err != nil {
+				return nil, nil, err
+			}
+			head = node.InsertAfter(head, tail, p)
+			tail = g
+		case *Footnote:
+			call, ins, err := fe.footnoteNodes(t, newSettings)
+			if err != nil {
+				return nil, nil, err
+			}
+			head = node.InsertAfter(head, tail, call)
+			head = node.InsertAfter(head, call, ins)
+			tail = ins
 		case *Table:
 			s := node.NewStartStop()
 			s.Attributes = node.H{"table": t}
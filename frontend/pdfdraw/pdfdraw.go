@@ -2,6 +2,8 @@ package pdfdraw
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/speedata/boxesandglue/backend/bag"
@@ -12,38 +14,57 @@ import (
 type Object struct {
 	pdfstring    []string
 	encapsulated bool
+	// alpha holds the non stroking and stroking alpha values, one entry for
+	// each saved graphics state.
+	alpha [][2]float64
 }
 
 // New creates a new PDF object.
 func New() *Object {
-	return &Object{}
+	return &Object{
+		alpha: [][2]float64{{1, 1}},
+	}
 }
 
 // NewStandalone creates a new PDF object encapsulated in q ... Q .
 func NewStandalone() *Object {
 	return &Object{
 		encapsulated: true,
+		alpha:        [][2]float64{{1, 1}},
 	}
 }
 
+// setAlpha sets the non stroking (i = 0) or the stroking (i = 1) alpha value
+// if it differs from the current one.
+func (pd *Object) setAlpha(i int, a float64) {
+	cur := &pd.alpha[len(pd.alpha)-1]
+	if cur[i] == a {
+		return
+	}
+	cur[i] = a
+	pd.pdfstring = append(pd.pdfstring, color.PDFStringAlpha(cur[0], cur[1]))
+}
+
 // Color sets the stroking and nonstroking color
 func (pd *Object) Color(col color.Color) *Object {
 	pd.ColorStroking(col).ColorNonstroking(col)
 	return pd
 }
 
-// ColorStroking sets the stroking color
+// ColorStroking sets the stroking color and the stroking alpha value.
 func (pd *Object) ColorStroking(col color.Color) *Object {
 	if col.Space != color.ColorNone {
+		pd.setAlpha(1, col.Opacity())
 		pd.pdfstring = append(pd.pdfstring, col.PDFStringStroking())
 	}
 	return pd
 }
 
-// ColorNonstroking sets the non stroking color. If the color is the color
-// “none”, then no color will be set.
+// ColorNonstroking sets the non stroking color and the non stroking alpha
+// value. If the color is the color “none”, then no color will be set.
 func (pd *Object) ColorNonstroking(col color.Color) *Object {
 	if col.Space != color.ColorNone {
+		pd.setAlpha(0, col.Opacity())
 		pd.pdfstring = append(pd.pdfstring, col.PDFStringNonStroking())
 	}
 	return pd
@@ -135,12 +156,16 @@ func (pd *Object) Rect(x, y, wd, ht bag.ScaledPoint) *Object {
 
 // Save saves the graphics state.
 func (pd *Object) Save() *Object {
+	pd.alpha = append(pd.alpha, pd.alpha[len(pd.alpha)-1])
 	pd.pdfstring = append(pd.pdfstring, "q")
 	return pd
 }
 
 // Restore restores the graphics state.
 func (pd *Object) Restore() *Object {
+	if len(pd.alpha) > 1 {
+		pd.alpha = pd.alpha[:len(pd.alpha)-1]
+	}
 	pd.pdfstring = append(pd.pdfstring, "Q")
 	return pd
 }
@@ -181,6 +206,85 @@ func (pd *Object) SetDash(dasharray []uint, dashphase uint) *Object {
 	return pd
 }
 
+// DashPattern sets the dash pattern with lengths given as scaled points. An
+// empty dasharray resets the pattern to a solid line.
+func (pd *Object) DashPattern(dasharray []bag.ScaledPoint, dashphase bag.ScaledPoint) *Object {
+	dashes := make([]string, len(dasharray))
+	for i, d := range dasharray {
+		dashes[i] = d.String()
+	}
+	pd.pdfstring = append(pd.pdfstring, fmt.Sprintf("[%s] %s d", strings.Join(dashes, " "), dashphase))
+	return pd
+}
+
+// LineCap sets the line cap style (0 = butt, 1 = round, 2 = projecting square).
+func (pd *Object) LineCap(style int) *Object {
+	pd.pdfstring = append(pd.pdfstring, fmt.Sprintf("%d J", style))
+	return pd
+}
+
+// Shadings stores the shading dictionaries for the page resources and returns
+// their resource names. The PDF document implements this interface.
+type Shadings interface {
+	Shading(dict string) string
+}
+
+// numberString formats a transformation matrix entry.
+func numberString(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10000)/10000, 'f', -1, 64)
+}
+
+// shade fills the rectangle at (x, y) with the shading of the gradient g. The
+// coordinates of the shading are in the coordinate system given by the
+// transformation matrix m which is applied after clipping to the rectangle.
+func (pd *Object) shade(shadings Shadings, g *color.Gradient, shadingType int, coords []float64, x, y, wd, ht bag.ScaledPoint, m [6]float64) *Object {
+	dict, err := g.Shading(shadingType, coords)
+	if err != nil {
+		return pd
+	}
+	pd.Save()
+	pd.Rect(x, y, wd, ht).Clip().Endpath()
+	pd.pdfstring = append(pd.pdfstring, fmt.Sprintf("%s %s %s %s %s %s cm",
+		numberString(m[0]), numberString(m[1]), numberString(m[2]), numberString(m[3]), numberString(m[4]), numberString(m[5])))
+	pd.pdfstring = append(pd.pdfstring, shadings.Shading(dict)+" sh")
+	pd.Restore()
+	return pd
+}
+
+// LinearGradient fills the rectangle at (x, y) with an axial shading of the
+// gradient g. The angle (in degrees) is the direction of the gradient line, 0
+// goes upwards and 90 to the right (as in CSS). The shading is registered
+// with shadings. To fill other shapes, clip the path before.
+func (pd *Object) LinearGradient(shadings Shadings, g *color.Gradient, x, y, wd, ht bag.ScaledPoint, angle float64) *Object {
+	rad := angle * math.Pi / 180
+	dx, dy := math.Sin(rad), math.Cos(rad)
+	w, h := wd.ToPT(), ht.ToPT()
+	length := math.Abs(w*dx) + math.Abs(h*dy)
+	if length == 0 {
+		return pd
+	}
+	// the gradient line runs along the x axis through the center of the
+	// rectangle
+	m := [6]float64{dx, dy, -dy, dx, x.ToPT() + w/2, y.ToPT() + h/2}
+	return pd.shade(shadings, g, 2, []float64{-length / 2, 0, length / 2, 0}, x, y, wd, ht, m)
+}
+
+// RadialGradient fills the rectangle at (x, y) with a radial shading of the
+// gradient g. The gradient is an ellipse around (cx, cy) with the radii rx
+// and ry, outside of the ellipse the last color is used. The shading is
+// registered with shadings. To fill other shapes, clip the path before.
+func (pd *Object) RadialGradient(shadings Shadings, g *color.Gradient, x, y, wd, ht, cx, cy, rx, ry bag.ScaledPoint) *Object {
+	if rx <= 0 || ry <= 0 {
+		if _, err := g.Space(); err == nil {
+			pd.Save().ColorNonstroking(g.ColorAt(1)).Rect(x, y, wd, ht).Fill().Restore()
+		}
+		return pd
+	}
+	// a circle with the radius rx, scaled vertically to the ellipse
+	m := [6]float64{1, 0, 0, ry.ToPT() / rx.ToPT(), cx.ToPT(), cy.ToPT()}
+	return pd.shade(shadings, g, 3, []float64{0, 0, 0, 0, 0, rx.ToPT()}, x, y, wd, ht, m)
+}
+
 // String returns the PDF instructions used for
 func (pd *Object) String() string {
 	ret := []string{}
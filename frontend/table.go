@@ -12,19 +12,29 @@ import (
 
 // Table represents tabular material to be typeset.
 type Table struct {
-	MaxWidth     bag.ScaledPoint
-	Stretch      bool
-	FontFamily   *FontFamily
-	FontSize     bag.ScaledPoint
-	Leading      bag.ScaledPoint
-	Rows         TableRows
-	ColSpec      []ColSpec
-	doc          *Document
-	columnWidths []bag.ScaledPoint
-	rowHeights   []bag.ScaledPoint
-	nCol         int
-	nRow         int
-	cellMatrix   matrix
+	MaxWidth   bag.ScaledPoint
+	Stretch    bool
+	FontFamily *FontFamily
+	FontSize   bag.ScaledPoint
+	Leading    bag.ScaledPoint
+	Rows       TableRows
+	// Head and Foot rows are repeated at the top and the bottom of each part
+	// of a split table.
+	Head    TableRows
+	Foot    TableRows
+	ColSpec []ColSpec
+	// FirstPageHeight is the available height for the first part of the
+	// table, PageHeight the height for all following parts. If PageHeight is
+	// 0, the table is not split.
+	FirstPageHeight bag.ScaledPoint
+	PageHeight      bag.ScaledPoint
+	doc             *Document
+	allRows         TableRows
+	columnWidths    []bag.ScaledPoint
+	rowHeights      []bag.ScaledPoint
+	nCol            int
+	nRow            int
+	cellMatrix      matrix
 }
 
 // TableRow represents a row in a table.
@@ -38,6 +48,9 @@ type TableRow struct {
 
 // TableCell represents a table cell
 type TableCell struct {
+	BackgroundColor             *color.Color
+	BackgroundGradient          *Gradient
+	BackgroundImage             *BackgroundImage
 	BorderTopWidth              bag.ScaledPoint
 	BorderBottomWidth           bag.ScaledPoint
 	BorderLeftWidth             bag.ScaledPoint
@@ -46,6 +59,10 @@ type TableCell struct {
 	BorderBottomColor           *color.Color
 	BorderLeftColor             *color.Color
 	BorderRightColor            *color.Color
+	BorderTopStyle              BorderStyle
+	BorderBottomStyle           BorderStyle
+	BorderLeftStyle             BorderStyle
+	BorderRightStyle            BorderStyle
 	CalculatedWidth             bag.ScaledPoint
 	CalculatedHeight            bag.ScaledPoint
 	HAlign                      HorizontalAlignment
@@ -229,8 +246,7 @@ func (cell *TableCell) build() (*node.VList, error) {
 		r := node.NewRule()
 		r.Height = cellHeight - cell.calculatedBorderTopWidth - cell.calculatedBorderBottomWidth
 		r.Width = cell.calculatedBorderLeftWidth
-		r.Pre = pdfdraw.New().Save().ColorNonstroking(*cell.BorderLeftColor).String()
-		r.Post = pdfdraw.New().Restore().String()
+		cell.setBorderRule(r, cell.BorderLeftStyle, cell.BorderLeftColor, false, true)
 		r.Attributes = node.H{"origin": "left rule"}
 		head = r
 	}
@@ -256,8 +272,7 @@ func (cell *TableCell) build() (*node.VList, error) {
 		r := node.NewRule()
 		r.Height = cellHeight - cell.calculatedBorderTopWidth - cell.calculatedBorderBottomWidth
 		r.Width = cell.calculatedBorderRightWidth
-		r.Pre = pdfdraw.New().Save().ColorNonstroking(*cell.BorderRightColor).String()
-		r.Post = pdfdraw.New().Restore().String()
+		cell.setBorderRule(r, cell.BorderRightStyle, cell.BorderRightColor, false, false)
 		r.Attributes = node.H{"origin": "right rule"}
 		head = node.InsertAfter(head, node.Tail(head), r)
 	}
@@ -272,8 +287,7 @@ func (cell *TableCell) build() (*node.VList, error) {
 		r := node.NewRule()
 		r.Width = hl.Width
 		r.Height = cell.calculatedBorderTopWidth
-		r.Pre = pdfdraw.New().Save().ColorNonstroking(*cell.BorderTopColor).String()
-		r.Post = pdfdraw.New().Restore().String()
+		cell.setBorderRule(r, cell.BorderTopStyle, cell.BorderTopColor, true, true)
 		r.Attributes = node.H{"origin": "top rule"}
 		head = node.InsertBefore(head, head, r)
 	}
@@ -284,17 +298,42 @@ func (cell *TableCell) build() (*node.VList, error) {
 		r := node.NewRule()
 		r.Width = hl.Width
 		r.Height = cell.calculatedBorderBottomWidth
-		r.Pre = pdfdraw.New().Save().ColorNonstroking(*cell.BorderBottomColor).String()
-		r.Post = pdfdraw.New().Restore().String()
+		cell.setBorderRule(r, cell.BorderBottomStyle, cell.BorderBottomColor, true, false)
 		r.Attributes = node.H{"origin": "bottom rule"}
 		head = node.InsertAfter(head, node.Tail(head), r)
 	}
 
 	vl = node.Vpack(head)
 	vl.Attributes = node.H{"origin": "td"}
+	if cell.BackgroundColor != nil || cell.BackgroundGradient != nil || cell.BackgroundImage != nil {
+		bg := cell.row.table.doc.BackgroundVList(HTMLValues{
+			BackgroundColor:    cell.BackgroundColor,
+			BackgroundGradient: cell.BackgroundGradient,
+			BackgroundImage:    cell.BackgroundImage,
+		}, vl.Width, vl.Height+vl.Depth)
+		vl.List = node.InsertBefore(vl.List, vl.List, bg)
+	}
 	return vl, nil
 }
 
+// setBorderRule sets the PDF instructions for the border rule r. Top and bottom
+// rules are horizontal, left and right rules are vertical. A cell without a
+// border style gets a solid border.
+func (cell *TableCell) setBorderRule(r *node.Rule, sty BorderStyle, col *color.Color, horizontal, topLeft bool) {
+	if sty == BorderStyleNone || sty == BorderStyleSolid {
+		r.Pre = pdfdraw.New().Save().ColorNonstroking(*col).String()
+		r.Post = pdfdraw.New().Restore().String()
+		return
+	}
+	r.Hide = true
+	// rules in a vertical list are drawn from the top downwards
+	y := bag.ScaledPoint(0)
+	if horizontal {
+		y = -r.Height
+	}
+	r.Pre = borderRect(sty, *col, 0, y, r.Width, r.Height, horizontal, topLeft)
+}
+
 func (row *TableRow) getNumberOfColumns() int {
 	return len(row.Cells)
 }
@@ -455,8 +494,12 @@ func (m matrix) String() string {
 // row span and col span and border widths (in case of border collapse).
 func (tbl *Table) analyzeTable() {
 	// calculate number of rows and columns
-	tbl.nRow = len(tbl.Rows)
-	for i, row := range tbl.Rows {
+	tbl.allRows = make(TableRows, 0, len(tbl.Head)+len(tbl.Rows)+len(tbl.Foot))
+	tbl.allRows = append(tbl.allRows, tbl.Head...)
+	tbl.allRows = append(tbl.allRows, tbl.Rows...)
+	tbl.allRows = append(tbl.allRows, tbl.Foot...)
+	tbl.nRow = len(tbl.allRows)
+	for i, row := range tbl.allRows {
 		row.table = tbl
 		row.row = i
 		if n := len(row.Cells); n > tbl.nCol {
@@ -471,7 +514,7 @@ func (tbl *Table) analyzeTable() {
 		tbl.cellMatrix[x] = make([]cellptr, tbl.nRow)
 	}
 
-	for y, row := range tbl.Rows {
+	for y, row := range tbl.allRows {
 		extraCol := 0
 		for x := 0; x < len(row.Cells); x++ {
 			cell := row.Cells[x]
@@ -546,7 +589,7 @@ func (tbl *Table) analyzeTable() {
 		}
 	}
 	// border collapse
-	for _, row := range tbl.Rows {
+	for _, row := range tbl.allRows {
 		for _, cell := range row.Cells {
 			maxBorderLeft := bag.ScaledPoint(0)
 			for _, nc := range cell.nextCell {
@@ -593,7 +636,9 @@ func (tbl *Table) analyzeTable() {
 	}
 }
 
-// BuildTable creates one or more vertical lists to be placed into the PDF.
+// BuildTable creates one or more vertical lists to be placed into the PDF. If
+// the PageHeight of the table is set, the table is split into parts that fit
+// into FirstPageHeight and PageHeight.
 func (fe *Document) BuildTable(tbl *Table) ([]*node.VList, error) {
 	tbl.doc = fe
 	var head, tail node.Node
@@ -604,7 +649,7 @@ func (fe *Document) BuildTable(tbl *Table) ([]*node.VList, error) {
 	if tbl.ColSpec == nil || len(tbl.ColSpec) == 0 {
 		colmax := make([]bag.ScaledPoint, tbl.nCol)
 		colmin := make([]bag.ScaledPoint, tbl.nCol)
-		for _, r := range tbl.Rows {
+		for _, r := range tbl.allRows {
 			rowmin, rowmax, colspan, err := r.calculateWidths()
 			if err != nil {
 				return nil, err
@@ -695,15 +740,14 @@ func (fe *Document) BuildTable(tbl *Table) ([]*node.VList, error) {
 			i++
 		}
 	}
-	head = nil
-	tail = nil
 	// now that the column widths are known, the row heights can be calculated
-	err := tbl.Rows.calculateHeights()
+	err := tbl.allRows.calculateHeights()
 	if err != nil {
 		return nil, err
 	}
 
-	for i, row := range tbl.Rows {
+	rowlists := make([]*node.HList, len(tbl.allRows))
+	for i, row := range tbl.allRows {
 		hl, err := row.build()
 		if err != nil {
 			return nil, err
@@ -712,10 +756,109 @@ func (fe *Document) BuildTable(tbl *Table) ([]*node.VList, error) {
 		// calculated row height, so we need to adjust
 		hl.Height = tbl.rowHeights[i]
 		hl.Depth = 0
+		rowlists[i] = hl
+	}
+	if tbl.PageHeight == 0 {
+		return []*node.VList{tbl.packRows(rowlists)}, nil
+	}
+	return tbl.split(rowlists), nil
+}
+
+// packRows returns a vertical list with the rows.
+func (tbl *Table) packRows(rows []*node.HList) *node.VList {
+	var head, tail node.Node
+	for _, hl := range rows {
 		head = node.InsertAfter(head, tail, hl)
 		tail = hl
 	}
 	vl := node.Vpack(head)
 	vl.Attributes = node.H{"origin": "table"}
-	return []*node.VList{vl}, nil
+	return vl
+}
+
+// split distributes the body rows to parts of the height FirstPageHeight and
+// PageHeight. The table is only broken between rows which are not connected by
+// a rowspan. Each part gets the head rows at the top and the foot rows at the
+// bottom. If not even the first rows fit into FirstPageHeight, the first part
+// is empty. Rows that do not fit into PageHeight get a part on their own.
+func (tbl *Table) split(rowlists []*node.HList) []*node.VList {
+	nHead, nFoot := len(tbl.Head), len(tbl.Foot)
+	bodyEnd := len(rowlists) - nFoot
+	// breakAfter[i] is true if there is no rowspan from row i to row i+1
+	breakAfter := make([]bool, len(rowlists))
+	for i := range breakAfter {
+		breakAfter[i] = true
+	}
+	for _, row := range tbl.allRows {
+		for _, cell := range row.Cells {
+			for r := cell.rowStart; r < cell.rowStart+cell.ExtraRowspan && r < len(breakAfter); r++ {
+				breakAfter[r] = false
+			}
+		}
+	}
+	var headFootHeight bag.ScaledPoint
+	for i := 0; i < nHead; i++ {
+		headFootHeight += tbl.rowHeights[i]
+	}
+	for i := bodyEnd; i < len(rowlists); i++ {
+		headFootHeight += tbl.rowHeights[i]
+	}
+	copyRows := func(rows []*node.HList) []*node.HList {
+		ret := make([]*node.HList, len(rows))
+		for i, hl := range rows {
+			ret[i] = hl.Copy().(*node.HList)
+			ret[i].Attributes = node.H{"origin": "table row"}
+		}
+		return ret
+	}
+
+	var parts []*node.VList
+	height := tbl.PageHeight
+	if tbl.FirstPageHeight != 0 {
+		height = tbl.FirstPageHeight
+	}
+	start := nHead
+	for start < bodyEnd {
+		end := start
+		var sumHT bag.ScaledPoint
+		for i := start; i < bodyEnd; i++ {
+			sumHT += tbl.rowHeights[i]
+			if i < bodyEnd-1 && !breakAfter[i] {
+				continue
+			}
+			if headFootHeight+sumHT > height {
+				if end == start && len(parts) > 0 {
+					end = i + 1
+				}
+				break
+			}
+			end = i + 1
+		}
+		if end == start {
+			vl := node.NewVList()
+			vl.Attributes = node.H{"origin": "table"}
+			parts = append(parts, vl)
+		} else {
+			var rows []*node.HList
+			if len(parts) == 0 {
+				rows = append(rows, rowlists[:nHead]...)
+			} else {
+				rows = append(rows, copyRows(rowlists[:nHead])...)
+			}
+			rows = append(rows, rowlists[start:end]...)
+			if end == bodyEnd {
+				rows = append(rows, rowlists[bodyEnd:]...)
+			} else {
+				rows = append(rows, copyRows(rowlists[bodyEnd:])...)
+			}
+			parts = append(parts, tbl.packRows(rows))
+		}
+		start = end
+		height = tbl.PageHeight
+	}
+	if len(parts) == 0 {
+		// no body rows
+		parts = append(parts, tbl.packRows(rowlists))
+	}
+	return parts
 }
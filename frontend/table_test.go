@@ -0,0 +1,40 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/backend/node"
+)
+
+func TestTableSplit(t *testing.T) {
+	tbl := &Table{
+		Head:            TableRows{&TableRow{}},
+		Rows:            TableRows{&TableRow{}, &TableRow{}, &TableRow{}, &TableRow{}},
+		Foot:            TableRows{&TableRow{}},
+		FirstPageHeight: bag.MustSp("30pt"),
+		PageHeight:      bag.MustSp("40pt"),
+	}
+	tbl.allRows = append(append(append(TableRows{}, tbl.Head...), tbl.Rows...), tbl.Foot...)
+	rowlists := make([]*node.HList, len(tbl.allRows))
+	for i := range rowlists {
+		tbl.rowHeights = append(tbl.rowHeights, bag.MustSp("10pt"))
+		rowlists[i] = node.NewHList()
+		rowlists[i].Height = bag.MustSp("10pt")
+	}
+	parts := tbl.split(rowlists)
+	if got, want := len(parts), 3; got != want {
+		t.Fatalf("len(parts) = %d, want %d", got, want)
+	}
+	for i, want := range []bag.ScaledPoint{bag.MustSp("30pt"), bag.MustSp("40pt"), bag.MustSp("30pt")} {
+		if got := parts[i].Height + parts[i].Depth; got != want {
+			t.Errorf("parts[%d] height = %s, want %s", i, got, want)
+		}
+	}
+	if parts[0].List != rowlists[0] {
+		t.Errorf("the first part should start with the head row")
+	}
+	if parts[1].List == rowlists[0] {
+		t.Errorf("the second part should start with a copy of the head row")
+	}
+}
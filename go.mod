@@ -12,10 +12,10 @@ require (
 	github.com/speedata/optionparser v1.0.1
 	github.com/speedata/textlayout v0.0.0-20230827181055-b7ff752e85ae
 	golang.org/x/net v0.10.0
+	golang.org/x/text v0.9.0
 )
 
 require (
 	github.com/speedata/gofpdi v1.0.18 // indirect
 	golang.org/x/image v0.7.0 // indirect
-	golang.org/x/text v0.9.0 // indirect
 )
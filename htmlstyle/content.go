@@ -0,0 +1,141 @@
+package htmlstyle
+
+import (
+	"strings"
+
+	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/csshtml"
+	"github.com/speedata/boxesandglue/frontend"
+	"golang.org/x/net/html"
+)
+
+// stringSetMarker returns a node which carries the named strings of the
+// string-set property through the layout or nil if the styles don't contain
+// string-set. The page builder reads the assignments from the placed nodes.
+func stringSetMarker(styles map[string]string) node.Node {
+	v, ok := styles["string-set"]
+	if !ok {
+		return nil
+	}
+	assignments := csshtml.ParseStringSet(v)
+	if len(assignments) == 0 {
+		return nil
+	}
+	marker := node.NewStartStop()
+	marker.Attributes = node.H{
+		"origin":     "string-set",
+		"string-set": assignments,
+	}
+	return marker
+}
+
+// destinationNode returns a node which creates a named destination for the
+// id of the element or nil if the element has no id. Links to #id jump to the
+// destination and the page builder records the page of the destination.
+func destinationNode(attributes map[string]string) node.Node {
+	id := attributes["id"]
+	if id == "" {
+		return nil
+	}
+	dest := node.NewStartStop()
+	dest.Action = node.ActionDest
+	dest.Value = id
+	return dest
+}
+
+// prependNode inserts n at the beginning of the first paragraph in te. Tables
+// get no additional nodes.
+func prependNode(te *frontend.Text, n node.Node) {
+	if bx, ok := te.Settings[frontend.SettingBox]; ok && bx.(bool) {
+		for _, itm := range te.Items {
+			if txt, ok := itm.(*frontend.Text); ok {
+				prependNode(txt, n)
+				return
+			}
+		}
+		return
+	}
+	if len(te.Items) > 0 {
+		if _, ok := te.Items[0].(*frontend.Table); ok {
+			return
+		}
+	}
+	te.Items = append([]any{n}, te.Items...)
+}
+
+// pseudoStyles returns the styles of the pseudo element (before, after or
+// marker) without the prefix.
+func pseudoStyles(styles map[string]string, pseudo string) map[string]string {
+	ret := make(map[string]string)
+	prefix := pseudo + "::"
+	for k, v := range styles {
+		if strings.HasPrefix(k, prefix) {
+			ret[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+	return ret
+}
+
+// hasContent returns true if the content value generates a box.
+func hasContent(styles map[string]string) bool {
+	content, ok := styles["content"]
+	return ok && content != "none" && content != "normal" && styles["display"] != "none"
+}
+
+// pseudoElementItem returns the item for the ::before or ::after pseudo element
+// of itm or nil if the pseudo element has no content. The pseudo element is a
+// block if its display property says so and the element itself is a block.
+func pseudoElementItem(itm *HTMLItem, pseudo string) *HTMLItem {
+	styles := pseudoStyles(itm.Styles, pseudo)
+	if !hasContent(styles) {
+		return nil
+	}
+	pe := &HTMLItem{
+		Typ:        html.ElementNode,
+		Data:       "::" + pseudo,
+		Dir:        ModeHorizontal,
+		Attributes: map[string]string{},
+		Styles:     styles,
+	}
+	if itm.Dir == ModeVertical {
+		switch styles["display"] {
+		case "block", "list-item", "flow-root":
+			pe.Dir = ModeVertical
+		}
+	}
+	for _, part := range csshtml.ContentParts(styles["content"]) {
+		if part.Leader != "" {
+			pe.Children = append(pe.Children, &HTMLItem{
+				Typ:        html.ElementNode,
+				Data:       "::leader",
+				Dir:        ModeHorizontal,
+				Attributes: map[string]string{"text": part.Leader},
+				Styles:     map[string]string{},
+			})
+			continue
+		}
+		if part.URL != "" {
+			pe.Children = append(pe.Children, &HTMLItem{
+				Typ:        html.ElementNode,
+				Data:       "img",
+				Dir:        ModeHorizontal,
+				Attributes: map[string]string{"src": part.URL},
+				Styles:     map[string]string{},
+			})
+			continue
+		}
+		pe.Children = append(pe.Children, &HTMLItem{Typ: html.TextNode, Data: part.Text})
+	}
+	return pe
+}
+
+// addPseudoElements inserts the ::before and ::after pseudo elements as the
+// first and the last child of itm.
+func addPseudoElements(itm *HTMLItem) {
+	if before := pseudoElementItem(itm, "before"); before != nil {
+		itm.Children = append([]*HTMLItem{before}, itm.Children...)
+	}
+	if after := pseudoElementItem(itm, "after"); after != nil {
+		itm.Children = append(itm.Children, after)
+	}
+}
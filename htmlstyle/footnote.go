@@ -0,0 +1,91 @@
+package htmlstyle
+
+import (
+	"github.com/speedata/boxesandglue/backend/bag"
+	"github.com/speedata/boxesandglue/csshtml"
+	"github.com/speedata/boxesandglue/frontend"
+)
+
+// footnoteResolver resolves counter(footnote) in the content of the footnote
+// call and the footnote marker.
+type footnoteResolver int
+
+func (fr footnoteResolver) Counter(name string) ([]int, bool) {
+	if name == "footnote" {
+		return []int{int(fr)}, true
+	}
+	return []int{0}, true
+}
+
+func (fr footnoteResolver) Function(name string, args []string) (string, bool) {
+	return "", false
+}
+
+// footnotePseudoSettings returns the settings and the content of the
+// ::footnote-call or ::footnote-marker pseudo element of item. The defaults are
+// used for the properties which are not set.
+func footnotePseudoSettings(item *HTMLItem, pseudo string, defaults map[string]string, ss StylesStack, currentFontsize bag.ScaledPoint, df *frontend.Document) (frontend.TypesettingSettings, string, error) {
+	styles := pseudoStyles(item.Styles, pseudo)
+	for k, v := range defaults {
+		if _, ok := styles[k]; !ok {
+			styles[k] = v
+		}
+	}
+	content := styles["content"]
+	delete(styles, "content")
+	settings := make(frontend.TypesettingSettings)
+	sty := ss.PushStyles()
+	if err := StylesToStyles(sty, styles, df, currentFontsize); err != nil {
+		return nil, "", err
+	}
+	ApplySettings(settings, sty)
+	ss.PopStyles()
+	return settings, content, nil
+}
+
+// collectFootnote appends the footnote for the element with float: footnote
+// to te. The children of the element are the footnote body.
+func collectFootnote(te *frontend.Text, item *HTMLItem, ss StylesStack, currentFontsize bag.ScaledPoint, defaultFontsize bag.ScaledPoint, df *frontend.Document) error {
+	callSettings, callContent, err := footnotePseudoSettings(item, "footnote-call", map[string]string{
+		"content":        "counter( footnote )",
+		"font-size":      "smaller",
+		"vertical-align": "super",
+	}, ss, currentFontsize, df)
+	if err != nil {
+		return err
+	}
+	markerSettings, markerContent, err := footnotePseudoSettings(item, "footnote-marker", map[string]string{
+		"content": `counter( footnote ) ". "`,
+	}, ss, currentFontsize, df)
+	if err != nil {
+		return err
+	}
+	inner := *item
+	inner.Styles = make(map[string]string, len(item.Styles))
+	for k, v := range item.Styles {
+		if k != "float" {
+			inner.Styles[k] = v
+		}
+	}
+	body := frontend.NewText()
+	sty := ss.PushStyles()
+	if err = StylesToStyles(sty, inner.Styles, df, currentFontsize); err != nil {
+		return err
+	}
+	ApplySettings(body.Settings, sty)
+	err = collectHorizontalNodes(body, &inner, ss, currentFontsize, defaultFontsize, df)
+	ss.PopStyles()
+	if err != nil {
+		return err
+	}
+	fn := frontend.NewFootnote(body)
+	fn.Call.Settings = callSettings
+	fn.Marker.Settings = markerSettings
+	fn.Label = func(number int) (string, string) {
+		fr := footnoteResolver(number)
+		return csshtml.ContentText(csshtml.ResolveContent(callContent, fr)),
+			csshtml.ContentText(csshtml.ResolveContent(markerContent, fr))
+	}
+	te.Items = append(te.Items, fn)
+	return nil
+}
@@ -5,10 +5,11 @@ import (
 	"strconv"
 	"strings"
 
+	pdf "github.com/speedata/baseline-pdf"
 	"github.com/speedata/boxesandglue/backend/bag"
 	"github.com/speedata/boxesandglue/backend/color"
 	"github.com/speedata/boxesandglue/backend/document"
-	"github.com/speedata/boxesandglue/backend/node"
+	"github.com/speedata/boxesandglue/csshtml"
 	"github.com/speedata/boxesandglue/frontend"
 	"golang.org/x/net/html"
 )
@@ -43,6 +44,10 @@ func ParseHorizontalAlign(align string, styles *FormattingStyles) frontend.Horiz
 		return frontend.HAlignCenter
 	case "right":
 		return frontend.HAlignRight
+	case "start":
+		return frontend.HAlignStart
+	case "end":
+		return frontend.HAlignEnd
 	case "inherit":
 		return styles.Halign
 	default:
@@ -136,10 +141,36 @@ func StylesToStyles(ih *FormattingStyles, attributes map[string]string, df *fron
 			// already set
 		case "hyphens":
 			// ignore for now
+		case "direction":
+			switch v {
+			case "ltr":
+				ih.direction = frontend.DirectionLTR
+			case "rtl":
+				ih.direction = frontend.DirectionRTL
+			}
 		case "display":
 			ih.Hide = (v == "none")
 		case "background-color":
 			ih.BackgroundColor = df.GetColor(v)
+		case "background-image":
+			ih.BackgroundGradient = nil
+			ih.backgroundImage = nil
+			if v == "none" {
+				break
+			}
+			if strings.HasPrefix(v, "url(") {
+				ih.backgroundImage = loadBackgroundImage(df, v)
+			} else if g, err := df.ParseGradient(v); err == nil {
+				ih.BackgroundGradient = g
+			} else {
+				bag.Logger.Error(err.Error())
+			}
+		case "background-position":
+			ih.backgroundPosition = v
+		case "background-repeat":
+			ih.backgroundRepeat = v
+		case "background-size":
+			ih.backgroundSize = v
 		case "border-right-width", "border-left-width", "border-top-width", "border-bottom-width":
 			size := ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
 			switch k {
@@ -165,15 +196,7 @@ func StylesToStyles(ih *FormattingStyles, attributes map[string]string, df *fron
 				ih.BorderBottomRightRadius = size
 			}
 		case "border-right-style", "border-left-style", "border-top-style", "border-bottom-style":
-			var sty frontend.BorderStyle
-			switch v {
-			case "none":
-				// default
-			case "solid":
-				sty = frontend.BorderStyleSolid
-			default:
-				// logger.Error(fmt.Sprintf("not implemented: border style %q", v))
-			}
+			sty := frontend.ParseBorderStyle(v)
 			switch k {
 			case "border-right-style":
 				ih.BorderRightStyle = sty
@@ -196,7 +219,45 @@ func StylesToStyles(ih *FormattingStyles, attributes map[string]string, df *fron
 		case "border-spacing":
 			// ignore
 		case "color":
-			ih.color = df.GetColor(v)
+			// currentColor, inherit and invalid colors keep the inherited
+			// color
+			if col := df.GetColor(v); col != nil {
+				ih.color = col
+			}
+		case "column-count":
+			if v == "auto" {
+				ih.columnCount = 0
+			} else if cc, err := strconv.Atoi(v); err == nil {
+				ih.columnCount = cc
+			}
+		case "column-fill":
+			switch v {
+			case "auto":
+				ih.columnFill = frontend.ColumnFillAuto
+			case "balance", "balance-all":
+				ih.columnFill = frontend.ColumnFillBalance
+			}
+		case "column-gap":
+			ih.columnGap = v
+		case "column-rule-color":
+			if v == "currentcolor" {
+				// the text color, see ApplySettings
+				ih.columnRuleColor = nil
+			} else {
+				ih.columnRuleColor = df.GetColor(v)
+			}
+		case "column-rule-style":
+			ih.columnRuleStyle = frontend.ParseBorderStyle(v)
+		case "column-rule-width":
+			ih.columnRuleWidth = ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
+		case "column-span":
+			ih.columnSpan = (v == "all")
+		case "column-width":
+			if v == "auto" {
+				ih.columnWidth = 0
+			} else {
+				ih.columnWidth = ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
+			}
 		case "content":
 			// ignore
 		case "font-style":
@@ -234,6 +295,12 @@ func StylesToStyles(ih *FormattingStyles, attributes map[string]string, df *fron
 			ih.marginRight = ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
 		case "margin-top":
 			ih.marginTop = ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
+		case "opacity":
+			if op, ok := parseOpacity(v); ok {
+				// the opacity of the parent applies to the children
+				op *= ih.Opacity()
+				ih.opacity = &op
+			}
 		case "padding-inline-start":
 			ih.paddingInlineStart = ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
 		case "padding-bottom":
@@ -252,18 +319,54 @@ func StylesToStyles(ih *FormattingStyles, attributes map[string]string, df *fron
 			}
 		case "text-align":
 			ih.Halign = ParseHorizontalAlign(v, ih)
-		case "text-decoration-style":
-			// not yet implemented
+		case "text-decoration-color":
+			ih.TextDecorationColor = df.GetColor(v)
 		case "text-decoration-line":
+			ih.TextDecorationLine = frontend.TextDecorationLineNone
+			for _, line := range strings.Fields(v) {
+				switch line {
+				case "underline":
+					ih.TextDecorationLine |= frontend.TextDecorationUnderline
+				case "overline":
+					ih.TextDecorationLine |= frontend.TextDecorationOverline
+				case "line-through":
+					ih.TextDecorationLine |= frontend.TextDecorationLineThrough
+				}
+			}
+		case "text-decoration-style":
 			switch v {
-			case "underline":
-				ih.TextDecorationLine = frontend.TextDecorationUnderline
+			case "solid":
+				ih.TextDecorationStyle = frontend.TextDecorationStyleSolid
+			case "double":
+				ih.TextDecorationStyle = frontend.TextDecorationStyleDouble
+			case "dotted":
+				ih.TextDecorationStyle = frontend.TextDecorationStyleDotted
+			case "dashed":
+				ih.TextDecorationStyle = frontend.TextDecorationStyleDashed
+			case "wavy":
+				ih.TextDecorationStyle = frontend.TextDecorationStyleWavy
+			}
+		case "text-decoration-thickness":
+			if v == "auto" || v == "from-font" {
+				ih.TextDecorationThickness = 0
+			} else {
+				ih.TextDecorationThickness = ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
+			}
+		case "text-underline-offset":
+			if v == "auto" {
+				ih.TextUnderlineOffset = 0
+			} else {
+				ih.TextUnderlineOffset = ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
 			}
 		case "text-indent":
 			ih.indent = ParseRelativeSize(v, curFontSize, ih.DefaultFontSize)
 			ih.indentRows = 1
 		case "user-select":
 			// ignore
+		case "counter-increment", "counter-reset", "counter-set", "string-set":
+			// resolved by csshtml and the page builder
+		case "float":
+			// float: footnote is handled by collectHorizontalNodes
 		case "vertical-align":
 			if v == "sub" {
 				ih.yoffset = -1 * ih.Fontsize * 1000 / 5000
@@ -285,6 +388,10 @@ func StylesToStyles(ih *FormattingStyles, attributes map[string]string, df *fron
 				ih.fontexpansion = &fe
 			}
 		default:
+			if strings.Contains(k, "::") {
+				// pseudo elements get their own styles
+				break
+			}
 			fmt.Println("unresolved attribute", k, v)
 		}
 	}
@@ -294,6 +401,11 @@ func StylesToStyles(ih *FormattingStyles, attributes map[string]string, df *fron
 // FormattingStyles are HTML formatting styles.
 type FormattingStyles struct {
 	BackgroundColor         *color.Color
+	BackgroundGradient      *frontend.Gradient
+	backgroundImage         *pdf.Imagefile
+	backgroundPosition      string
+	backgroundRepeat        string
+	backgroundSize          string
 	BorderLeftWidth         bag.ScaledPoint
 	BorderRightWidth        bag.ScaledPoint
 	BorderBottomWidth       bag.ScaledPoint
@@ -312,7 +424,16 @@ type FormattingStyles struct {
 	BorderTopStyle          frontend.BorderStyle
 	DefaultFontSize         bag.ScaledPoint
 	DefaultFontFamily       *frontend.FontFamily
+	direction               frontend.Direction
 	color                   *color.Color
+	columnCount             int
+	columnFill              frontend.ColumnFill
+	columnGap               string
+	columnRuleColor         *color.Color
+	columnRuleStyle         frontend.BorderStyle
+	columnRuleWidth         bag.ScaledPoint
+	columnSpan              bool
+	columnWidth             bag.ScaledPoint
 	Hide                    bool
 	fontfamily              *frontend.FontFamily
 	fontfeatures            []string
@@ -331,13 +452,18 @@ type FormattingStyles struct {
 	marginLeft              bag.ScaledPoint
 	marginRight             bag.ScaledPoint
 	marginTop               bag.ScaledPoint
+	opacity                 *float64
 	paddingInlineStart      bag.ScaledPoint
 	OlCounter               int
 	PaddingBottom           bag.ScaledPoint
 	PaddingLeft             bag.ScaledPoint
 	PaddingRight            bag.ScaledPoint
 	PaddingTop              bag.ScaledPoint
+	TextDecorationColor     *color.Color
 	TextDecorationLine      frontend.TextDecorationLine
+	TextDecorationStyle     frontend.TextDecorationStyle
+	TextDecorationThickness bag.ScaledPoint
+	TextUnderlineOffset     bag.ScaledPoint
 	preserveWhitespace      bool
 	tabsize                 bag.ScaledPoint
 	tabsizeSpaces           int
@@ -357,6 +483,7 @@ func (is *FormattingStyles) Clone() *FormattingStyles {
 		color:              is.color,
 		DefaultFontSize:    is.DefaultFontSize,
 		DefaultFontFamily:  is.DefaultFontFamily,
+		direction:          is.direction,
 		fontexpansion:      is.fontexpansion,
 		fontfamily:         is.fontfamily,
 		fontfeatures:       newFontFeatures,
@@ -368,6 +495,7 @@ func (is *FormattingStyles) Clone() *FormattingStyles {
 		lineheight:         is.lineheight,
 		ListStyleType:      is.ListStyleType,
 		OlCounter:          is.OlCounter,
+		opacity:            is.opacity,
 		preserveWhitespace: is.preserveWhitespace,
 		tabsize:            is.tabsize,
 		tabsizeSpaces:      is.tabsizeSpaces,
@@ -377,6 +505,84 @@ func (is *FormattingStyles) Clone() *FormattingStyles {
 	return newis
 }
 
+// loadBackgroundImage loads the image file from a CSS url() value. Errors are
+// logged and nil is returned.
+func loadBackgroundImage(df *frontend.Document, v string) *pdf.Imagefile {
+	filename := strings.TrimSuffix(strings.TrimPrefix(v, "url("), ")")
+	filename = strings.Trim(strings.TrimSpace(filename), `"'`)
+	imgfile, err := df.Doc.LoadImageFile(filename)
+	if err != nil {
+		bag.Logger.Error("cannot load background image", "filename", filename, "error", err)
+		return nil
+	}
+	return imgfile
+}
+
+// newBackgroundImage creates a background image from the CSS values of
+// background-size, background-position and background-repeat. Empty values
+// are the defaults (auto, 0% 0%, repeat).
+func newBackgroundImage(imgfile *pdf.Imagefile, size, position, repeat string) *frontend.BackgroundImage {
+	bi := &frontend.BackgroundImage{ImageFile: imgfile}
+	if size != "" {
+		if err := bi.SetSize(size); err != nil {
+			bag.Logger.Error(err.Error())
+		}
+	}
+	if position == "" {
+		position = "0% 0%"
+	}
+	if err := bi.SetPosition(position); err != nil {
+		bag.Logger.Error(err.Error())
+	}
+	if repeat != "" {
+		var err error
+		if bi.Repeat, err = frontend.ParseBackgroundRepeat(repeat); err != nil {
+			bag.Logger.Error(err.Error())
+		}
+	}
+	return bi
+}
+
+// parseOpacity interprets a CSS alpha value (a number or a percentage) and
+// clamps it to the range 0 to 1.
+func parseOpacity(v string) (float64, bool) {
+	var op float64
+	var err error
+	if strings.HasSuffix(v, "%") {
+		op, err = strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+		op /= 100
+	} else {
+		op, err = strconv.ParseFloat(v, 64)
+	}
+	if err != nil {
+		return 0, false
+	}
+	if op < 0 {
+		op = 0
+	} else if op > 1 {
+		op = 1
+	}
+	return op, true
+}
+
+// Opacity returns the opacity of the element, which includes the opacity of
+// the parent elements.
+func (is *FormattingStyles) Opacity() float64 {
+	if is.opacity == nil {
+		return 1
+	}
+	return *is.opacity
+}
+
+// BackgroundImage returns the background image (from background-image: url())
+// or nil if there is none.
+func (is *FormattingStyles) BackgroundImage() *frontend.BackgroundImage {
+	if is.backgroundImage == nil {
+		return nil
+	}
+	return newBackgroundImage(is.backgroundImage, is.backgroundSize, is.backgroundPosition, is.backgroundRepeat)
+}
+
 // ApplySettings converts the inheritable settings to boxes and glue text
 // settings.
 func ApplySettings(settings frontend.TypesettingSettings, ih *FormattingStyles) {
@@ -384,6 +590,12 @@ func ApplySettings(settings frontend.TypesettingSettings, ih *FormattingStyles)
 		settings[frontend.SettingFontWeight] = ih.Fontweight
 	}
 	settings[frontend.SettingBackgroundColor] = ih.BackgroundColor
+	if ih.BackgroundGradient != nil {
+		settings[frontend.SettingBackgroundGradient] = ih.BackgroundGradient
+	}
+	if bi := ih.BackgroundImage(); bi != nil {
+		settings[frontend.SettingBackgroundImage] = bi
+	}
 	settings[frontend.SettingBorderTopWidth] = ih.BorderTopWidth
 	settings[frontend.SettingBorderLeftWidth] = ih.BorderLeftWidth
 	settings[frontend.SettingBorderRightWidth] = ih.BorderRightWidth
@@ -401,6 +613,29 @@ func ApplySettings(settings frontend.TypesettingSettings, ih *FormattingStyles)
 	settings[frontend.SettingBorderBottomLeftRadius] = ih.BorderBottomLeftRadius
 	settings[frontend.SettingBorderBottomRightRadius] = ih.BorderBottomRightRadius
 	settings[frontend.SettingColor] = ih.color
+	if ih.columnCount > 0 || ih.columnWidth > 0 {
+		gap := ih.Fontsize
+		if ih.columnGap != "" && ih.columnGap != "normal" {
+			gap = ParseRelativeSize(ih.columnGap, ih.Fontsize, ih.DefaultFontSize)
+		}
+		ruleColor := ih.columnRuleColor
+		if ruleColor == nil {
+			ruleColor = ih.color
+		}
+		settings[frontend.SettingColumns] = &frontend.Columns{
+			Count:     ih.columnCount,
+			Width:     ih.columnWidth,
+			Gap:       gap,
+			RuleWidth: ih.columnRuleWidth,
+			RuleStyle: ih.columnRuleStyle,
+			RuleColor: ruleColor,
+			Fill:      ih.columnFill,
+		}
+	}
+	if ih.columnSpan {
+		settings[frontend.SettingColumnSpan] = true
+	}
+	settings[frontend.SettingDirection] = ih.direction
 	if ih.fontexpansion != nil {
 		settings[frontend.SettingFontExpansion] = *ih.fontexpansion
 	} else {
@@ -416,6 +651,9 @@ func ApplySettings(settings frontend.TypesettingSettings, ih *FormattingStyles)
 	settings[frontend.SettingMarginRight] = ih.marginRight
 	settings[frontend.SettingMarginLeft] = ih.marginLeft
 	settings[frontend.SettingMarginTop] = ih.marginTop
+	if ih.opacity != nil {
+		settings[frontend.SettingOpacity] = *ih.opacity
+	}
 	settings[frontend.SettingOpenTypeFeature] = ih.fontfeatures
 	settings[frontend.SettingPaddingRight] = ih.PaddingRight
 	settings[frontend.SettingPaddingLeft] = ih.PaddingLeft
@@ -427,7 +665,11 @@ func ApplySettings(settings frontend.TypesettingSettings, ih *FormattingStyles)
 	settings[frontend.SettingYOffset] = ih.yoffset
 	settings[frontend.SettingTabSize] = ih.tabsize
 	settings[frontend.SettingTabSizeSpaces] = ih.tabsizeSpaces
+	settings[frontend.SettingTextDecorationColor] = ih.TextDecorationColor
 	settings[frontend.SettingTextDecorationLine] = ih.TextDecorationLine
+	settings[frontend.SettingTextDecorationStyle] = ih.TextDecorationStyle
+	settings[frontend.SettingTextDecorationThickness] = ih.TextDecorationThickness
+	settings[frontend.SettingTextUnderlineOffset] = ih.TextUnderlineOffset
 
 	if ih.width != "" {
 		settings[frontend.SettingWidth] = ih.width
@@ -511,32 +753,50 @@ func Output(item *HTMLItem, ss StylesStack, df *frontend.Document) (*frontend.Te
 		}
 		newte.Items = append(newte.Items, tbl)
 		return newte, nil
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		newte.Settings[frontend.SettingOutlineLevel] = int(item.Data[1] - '0')
 	case "ol", "ul":
 		styles.OlCounter = 0
 	case "li":
-		var item string
-		if strings.HasPrefix(styles.ListStyleType, `"`) && strings.HasSuffix(styles.ListStyleType, `"`) {
-			item = strings.TrimPrefix(styles.ListStyleType, `"`)
-			item = strings.TrimSuffix(item, `"`)
+		var marker string
+		markerStyles := pseudoStyles(item.Styles, "marker")
+		if markerStyles["display"] == "none" {
+			// no marker
+		} else if hasContent(markerStyles) {
+			marker = csshtml.ContentText(markerStyles["content"])
+		} else if strings.HasPrefix(styles.ListStyleType, `"`) && strings.HasSuffix(styles.ListStyleType, `"`) {
+			marker = strings.TrimPrefix(styles.ListStyleType, `"`)
+			marker = strings.TrimSuffix(marker, `"`)
 		} else {
 			switch styles.ListStyleType {
 			case "disc":
-				item = "•"
+				marker = "•"
 			case "circle":
-				item = "◦"
+				marker = "◦"
 			case "none":
-				item = ""
+				marker = ""
 			case "square":
-				item = "□"
+				marker = "□"
 			case "decimal":
-				item = fmt.Sprintf("%d.", styles.OlCounter)
+				marker = fmt.Sprintf("%d.", styles.OlCounter)
 			default:
 				// logger.Error(fmt.Sprintf("unhandled list-style-type: %q", styles.ListStyleType))
-				item = "•"
+				marker = "•"
 			}
-			item += " "
+			marker += " "
 		}
-		n, err := df.BuildNodelistFromString(newte.Settings, item)
+		// the marker has the styles of the list item and its own
+		markerSettings := newte.Settings
+		if len(markerStyles) > 0 {
+			sty := ss.PushStyles()
+			if err := StylesToStyles(sty, markerStyles, df, styles.Fontsize); err != nil {
+				return nil, err
+			}
+			markerSettings = make(frontend.TypesettingSettings)
+			ApplySettings(markerSettings, sty)
+			ss.PopStyles()
+		}
+		n, err := df.BuildNodelistFromString(markerSettings, marker)
 		if err != nil {
 			return nil, err
 		}
@@ -589,6 +849,9 @@ func Output(item *HTMLItem, ss StylesStack, df *frontend.Document) (*frontend.Te
 			}
 			if len(te.Items) > 0 {
 				newte.Items = append(newte.Items, te)
+				// following inline material must not be merged into the
+				// paragraph of the block
+				newte.Settings[frontend.SettingBox] = true
 			}
 		}
 	}
@@ -607,15 +870,76 @@ func Output(item *HTMLItem, ss StylesStack, df *frontend.Document) (*frontend.Te
 		ss.PopStyles()
 		te = nil
 	}
+	if marker := stringSetMarker(item.Styles); marker != nil {
+		prependNode(newte, marker)
+	}
+	if dest := destinationNode(item.Attributes); dest != nil {
+		prependNode(newte, dest)
+	}
 	ss.PopStyles()
 	return newte, nil
 }
 
+// imageLength converts the CSS size s of an image to a length understood by
+// frontend.Image. Font relative sizes are resolved, percentages are kept.
+func imageLength(s string, currentFontsize, defaultFontsize bag.ScaledPoint) string {
+	switch {
+	case s == "" || s == "auto" || s == "none" || strings.HasSuffix(s, "%"):
+		return s
+	case strings.HasSuffix(s, "em"):
+		return ParseRelativeSize(s, currentFontsize, defaultFontsize).String() + "pt"
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		// unitless HTML attribute
+		return s + "px"
+	}
+	return s
+}
+
+// imageItem returns the image for the img element item. The size is taken
+// from the width and height attributes, the CSS properties override them.
+func imageItem(imgfile *pdf.Imagefile, item *HTMLItem, currentFontsize, defaultFontsize bag.ScaledPoint, df *frontend.Document) (*frontend.Image, error) {
+	img := frontend.NewImage(df.Doc.CreateImage(imgfile, 1, "/MediaBox"))
+	if img.Img == nil {
+		return nil, fmt.Errorf("cannot get the size of image %s", imgfile.Filename)
+	}
+	img.Width = imageLength(item.Attributes["width"], currentFontsize, defaultFontsize)
+	img.Height = imageLength(item.Attributes["height"], currentFontsize, defaultFontsize)
+	for k, v := range item.Styles {
+		var err error
+		switch k {
+		case "width":
+			img.Width = imageLength(v, currentFontsize, defaultFontsize)
+		case "height":
+			img.Height = imageLength(v, currentFontsize, defaultFontsize)
+		case "min-width":
+			img.MinWidth = imageLength(v, currentFontsize, defaultFontsize)
+		case "max-width":
+			img.MaxWidth = imageLength(v, currentFontsize, defaultFontsize)
+		case "min-height":
+			img.MinHeight = imageLength(v, currentFontsize, defaultFontsize)
+		case "max-height":
+			img.MaxHeight = imageLength(v, currentFontsize, defaultFontsize)
+		case "object-fit":
+			img.ObjectFit, err = frontend.ParseObjectFit(v)
+		case "object-position":
+			err = img.SetPosition(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
 func collectHorizontalNodes(te *frontend.Text, item *HTMLItem, ss StylesStack, currentFontsize bag.ScaledPoint, defaultFontsize bag.ScaledPoint, df *frontend.Document) error {
 	switch item.Typ {
 	case html.TextNode:
 		te.Items = append(te.Items, item.Data)
 	case html.ElementNode:
+		if item.Styles["float"] == "footnote" {
+			return collectFootnote(te, item, ss, currentFontsize, defaultFontsize, df)
+		}
 		childSettings := make(frontend.TypesettingSettings)
 		switch item.Data {
 		case "a":
@@ -627,33 +951,39 @@ func collectHorizontalNodes(te *frontend.Text, item *HTMLItem, ss StylesStack, c
 				}
 			}
 			hl := document.Hyperlink{URI: href}
+			if strings.HasPrefix(href, "#") {
+				// a link to an element with an id in the document
+				hl = document.Hyperlink{Local: strings.TrimPrefix(href, "#")}
+			}
 			childSettings[frontend.SettingHyperlink] = hl
 		case "img":
-			wd := bag.MustSp("3cm")
-			ht := wd
-			var filename string
-			for k, v := range item.Attributes {
-				switch k {
-				case "width":
-					wd = bag.MustSp(v)
-				case "height":
-					ht = bag.MustSp(v)
-				case "src":
-					filename = v
-				}
+			imgfile, err := df.Doc.LoadImageFile(item.Attributes["src"])
+			if err != nil {
+				return err
 			}
-			imgfile, err := df.Doc.LoadImageFile(filename)
+			img, err := imageItem(imgfile, item, currentFontsize, defaultFontsize, df)
 			if err != nil {
-				panic(err)
+				return err
 			}
-
-			ii := df.Doc.CreateImage(imgfile, 1, "/MediaBox")
-			imgNode := node.NewImage()
-			imgNode.Img = ii
-			imgNode.Width = wd
-			imgNode.Height = ht
-			hlist := node.Hpack(imgNode)
-			te.Items = append(te.Items, hlist)
+			te.Items = append(te.Items, img)
+		case "::leader":
+			te.Items = append(te.Items, &frontend.Leader{Text: item.Attributes["text"]})
+		case "svg":
+			imgfile, err := df.Doc.LoadSVG(strings.NewReader(item.SVG))
+			if err != nil {
+				return err
+			}
+			img, err := imageItem(imgfile, item, currentFontsize, defaultFontsize, df)
+			if err != nil {
+				return err
+			}
+			te.Items = append(te.Items, img)
+		}
+		if marker := stringSetMarker(item.Styles); marker != nil {
+			te.Items = append(te.Items, marker)
+		}
+		if dest := destinationNode(item.Attributes); dest != nil {
+			te.Items = append(te.Items, dest)
 		}
 
 		for _, itm := range item.Children {
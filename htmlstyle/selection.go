@@ -3,6 +3,7 @@ package htmlstyle
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/speedata/boxesandglue/csshtml"
 	"github.com/speedata/boxesandglue/frontend"
@@ -42,6 +43,8 @@ type HTMLItem struct {
 	Attributes map[string]string
 	Styles     map[string]string
 	Children   []*HTMLItem
+	// SVG contains the markup of an inline svg element.
+	SVG string
 }
 
 func (itm *HTMLItem) String() string {
@@ -95,7 +98,7 @@ func DumpElement(thisNode *html.Node, direction Mode, firstItem *HTMLItem) error
 
 			if eltname == "body" || eltname == "address" || eltname == "article" || eltname == "aside" || eltname == "blockquote" || eltname == "br" || eltname == "canvas" || eltname == "dd" || eltname == "div" || eltname == "dl" || eltname == "dt" || eltname == "fieldset" || eltname == "figcaption" || eltname == "figure" || eltname == "footer" || eltname == "form" || eltname == "h1" || eltname == "h2" || eltname == "h3" || eltname == "h4" || eltname == "h5" || eltname == "h6" || eltname == "header" || eltname == "hr" || eltname == "li" || eltname == "main" || eltname == "nav" || eltname == "noscript" || eltname == "ol" || eltname == "p" || eltname == "pre" || eltname == "section" || eltname == "table" || eltname == "tfoot" || eltname == "thead" || eltname == "tbody" || eltname == "tr" || eltname == "td" || eltname == "th" || eltname == "ul" || eltname == "video" {
 				newDir = ModeVertical
-			} else if eltname == "b" || eltname == "big" || eltname == "i" || eltname == "small" || eltname == "tt" || eltname == "abbr" || eltname == "acronym" || eltname == "cite" || eltname == "code" || eltname == "dfn" || eltname == "em" || eltname == "kbd" || eltname == "strong" || eltname == "samp" || eltname == "var" || eltname == "a" || eltname == "bdo" || eltname == "img" || eltname == "map" || eltname == "object" || eltname == "q" || eltname == "script" || eltname == "span" || eltname == "sub" || eltname == "sup" || eltname == "button" || eltname == "input" || eltname == "label" || eltname == "select" || eltname == "textarea" {
+			} else if eltname == "b" || eltname == "big" || eltname == "i" || eltname == "small" || eltname == "tt" || eltname == "abbr" || eltname == "acronym" || eltname == "cite" || eltname == "code" || eltname == "dfn" || eltname == "em" || eltname == "kbd" || eltname == "strong" || eltname == "samp" || eltname == "var" || eltname == "a" || eltname == "bdo" || eltname == "img" || eltname == "map" || eltname == "object" || eltname == "q" || eltname == "script" || eltname == "span" || eltname == "sub" || eltname == "sup" || eltname == "button" || eltname == "input" || eltname == "label" || eltname == "select" || eltname == "textarea" || eltname == "svg" {
 				newDir = ModeHorizontal
 			} else {
 				// keep dir
@@ -110,6 +113,11 @@ func DumpElement(thisNode *html.Node, direction Mode, firstItem *HTMLItem) error
 			attributes := thisNode.Attr
 			if len(attributes) > 0 {
 				itm.Styles, itm.Attributes, attributes = csshtml.ResolveAttributes(attributes)
+				if itm.Styles["float"] == "footnote" {
+					// the footnote call is part of the surrounding text
+					newDir = ModeHorizontal
+					itm.Dir = newDir
+				}
 				for key, value := range itm.Styles {
 					if key == "white-space" {
 						if value == "pre" {
@@ -120,11 +128,21 @@ func DumpElement(thisNode *html.Node, direction Mode, firstItem *HTMLItem) error
 					}
 				}
 			}
-			if thisNode.FirstChild != nil {
+			if eltname == "svg" {
+				// the graphic is converted as a whole
+				var sb strings.Builder
+				if err := html.Render(&sb, thisNode); err != nil {
+					return err
+				}
+				itm.SVG = sb.String()
+			} else if thisNode.FirstChild != nil {
 				preserveWhitespace = append(preserveWhitespace, ws)
 				DumpElement(thisNode.FirstChild, newDir, itm)
 				preserveWhitespace = preserveWhitespace[:len(preserveWhitespace)-1]
 			}
+			if eltname != "img" && eltname != "svg" {
+				addPseudoElements(itm)
+			}
 		case html.DocumentNode:
 			// just passthrough
 			DumpElement(thisNode.FirstChild, newDir, firstItem)
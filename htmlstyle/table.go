@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	pdf "github.com/speedata/baseline-pdf"
 	"github.com/speedata/boxesandglue/backend/bag"
 	"github.com/speedata/boxesandglue/backend/node"
 	"github.com/speedata/boxesandglue/frontend"
@@ -29,8 +30,28 @@ func processTr(item *HTMLItem, ss StylesStack, df *frontend.Document) (*frontend
 			borderRightStyle := ""
 			borderTopStyle := ""
 			borderBottomStyle := ""
+			var bgImage *pdf.Imagefile
+			var bgSize, bgPosition, bgRepeat string
 			for k, v := range itm.Styles {
 				switch k {
+				case "background-color":
+					tc.BackgroundColor = df.GetColor(v)
+				case "background-image":
+					if strings.HasPrefix(v, "url(") {
+						bgImage = loadBackgroundImage(df, v)
+					} else if v != "none" {
+						if g, err := df.ParseGradient(v); err == nil {
+							tc.BackgroundGradient = g
+						} else {
+							bag.Logger.Error(err.Error())
+						}
+					}
+				case "background-position":
+					bgPosition = v
+				case "background-repeat":
+					bgRepeat = v
+				case "background-size":
+					bgSize = v
 				case "padding-top":
 					tc.PaddingTop = ParseRelativeSize(v, curFontsize, defaultFontsize)
 				case "padding-bottom":
@@ -83,6 +104,13 @@ func processTr(item *HTMLItem, ss StylesStack, df *frontend.Document) (*frontend
 			if borderRightStyle == "none" {
 				tc.BorderRightWidth = 0
 			}
+			tc.BorderTopStyle = frontend.ParseBorderStyle(borderTopStyle)
+			tc.BorderBottomStyle = frontend.ParseBorderStyle(borderBottomStyle)
+			tc.BorderLeftStyle = frontend.ParseBorderStyle(borderLeftStyle)
+			tc.BorderRightStyle = frontend.ParseBorderStyle(borderRightStyle)
+			if bgImage != nil {
+				tc.BackgroundImage = newBackgroundImage(bgImage, bgSize, bgPosition, bgRepeat)
+			}
 
 			for k, v := range itm.Attributes {
 				switch k {
@@ -194,7 +222,7 @@ func processTable(item *HTMLItem, ss StylesStack, df *frontend.Document) (*front
 			}
 
 		}
-		if itm.Data == "thead" || itm.Data == "tbody" {
+		if itm.Data == "thead" || itm.Data == "tbody" || itm.Data == "tfoot" {
 			styles := ss.PushStyles()
 			for k, v := range itm.Styles {
 				switch k {
@@ -235,7 +263,14 @@ func processTable(item *HTMLItem, ss StylesStack, df *frontend.Document) (*front
 			if rows, err = processTbody(itm, ss, df); err != nil {
 				return nil, err
 			}
-			tbl.Rows = append(tbl.Rows, rows...)
+			switch itm.Data {
+			case "thead":
+				tbl.Head = append(tbl.Head, rows...)
+			case "tfoot":
+				tbl.Foot = append(tbl.Foot, rows...)
+			default:
+				tbl.Rows = append(tbl.Rows, rows...)
+			}
 			ss.PopStyles()
 		}
 	}